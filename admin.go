@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrNotDraining is returned when moving the deadline of the closers while
+// they are not being notified.
+var ErrNotDraining = errors.New("closers are not being notified")
+
+// errNoAdminAuth is the error of the requests to an admin API without an auth
+// hook.
+var errNoAdminAuth = errors.New("admin API has no auth hook")
+
+// ExtendDeadline moves the deadline of the closers that are being notified by
+// d, which can be negative, e.g. for an operator to let a slow drain finish.
+// Closers that already read the deadline of their context are not told.
+// ErrNotDraining is returned if the closers are not being notified, e.g.
+// during the drain delay, or once their notification is over.
+func (w *watcher) ExtendDeadline(d time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.draining == nil {
+		return ErrNotDraining
+	}
+
+	deadline, _ := w.draining.Deadline()
+	w.budget = deadline.Add(d)
+	w.draining.setDeadline(w.budget)
+
+	return nil
+}
+
+// Abort ends the notification of the closers now, as if the timeout had
+// fired: the timeout policy applies to the closers that have not completed,
+// which the error that Wait() returns reports.  Synchronous closers are not
+// interrupted, but those that follow are not called.  ErrNotDraining is
+// returned if the closers are not being notified.
+func (w *watcher) Abort() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.draining == nil {
+		return ErrNotDraining
+	}
+
+	w.budget = w.clock.Now()
+	w.draining.setDeadline(w.budget)
+
+	return nil
+}
+
+// AdminEvent is the audit event of a request to the admin API.
+type AdminEvent struct {
+	// Time is the time of the request.
+	Time time.Time
+
+	// Action is the requested action: status, plan, shutdown, drain, abort,
+	// or extend, or the path of the request if it is none of them.
+	Action string
+
+	// Remote is the network address of the client.
+	Remote string
+
+	// Denied reports whether the request was rejected by the auth hook.
+	Denied bool
+
+	// Err is the error, if any, that the request failed with.
+	Err error
+}
+
+// AllowingAllAdmin is the auth hook of an admin API that allows all requests,
+// for a handler that is only served to trusted clients, e.g. on a loopback
+// or Unix socket.
+func AllowingAllAdmin(*http.Request) error {
+	return nil
+}
+
+// AdminHandler returns the handler of the admin API of the instance, which
+// can be mounted on any mux, e.g. with http.StripPrefix():
+//
+//	GET  /status          the live status, as rendered by StatusHandler()
+//	GET  /plan            the plan, or its DOT graph with ?format=dot
+//	POST /shutdown        closes the instance, without waiting for it
+//	POST /drain?tag=web   closes the closers with the tags, see CloseTagged()
+//	POST /abort           aborts the notification of the closers
+//	POST /extend?by=30s   extends the deadline of the closers
+//
+// Requests that auth returns an error for are rejected with 403 Forbidden; a
+// nil auth rejects all requests, so that serving all clients takes passing
+// AllowingAllAdmin, in which case the handler must only be served to trusted
+// clients.  Requests for other paths are rejected with 404 Not Found.  audit,
+// if not nil, is called once each request has been answered, whether it was
+// denied or not.
+func (w *watcher) AdminHandler(auth func(r *http.Request) error, audit func(AdminEvent)) http.Handler {
+	mux := http.NewServeMux()
+
+	// authorize tells whether the request can be served, and otherwise
+	// rejects it
+	authorize := func(rw http.ResponseWriter, r *http.Request, ev *AdminEvent) bool {
+		err := errNoAdminAuth
+		if auth != nil {
+			err = auth(r)
+		}
+
+		if err != nil {
+			ev.Denied, ev.Err = true, err
+			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+			return false
+		}
+
+		return true
+	}
+
+	mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		ev := AdminEvent{Time: w.clock.Now().UTC(), Action: r.URL.Path, Remote: r.RemoteAddr}
+
+		if audit != nil {
+			defer func() { audit(ev) }()
+		}
+
+		if !authorize(rw, r, &ev) {
+			return
+		}
+
+		ev.Err = fmt.Errorf("unknown action %q", r.URL.Path)
+		http.NotFound(rw, r)
+	})
+
+	handle := func(action, method string, serve func(rw http.ResponseWriter, r *http.Request) error) {
+		mux.HandleFunc("/"+action, func(rw http.ResponseWriter, r *http.Request) {
+			ev := AdminEvent{Time: w.clock.Now().UTC(), Action: action, Remote: r.RemoteAddr}
+
+			if audit != nil {
+				defer func() { audit(ev) }()
+			}
+
+			if !authorize(rw, r, &ev) {
+				return
+			}
+
+			if r.Method != method {
+				ev.Err = fmt.Errorf("method %v not allowed", r.Method)
+				rw.Header().Set("Allow", method)
+				http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+				return
+			}
+
+			if ev.Err = serve(rw, r); ev.Err == nil {
+				return
+			}
+
+			code := http.StatusBadRequest
+			var failed *adminFailure
+
+			switch {
+			case errors.Is(ev.Err, ErrShutdown) || errors.Is(ev.Err, ErrNotDraining):
+				code = http.StatusConflict
+			case errors.As(ev.Err, &failed):
+				code = http.StatusInternalServerError
+			}
+
+			http.Error(rw, ev.Err.Error(), code)
+		})
+	}
+
+	handle("status", http.MethodGet, func(rw http.ResponseWriter, r *http.Request) error {
+		w.StatusHandler().ServeHTTP(rw, r)
+		return nil
+	})
+
+	handle("plan", http.MethodGet, func(rw http.ResponseWriter, r *http.Request) error {
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "text":
+			rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = fmt.Fprint(rw, w.Plan())
+		case "dot":
+			rw.Header().Set("Content-Type", "text/vnd.graphviz")
+			_, _ = fmt.Fprint(rw, w.PlanDOT())
+		default:
+			return fmt.Errorf("unknown plan format %q", format)
+		}
+
+		return nil
+	})
+
+	handle("shutdown", http.MethodPost, func(rw http.ResponseWriter, _ *http.Request) error {
+		if w.ctx.Err() != nil {
+			return ErrShutdown
+		}
+
+		// the closers, e.g. shutting down the server, wait for the handler
+		// to return
+		go func() { _ = w.Close() }()
+
+		rw.WriteHeader(http.StatusAccepted)
+
+		return nil
+	})
+
+	handle("drain", http.MethodPost, func(rw http.ResponseWriter, r *http.Request) error {
+		tags := r.URL.Query()["tag"]
+		if len(tags) == 0 {
+			return errors.New("tag must be specified")
+		}
+
+		if err := w.CloseTagged(tags...); err == ErrShutdown {
+			return err
+		} else if err != nil {
+			return &adminFailure{err}
+		}
+
+		rw.WriteHeader(http.StatusNoContent)
+
+		return nil
+	})
+
+	handle("abort", http.MethodPost, func(rw http.ResponseWriter, _ *http.Request) error {
+		if err := w.Abort(); err != nil {
+			return err
+		}
+
+		rw.WriteHeader(http.StatusNoContent)
+
+		return nil
+	})
+
+	handle("extend", http.MethodPost, func(rw http.ResponseWriter, r *http.Request) error {
+		d, err := time.ParseDuration(r.URL.Query().Get("by"))
+		if err != nil {
+			return err
+		}
+
+		if err := w.ExtendDeadline(d); err != nil {
+			return err
+		}
+
+		rw.WriteHeader(http.StatusNoContent)
+
+		return nil
+	})
+
+	return mux
+}
+
+// adminFailure is the error of an admin request that was valid but failed.
+type adminFailure struct{ err error }
+
+func (e *adminFailure) Error() string { return e.err.Error() }
+
+func (e *adminFailure) Unwrap() error { return e.err }
@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestAdminHandler(t *testing.T) {
+
+	serve := func(h http.Handler, method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	Convey("Ensure requests rejected by the auth hook are denied and audited", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		var audited []yama.AdminEvent
+		h := watcher.AdminHandler(
+			func(r *http.Request) error {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					return errors.New("bad token")
+				}
+				return nil
+			},
+			func(ev yama.AdminEvent) { audited = append(audited, ev) })
+
+		So(serve(h, http.MethodPost, "/shutdown").Code, ShouldEqual, http.StatusForbidden)
+		So(watcher.Status().Triggered, ShouldBeFalse)
+
+		So(audited, ShouldHaveLength, 1)
+		So(audited[0].Action, ShouldEqual, "shutdown")
+		So(audited[0].Denied, ShouldBeTrue)
+		So(audited[0].Err, ShouldBeError, "bad token")
+
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		So(rec.Code, ShouldEqual, http.StatusOK)
+		So(rec.Header().Get("Content-Type"), ShouldEqual, "application/json")
+
+		So(audited, ShouldHaveLength, 2)
+		So(audited[1].Action, ShouldEqual, "status")
+		So(audited[1].Denied, ShouldBeFalse)
+		So(audited[1].Err, ShouldBeNil)
+	})
+
+	Convey("Ensure requests are denied without an auth hook", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		var audited []yama.AdminEvent
+		h := watcher.AdminHandler(nil, func(ev yama.AdminEvent) { audited = append(audited, ev) })
+
+		So(serve(h, http.MethodPost, "/shutdown").Code, ShouldEqual, http.StatusForbidden)
+		So(watcher.Status().Triggered, ShouldBeFalse)
+
+		So(audited, ShouldHaveLength, 1)
+		So(audited[0].Denied, ShouldBeTrue)
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure requests for unknown paths are rejected and audited", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		var audited []yama.AdminEvent
+		h := watcher.AdminHandler(yama.AllowingAllAdmin, func(ev yama.AdminEvent) { audited = append(audited, ev) })
+
+		So(serve(h, http.MethodPost, "/restart").Code, ShouldEqual, http.StatusNotFound)
+
+		So(audited, ShouldHaveLength, 1)
+		So(audited[0].Action, ShouldEqual, "/restart")
+		So(audited[0].Denied, ShouldBeFalse)
+		So(audited[0].Err, ShouldBeError, `unknown action "/restart"`)
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure the plan is rendered as text or DOT, with GET only", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithClosers(yamatest.CloserSpy("db")))
+		So(err, ShouldBeNil)
+
+		h := watcher.AdminHandler(yama.AllowingAllAdmin, nil)
+
+		rec := serve(h, http.MethodGet, "/plan")
+		So(rec.Code, ShouldEqual, http.StatusOK)
+		So(rec.Body.String(), ShouldEqual, watcher.Plan())
+
+		rec = serve(h, http.MethodGet, "/plan?format=dot")
+		So(rec.Code, ShouldEqual, http.StatusOK)
+		So(rec.Body.String(), ShouldEqual, watcher.PlanDOT())
+
+		So(serve(h, http.MethodGet, "/plan?format=svg").Code, ShouldEqual, http.StatusBadRequest)
+
+		rec = serve(h, http.MethodPost, "/plan")
+		So(rec.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(rec.Header().Get("Allow"), ShouldEqual, http.MethodGet)
+	})
+
+	Convey("Ensure the shutdown is triggered once", t, func() {
+		spy := yamatest.CloserSpy("db")
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(spy))
+		So(err, ShouldBeNil)
+
+		h := watcher.AdminHandler(yama.AllowingAllAdmin, nil)
+
+		So(serve(h, http.MethodPost, "/shutdown").Code, ShouldEqual, http.StatusAccepted)
+		So(watcher.Wait(), ShouldBeNil)
+		So(spy.Calls(), ShouldEqual, 1)
+
+		rec := serve(h, http.MethodPost, "/shutdown")
+		So(rec.Code, ShouldEqual, http.StatusConflict)
+		So(strings.TrimSpace(rec.Body.String()), ShouldEqual, yama.ErrShutdown.Error())
+	})
+
+	Convey("Ensure the deadline can only be moved while the closers are notified", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		h := watcher.AdminHandler(yama.AllowingAllAdmin, nil)
+
+		So(serve(h, http.MethodPost, "/extend?by=1m").Code, ShouldEqual, http.StatusConflict)
+		So(serve(h, http.MethodPost, "/extend?by=soon").Code, ShouldEqual, http.StatusBadRequest)
+		So(serve(h, http.MethodPost, "/abort").Code, ShouldEqual, http.StatusConflict)
+		So(watcher.Abort(), ShouldEqual, yama.ErrNotDraining)
+	})
+
+	Convey("Ensure the deadline of the closers is extended", t, func() {
+		start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+		clock := yamatest.NewClock(start)
+		running, release := make(chan struct{}), make(chan struct{})
+
+		var deadline time.Time
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Second),
+			yama.WithClosers(yama.ContextFnAsCloser(func(ctx context.Context) error {
+				close(running)
+				<-release
+				deadline, _ = ctx.Deadline()
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+		<-running
+
+		h := watcher.AdminHandler(yama.AllowingAllAdmin, nil)
+		So(serve(h, http.MethodPost, "/extend?by=1m").Code, ShouldEqual, http.StatusNoContent)
+
+		close(release)
+		So(watcher.Wait(), ShouldBeNil)
+		So(deadline, ShouldEqual, start.Add(time.Minute+time.Second))
+	})
+
+	Convey("Ensure aborting times the closers out", t, func() {
+		running, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+
+		blocked := yama.FnAsCloser(func() {
+			close(running)
+			<-release
+		})
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(yamatest.NewClock(time.Time{})),
+			yama.WithTimeout(time.Hour),
+			yama.WithClosers(blocked))
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+		<-running
+
+		So(serve(watcher.AdminHandler(yama.AllowingAllAdmin, nil), http.MethodPost, "/abort").Code, ShouldEqual, http.StatusNoContent)
+		So(watcher.Wait(), ShouldResemble, &yama.ErrTimedOut{Uncompleted: []io.Closer{blocked}})
+	})
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+// BudgetAlert tells that the shutdown has consumed a fraction of its budget,
+// the timeout measured from the start of the notification of the closers,
+// with the closers that have not completed yet, in registration order.
+type BudgetAlert struct {
+	Fraction  float64
+	Elapsed   time.Duration
+	Remaining time.Duration
+	Pending   []io.Closer
+}
+
+// WithBudgetAlerts returns an Option that specifies a hook that is called
+// each time the shutdown has consumed one of the fractions of its budget, e.g.
+// 0.5 and 0.8, while closers have not completed, so that humans can be paged
+// before the process is killed.  The hook is called in the order of the
+// fractions, on a goroutine of its own, and should not block; extending the
+// deadline of the closers delays the alerts that are still to come.
+func WithBudgetAlerts(hook func(alert BudgetAlert), fractions ...float64) Option {
+	return withBudgetAlerts{hook: hook, fractions: fractions}
+}
+
+type withBudgetAlerts struct {
+	hook      func(alert BudgetAlert)
+	fractions []float64
+}
+
+func (w withBudgetAlerts) Apply(o *Settings) {
+	o.BudgetAlert = w.hook
+	o.BudgetFractions = w.fractions
+}
+
+// sortedFractions returns a sorted copy of the fractions.
+func sortedFractions(fractions []float64) []float64 {
+	sorted := append([]float64(nil), fractions...)
+	sort.Float64s(sorted)
+
+	return sorted
+}
+
+// alertBudget calls the budget alert hook once each fraction of the budget,
+// which started at the given time, has been consumed, until stopped or all
+// the closers have completed.
+func (w *watcher) alertBudget(start time.Time, stop <-chan struct{}) {
+	for _, fraction := range w.budgetFractions {
+		for alerted := false; !alerted; {
+			timer := w.clock.NewTimer(w.budgetFraction(start, fraction).Sub(w.clock.Now()))
+
+			select {
+			case now := <-timer.C():
+				// the deadline may have been extended in the meantime
+				if now.Before(w.budgetFraction(start, fraction)) {
+					continue
+				}
+
+				pending := w.pendingClosers()
+				if len(pending) == 0 {
+					return
+				}
+
+				w.budgetAlert(BudgetAlert{
+					Fraction:  fraction,
+					Elapsed:   now.Sub(start),
+					Remaining: w.Remaining(),
+					Pending:   pending,
+				})
+
+				alerted = true
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+	}
+}
+
+// budgetFraction returns the time at which the fraction of the budget, which
+// started at the given time, is consumed.
+func (w *watcher) budgetFraction(start time.Time, fraction float64) time.Time {
+	w.mu.Lock()
+	budget := w.budget
+	w.mu.Unlock()
+
+	return start.Add(time.Duration(float64(budget.Sub(start)) * fraction))
+}
+
+// pendingClosers returns the notified closers that have not completed, in
+// registration order.
+func (w *watcher) pendingClosers() []io.Closer {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var pending []io.Closer
+
+	for i, closer := range w.closers {
+		if i >= len(w.statuses) || w.statuses[i].end.IsZero() {
+			pending = append(pending, closer)
+		}
+	}
+
+	return pending
+}
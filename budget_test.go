@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestBudgetAlerts(t *testing.T) {
+
+	Convey("Ensure alerts are raised as the budget is consumed, with the pending closers", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		alerts := make(chan yama.BudgetAlert, 2)
+		release := make(chan struct{})
+
+		spy := yamatest.CloserSpy("cache")
+		slow := yama.FnAsCloser(func() { <-release })
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(10*time.Second),
+			yama.WithClosers(spy, slow),
+			yama.WithBudgetAlerts(func(alert yama.BudgetAlert) { alerts <- alert }, 0.8, 0.5))
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+
+		clock.BlockUntil(2)
+		for watcher.Status().Closers[0].State != "done" {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(5 * time.Second)
+
+		alert := <-alerts
+		So(alert.Fraction, ShouldEqual, 0.5)
+		So(alert.Elapsed, ShouldEqual, 5*time.Second)
+		So(alert.Remaining, ShouldEqual, 5*time.Second)
+		So(alert.Pending, ShouldHaveLength, 1)
+		So(alert.Pending[0] == slow, ShouldBeTrue)
+
+		clock.BlockUntil(2)
+		clock.Advance(3 * time.Second)
+
+		alert = <-alerts
+		So(alert.Fraction, ShouldEqual, 0.8)
+		So(alert.Pending, ShouldHaveLength, 1)
+		So(alert.Pending[0] == slow, ShouldBeTrue)
+
+		close(release)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure extending the deadline delays the alerts", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		alerts := make(chan yama.BudgetAlert, 1)
+		release := make(chan struct{})
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(10*time.Second),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })),
+			yama.WithBudgetAlerts(func(alert yama.BudgetAlert) { alerts <- alert }, 0.5))
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+
+		clock.BlockUntil(2)
+		So(watcher.ExtendDeadline(10*time.Second), ShouldBeNil)
+
+		clock.Advance(5 * time.Second)
+		clock.BlockUntil(2)
+		So(alerts, ShouldBeEmpty)
+
+		clock.Advance(5 * time.Second)
+		So((<-alerts).Elapsed, ShouldEqual, 10*time.Second)
+
+		close(release)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure invalid budget alerts are rejected", t, func() {
+		_, err := yama.NewWatcher(yama.WithBudgetAlerts(nil, 0.5))
+		So(err, ShouldBeError)
+
+		_, err = yama.NewWatcher(yama.WithBudgetAlerts(func(yama.BudgetAlert) {}, 1.5))
+		So(err, ShouldBeError)
+	})
+}
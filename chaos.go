@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// chaosInterval is the interval at which rehearsals are drawn.
+const chaosInterval = time.Minute
+
+// DailyWindow is a window of the day, whose From and To are offsets from
+// midnight, in the location of the times of the watcher's clock; a window
+// whose To is before its From spans midnight, and the zero window is the whole
+// day.
+type DailyWindow struct {
+	From, To time.Duration
+}
+
+// contains reports whether the time is in the window.
+func (d DailyWindow) contains(t time.Time) bool {
+	if d.From == d.To {
+		return true
+	}
+
+	y, m, day := t.Date()
+	offset := t.Sub(time.Date(y, m, day, 0, 0, 0, 0, t.Location()))
+
+	if d.From < d.To {
+		return offset >= d.From && offset < d.To
+	}
+
+	return offset >= d.From || offset < d.To
+}
+
+// WithChaosShutdown returns an Option that specifies that the Watcher instance
+// closes itself at random, on average probabilityPerHour times an hour during
+// the window, to rehearse the shutdown, so that closers that rot are found
+// before a real deploy finds them; it must only be specified in non-production
+// environments.  Rehearsals are a full graceful shutdown, reported by Reason()
+// as Rehearsal, and tagged as such in the report and the notifications.
+func WithChaosShutdown(probabilityPerHour float64, allowedWindow DailyWindow) Option {
+	return withChaosShutdown{probability: probabilityPerHour, window: allowedWindow}
+}
+
+type withChaosShutdown struct {
+	probability float64
+	window      DailyWindow
+}
+
+func (w withChaosShutdown) Apply(o *Settings) {
+	o.ChaosProbability = w.probability
+	o.ChaosWindow = w.window
+}
+
+// rehearse closes the instance at random, during the window, until the
+// instance is closed or stopped.
+func (w *watcher) rehearse(probabilityPerHour float64, window DailyWindow) {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// the probability that at least one rehearsal occurs during an interval,
+	// with rehearsals occurring at the given rate
+	p := 1 - math.Exp(-probabilityPerHour*chaosInterval.Hours())
+
+	for {
+		select {
+		case now := <-w.clock.After(chaosInterval):
+			if !window.contains(now) || random.Float64() >= p {
+				continue
+			}
+
+			w.mu.Lock()
+			if w.ctx.Err() == nil {
+				w.rehearsal = true
+			}
+			w.mu.Unlock()
+
+			_ = w.Close()
+
+			return
+		case <-w.ctx.Done():
+			return
+		case <-w.stop:
+			return
+		}
+	}
+}
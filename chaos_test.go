@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestChaosShutdown(t *testing.T) {
+
+	Convey("Ensure rehearsals are tagged in the report and the notifications", t, func() {
+		clock := yamatest.NewClock(time.Date(2026, 10, 14, 10, 0, 0, 0, time.Local))
+		notifications := make(chan yama.Notification, 3)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithChaosShutdown(1e9, yama.DailyWindow{From: 9 * time.Hour, To: 17 * time.Hour}),
+			yama.WithNotifiers(time.Second, notifierFunc(func(ctx context.Context, n yama.Notification) error {
+				notifications <- n
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		So(watcher.Wait(), ShouldBeNil)
+
+		reason, sig := watcher.Reason()
+		So(reason, ShouldEqual, yama.Rehearsal)
+		So(sig, ShouldBeNil)
+		So(watcher.Status().Reason, ShouldEqual, "rehearsal")
+
+		report, err := watcher.Shutdown(context.Background())
+		So(err, ShouldBeNil)
+		So(report.Rehearsal, ShouldBeTrue)
+
+		n := <-notifications
+		So(n.Event, ShouldEqual, "triggered")
+		So(n.Rehearsal, ShouldBeTrue)
+	})
+
+	Convey("Ensure no rehearsal occurs outside the window", t, func() {
+		clock := yamatest.NewClock(time.Date(2026, 10, 14, 3, 0, 0, 0, time.Local))
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithChaosShutdown(1e9, yama.DailyWindow{From: 22 * time.Hour, To: 2 * time.Hour}))
+		So(err, ShouldBeNil)
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		clock.BlockUntil(1)
+		So(watcher.Status().Triggered, ShouldBeFalse)
+
+		So(watcher.Close(), ShouldBeNil)
+		reason, _ := watcher.Reason()
+		So(reason, ShouldEqual, yama.ClosedProgrammatically)
+	})
+
+	Convey("Ensure invalid rehearsals are rejected", t, func() {
+		_, err := yama.NewWatcher(yama.WithChaosShutdown(-1, yama.DailyWindow{}))
+		So(err, ShouldBeError)
+
+		_, err = yama.NewWatcher(yama.WithChaosShutdown(1, yama.DailyWindow{From: 25 * time.Hour}))
+		So(err, ShouldBeError)
+	})
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import "time"
+
+// Child creates a watcher with the options that is closed when the instance's
+// closers are notified, before the other closers, like an inline closer; the
+// child can also be closed on its own, e.g. when the module it belongs to
+// stops, and is then unregistered from the instance.  The child uses the
+// instance's clock, unless the options set another one.  The timeout of the
+// child, and its closer timeout, are capped by the instance's timeout, and
+// when the child is closed by the instance, what is left of the instance's
+// budget bounds the closers of the child.  ErrShutdown is returned if the
+// closers of the instance are being, or have been, notified.
+func (w *watcher) Child(options ...Option) (*Watcher, error) {
+	options = append(append([]Option{withClock{clock: w.clock}}, options...), cappingTimeout{max: w.timeout})
+
+	child, err := NewWatcher(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	closer := childCloser{parent: w, w: child}
+	if err := w.AddCloser(closer); err != nil {
+		child.Stop()
+		return nil, err
+	}
+
+	// a child closed on its own no longer needs to be closed by the instance,
+	// which is left alone if it is the one closing the child
+	child.addAfterClosers(func() { _ = w.RemoveCloser(closer) })
+
+	return child, nil
+}
+
+// cappingTimeout caps the timeout, and the closer timeout, of a child to the
+// timeout of its parent.
+type cappingTimeout struct{ max time.Duration }
+
+func (c cappingTimeout) Apply(o *Settings) {
+	if o.TimeOut > c.max {
+		o.TimeOut = c.max
+	}
+
+	if o.CloserTimeOut > o.TimeOut {
+		o.CloserTimeOut = o.TimeOut
+	}
+}
+
+// childCloser closes a child watcher inline, within what is left of the
+// budget of its parent.
+type childCloser struct {
+	parent *watcher
+	w      *Watcher
+}
+
+func (c childCloser) Close() error {
+	remaining := c.parent.Remaining()
+
+	c.w.mu.Lock()
+	c.w.budgetCap = c.w.clock.Now().Add(remaining)
+	c.w.mu.Unlock()
+
+	return c.w.Close()
+}
+
+func (childCloser) Inline() {}
+
+func (c childCloser) String() string {
+	return "child " + c.w.String()
+}
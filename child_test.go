@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestChild(t *testing.T) {
+
+	Convey("Ensure closing the parent closes the children first", t, func() {
+		var order []string
+
+		parent, err := yama.NewWatcher(yama.WithClosers(yama.FnAsCloser(func() { order = append(order, "parent") })))
+		So(err, ShouldBeNil)
+
+		child, err := parent.Child(yama.WithClosers(yama.FnAsCloser(func() { order = append(order, "child") })))
+		So(err, ShouldBeNil)
+
+		So(parent.Close(), ShouldBeNil)
+		So(order, ShouldResemble, []string{"child", "parent"})
+		So(child.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure children can be closed on their own", t, func() {
+		parent, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		child, err := parent.Child()
+		So(err, ShouldBeNil)
+
+		So(child.Close(), ShouldBeNil)
+		So(parent.String(), ShouldContainSubstring, "state: watching")
+		So(parent.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure the timeout of children is capped by the parent's", t, func() {
+		parent, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		child, err := parent.Child(yama.WithTimeout(time.Minute))
+		So(err, ShouldBeNil)
+		So(child.String(), ShouldContainSubstring, "timeout: 1s")
+
+		So(parent.Close(), ShouldBeNil)
+		_, err = parent.Child()
+		So(err, ShouldEqual, yama.ErrShutdown)
+	})
+	Convey("Ensure the closer timeout of children is capped by the parent's timeout", t, func() {
+		parent, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		child, err := parent.Child(yama.WithTimeout(time.Minute), yama.WithCloserTimeout(30*time.Second))
+		So(err, ShouldBeNil)
+		So(child.String(), ShouldContainSubstring, "timeout: 1s")
+
+		So(parent.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure children are bounded by what is left of the parent's budget", t, func() {
+		var remaining time.Duration
+
+		parent, err := yama.NewWatcher(yama.WithTimeout(time.Second),
+			yama.WithClosers(yama.InlineFnAsCloser(func() { time.Sleep(300 * time.Millisecond) })))
+		So(err, ShouldBeNil)
+
+		var child *yama.Watcher
+		child, err = parent.Child(yama.WithClosers(yama.FnAsCloser(func() { remaining = child.Remaining() })))
+		So(err, ShouldBeNil)
+
+		So(parent.Close(), ShouldBeNil)
+		So(remaining, ShouldBeGreaterThan, 0)
+		So(remaining, ShouldBeLessThanOrEqualTo, 700*time.Millisecond)
+	})
+
+	Convey("Ensure children use the clock of the parent", t, func() {
+		var remaining time.Duration
+
+		clock := yamatest.NewClock(time.Now())
+		parent, err := yama.NewWatcher(yama.WithClock(clock), yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		var child *yama.Watcher
+		child, err = parent.Child(yama.WithClosers(yama.FnAsCloser(func() { remaining = child.Remaining() })))
+		So(err, ShouldBeNil)
+
+		So(parent.Close(), ShouldBeNil)
+		So(remaining, ShouldEqual, time.Second)
+	})
+
+	Convey("Ensure children closed on their own are unregistered from the parent", t, func() {
+		parent, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		child, err := parent.Child()
+		So(err, ShouldBeNil)
+
+		So(child.Close(), ShouldBeNil)
+		So(parent.String(), ShouldContainSubstring, "closers: 0")
+		So(parent.Close(), ShouldBeNil)
+	})
+}
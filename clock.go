@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock provides the time to a watcher; tests can provide a clock that they
+// advance instantly instead of sleeping through timeouts.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// SleepClock is implemented by clocks that can tell how long the system has
+// slept, e.g. a laptop whose lid was closed, for the watcher to detect it;
+// the clock of the OS does.
+type SleepClock interface {
+	Clock
+
+	// Slept returns how long the system has slept since an arbitrary point
+	// in time.  The clock of the OS tells it from how far the wall clock has
+	// jumped ahead of the monotonic clock, so that setting the wall clock by
+	// hand, or NTP stepping it, counts as well, and a step backward decreases
+	// the value, possibly below zero.
+	Slept() time.Duration
+}
+
+// Timer is a timer created by a Clock; its methods have the same semantics as
+// the field and methods of time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// realClock is the clock of the OS.
+type realClock struct{}
+
+var _ SleepClock = realClock{}
+
+// booted is the point in time from which the clock of the OS measures how
+// long the system has slept.
+var booted = time.Now()
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// Slept returns how far the wall clock has jumped ahead of the monotonic
+// clock, which does not advance while the system sleeps; the wall clock can
+// also be set by hand, or stepped by NTP, either way, so the value is negative
+// once it has been stepped back by more than the system slept.
+func (realClock) Slept() time.Duration {
+	now := time.Now()
+
+	return now.Round(0).Sub(booted.Round(0)) - now.Sub(booted)
+}
+
+// clockKey is the key of the clock carried by a context.
+type clockKey struct{}
+
+// contextWithClock returns a copy of the context that carries the clock, so
+// that the deadline of the context is measured with it.
+func contextWithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// clockOf returns the clock carried by the context, or the clock of the OS.
+func clockOf(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+
+	return realClock{}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (r realTimer) C() <-chan time.Time {
+	return r.t.C
+}
+
+func (r realTimer) Stop() bool {
+	return r.t.Stop()
+}
+
+// deadlineContext is a context that reports a deadline but that is only
+// expired when the watcher's timeout fires, so that it follows the watcher's
+// clock rather than the clock of the OS.
+type deadlineContext struct {
+	context.Context
+	cancel   context.CancelFunc
+	changed  chan struct{}
+	mu       sync.Mutex
+	deadline time.Time
+	err      error
+}
+
+func newDeadlineContext(deadline time.Time) *deadlineContext {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &deadlineContext{Context: ctx, cancel: cancel, changed: make(chan struct{}, 1), deadline: deadline}
+}
+
+func (c *deadlineContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.deadline, true
+}
+
+// setDeadline moves the deadline, for the timer that expires the context to
+// be reset; closers that already read the deadline are not told.
+func (c *deadlineContext) setDeadline(deadline time.Time) {
+	c.mu.Lock()
+	c.deadline = deadline
+	c.mu.Unlock()
+
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (c *deadlineContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.Context.Err()
+}
+
+// expire cancels the context with context.DeadlineExceeded.
+func (c *deadlineContext) expire() {
+	c.mu.Lock()
+	if c.err == nil && c.Context.Err() == nil {
+		c.err = context.DeadlineExceeded
+	}
+	c.mu.Unlock()
+
+	c.cancel()
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// SignalCombination is the shutdown policy of signals that occur together,
+// e.g. SIGTERM and SIGUSR1 for a fast shutdown, which some init systems send
+// to tell their intent.
+type SignalCombination struct {
+	Signals []os.Signal
+	Policy  SignalPolicy
+}
+
+// String returns the names of the signals joined with plus signs, e.g.
+// SIGTERM+SIGUSR1.
+func (c SignalCombination) String() string {
+	names := make([]string, len(c.Signals))
+	for i, sig := range c.Signals {
+		names[i] = signalName(sig)
+	}
+
+	return strings.Join(names, "+")
+}
+
+// CombiningSignals returns an Option that specifies a window, e.g. 200ms, that
+// the Watcher instance waits for, after the watched signal that triggers the
+// shutdown, before notifying its closers, to collect the signals of the
+// combinations; the policy of the largest combination whose signals all
+// occurred within the window, before or after the triggering signal, overrides
+// the policy of that signal.  At least one signal of each combination must be
+// watched; the others are ignored outside the window.
+func CombiningSignals(window time.Duration, combinations ...SignalCombination) Option {
+	return combiningSignals{window: window, combinations: combinations}
+}
+
+type combiningSignals struct {
+	window       time.Duration
+	combinations []SignalCombination
+}
+
+func (c combiningSignals) Apply(o *Settings) {
+	o.CombiningWindow = c.window
+	o.SignalCombinations = c.combinations
+}
+
+// combinationSignals returns the signals of the combinations that are not
+// watched.
+func combinationSignals(combinations []SignalCombination, watched []os.Signal) []os.Signal {
+	var signals []os.Signal
+
+	for _, c := range combinations {
+		for _, sig := range c.Signals {
+			if !watches(watched, sig) && !watches(signals, sig) {
+				signals = append(signals, sig)
+			}
+		}
+	}
+
+	return signals
+}
+
+// collectSignals collects the signals that occur within the combining window
+// after the triggering signal, in addition to the signals of the combinations
+// that occurred within the window before it; it reports false if the instance
+// is stopped first.  The window is cut short if the instance is closed.
+func (w *watcher) collectSignals(sig os.Signal, early map[os.Signal]time.Time) (map[os.Signal]bool, bool) {
+	now := w.clock.Now()
+	collected := map[os.Signal]bool{sig: true}
+
+	for s, at := range early {
+		if now.Sub(at) < w.combiningWindow {
+			collected[s] = true
+		}
+	}
+
+	timer := w.clock.NewTimer(w.combiningWindow)
+	defer timer.Stop()
+
+	for {
+		select {
+		case s := <-w.signals:
+			w.countSignal(s)
+			collected[s] = true
+		case s := <-w.combining:
+			w.countSignal(s)
+			collected[s] = true
+		case <-timer.C():
+			return collected, true
+		case <-w.done:
+			return collected, true
+		case <-w.stop:
+			return nil, false
+		}
+	}
+}
+
+// combinedPolicy returns the policy of the largest combination whose signals
+// were all collected, if any.
+func (w *watcher) combinedPolicy(collected map[os.Signal]bool) (SignalPolicy, bool) {
+	var policy SignalPolicy
+	size := 0
+
+	for _, c := range w.combinations {
+		if len(c.Signals) <= size {
+			continue
+		}
+
+		all := true
+		for _, sig := range c.Signals {
+			all = all && collected[sig]
+		}
+
+		if all {
+			policy, size = c.Policy, len(c.Signals)
+		}
+	}
+
+	return policy, size > 0
+}
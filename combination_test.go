@@ -0,0 +1,137 @@
+//go:build !js
+// +build !js
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+// awaitSignal waits for the watcher to have received the signal the given
+// number of times.
+func awaitSignal(watcher *yama.Watcher, sig os.Signal, count int) {
+	for {
+		for _, stat := range watcher.SignalStats() {
+			if stat.Signal == sig && stat.Count >= count {
+				return
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCombiningSignals(t *testing.T) {
+
+	newWatcher := func(clock *yamatest.Clock, signals *yamatest.Signals, deadlines chan time.Time) *yama.Watcher {
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.CombiningSignals(200*time.Millisecond, yama.SignalCombination{
+				Signals: []os.Signal{syscall.SIGTERM, syscall.SIGHUP},
+				Policy:  yama.SignalPolicy{TimeOut: 2 * time.Second},
+			}),
+			yama.WithClosers(yama.ContextFnAsCloser(func(ctx context.Context) error {
+				deadline, _ := ctx.Deadline()
+				deadlines <- deadline
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		return watcher
+	}
+
+	Convey("Ensure the policy of a combination applies when its signals occur within the window", t, func() {
+		start := time.Now()
+		clock := yamatest.NewClock(start)
+		signals := yamatest.NewSignals()
+		deadlines := make(chan time.Time, 1)
+		watcher := newWatcher(clock, signals, deadlines)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		clock.BlockUntil(1)
+
+		So(signals.Send(syscall.SIGHUP), ShouldBeTrue)
+		awaitSignal(watcher, syscall.SIGHUP, 1)
+
+		clock.Advance(200 * time.Millisecond)
+		So(watcher.Wait(), ShouldBeNil)
+		So(<-deadlines, ShouldEqual, start.Add(200*time.Millisecond+2*time.Second))
+	})
+
+	Convey("Ensure the signals of a combination that occur before the triggering signal count", t, func() {
+		start := time.Now()
+		clock := yamatest.NewClock(start)
+		signals := yamatest.NewSignals()
+		deadlines := make(chan time.Time, 1)
+		watcher := newWatcher(clock, signals, deadlines)
+
+		So(signals.Send(syscall.SIGHUP), ShouldBeTrue)
+		awaitSignal(watcher, syscall.SIGHUP, 1)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		clock.BlockUntil(1)
+		clock.Advance(200 * time.Millisecond)
+		So(watcher.Wait(), ShouldBeNil)
+		So(<-deadlines, ShouldEqual, start.Add(200*time.Millisecond+2*time.Second))
+	})
+
+	Convey("Ensure the policy of the signal applies when the combination is incomplete", t, func() {
+		start := time.Now()
+		clock := yamatest.NewClock(start)
+		signals := yamatest.NewSignals()
+		deadlines := make(chan time.Time, 1)
+		watcher := newWatcher(clock, signals, deadlines)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		clock.BlockUntil(1)
+		clock.Advance(200 * time.Millisecond)
+		So(watcher.Wait(), ShouldBeNil)
+		So(<-deadlines, ShouldEqual, start.Add(200*time.Millisecond+time.Minute))
+	})
+
+	Convey("Ensure invalid combinations are rejected", t, func() {
+		combination := yama.SignalCombination{
+			Signals: []os.Signal{syscall.SIGINT, syscall.SIGHUP},
+			Policy:  yama.SignalPolicy{TimeOut: time.Second},
+		}
+
+		_, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.CombiningSignals(time.Second, combination))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldContainSubstring, "combination SIGINT+SIGHUP must have a watched signal")
+
+		_, err = yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGINT),
+			yama.CombiningSignals(0, combination))
+		So(err, ShouldBeError)
+	})
+}
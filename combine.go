@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import "sync"
+
+// Combined aggregates several watchers, e.g. the watchers brought by the
+// libraries of an application, so that main() closes and waits for them at
+// once.
+type Combined struct {
+	watchers []Shutdowner
+}
+
+// Combine aggregates the watchers.
+func Combine(watchers ...Shutdowner) *Combined {
+	return &Combined{watchers: append([]Shutdowner(nil), watchers...)}
+}
+
+// Close all the watchers concurrently, and wait for them; see Wait().
+func (c *Combined) Close() error {
+	var wg sync.WaitGroup
+
+	for _, w := range c.watchers {
+		wg.Add(1)
+
+		go func(w Shutdowner) {
+			defer wg.Done()
+			_ = w.Close()
+		}(w)
+	}
+
+	wg.Wait()
+
+	return c.Wait()
+}
+
+// Wait until all the watchers have notified their closers, and return their
+// errors, combined by JoinErrors().
+func (c *Combined) Wait() error {
+	errs := make([]error, len(c.watchers))
+
+	for i, w := range c.watchers {
+		errs[i] = w.Wait()
+	}
+
+	return JoinErrors(errs...)
+}
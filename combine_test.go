@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestCombine(t *testing.T) {
+
+	Convey("Ensure closing combined watchers closes them all", t, func() {
+		db, cache := yamatest.CloserSpy("db"), yamatest.CloserSpy("cache")
+		first, err := yama.NewWatcher(yama.WithClosers(db))
+		So(err, ShouldBeNil)
+		second, err := yama.NewWatcher(yama.WithClosers(cache))
+		So(err, ShouldBeNil)
+
+		So(yama.Combine(first, second).Close(), ShouldBeNil)
+		So(db, yamatest.ShouldHaveBeenClosed)
+		So(cache, yamatest.ShouldHaveBeenClosed)
+	})
+
+	Convey("Ensure waiting returns once all the watchers have completed", t, func() {
+		first, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+		second, err := yama.NewWatcher(
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithClosers(yama.FnAsCloser(func() { time.Sleep(time.Second) })))
+		So(err, ShouldBeNil)
+
+		combined := yama.Combine(first, second)
+
+		done := make(chan error, 1)
+		go func() { done <- combined.Wait() }()
+
+		So(first.Close(), ShouldBeNil)
+
+		time.Sleep(10 * time.Millisecond)
+		So(done, ShouldBeEmpty)
+
+		So(second.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(<-done, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+	})
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Component is a background component of an application, supervised by a
+// watcher.
+type Component interface {
+	// Start runs the component until it is stopped, or dies.
+	Start() error
+
+	// Stop makes Start return; it is called with a context whose deadline is
+	// the end of the watcher's timeout.
+	Stop(ctx context.Context) error
+}
+
+// RestartPolicy specifies how components that die are restarted.
+type RestartPolicy struct {
+	// MaxRestarts is the number of times a component is restarted before the
+	// watcher is closed.
+	MaxRestarts int
+
+	// Backoff is how long to wait before restarting a component the first
+	// time; it doubles with each subsequent restart.
+	Backoff time.Duration
+
+	// MaxBackoff bounds how long to wait before restarting a component; zero
+	// means no bound.
+	MaxBackoff time.Duration
+}
+
+// Go starts the component in its own goroutine and registers its Stop()
+// method as a closer.  If the component dies, i.e. Start() returns before the
+// closers are notified, it is restarted according to the policy; once it died
+// more than the policy allows, the watcher is closed since the application can
+// no longer operate.
+func (w *watcher) Go(component Component, policy RestartPolicy) error {
+	if component == nil {
+		return errors.New("component must not be null")
+	}
+
+	if policy.MaxRestarts < 0 || policy.Backoff < 0 || policy.MaxBackoff < 0 {
+		return errors.New("restart policy must not be negative")
+	}
+
+	if err := w.AddCloser(ContextFnAsCloser(component.Stop)); err != nil {
+		return err
+	}
+
+	go func() {
+		backoff := policy.Backoff
+
+		for restarts := 0; ; restarts++ {
+			_ = component.Start()
+
+			if w.ctx.Err() != nil {
+				return
+			}
+
+			if restarts == policy.MaxRestarts {
+				_ = w.Close()
+				return
+			}
+
+			select {
+			case <-w.clock.After(backoff):
+			case <-w.ctx.Done():
+				return
+			}
+
+			if backoff *= 2; policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+// component dies as soon as it is started, unless it is told to keep running.
+type component struct {
+	mu      sync.Mutex
+	starts  int
+	running chan struct{}
+	stopped bool
+}
+
+func (c *component) Start() error {
+	c.mu.Lock()
+	c.starts++
+	running := c.running
+	c.mu.Unlock()
+
+	if running != nil {
+		<-running
+	}
+
+	return errors.New("died")
+}
+
+func (c *component) Stop(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	if c.running != nil {
+		close(c.running)
+		c.running = nil
+	}
+
+	return nil
+}
+
+func (c *component) Starts() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.starts
+}
+
+func TestGo(t *testing.T) {
+
+	Convey("Ensure dying components are restarted before the watcher is closed", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		c := &component{}
+
+		watcher, err := yama.NewWatcher(yama.WithClock(clock))
+		So(err, ShouldBeNil)
+
+		So(watcher.Go(c, yama.RestartPolicy{MaxRestarts: 2, Backoff: time.Second}), ShouldBeNil)
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		clock.BlockUntil(1)
+		clock.Advance(2 * time.Second)
+
+		So(watcher.Wait(), ShouldBeNil)
+		So(c.Starts(), ShouldEqual, 3)
+		So(c.stopped, ShouldBeTrue)
+	})
+
+	Convey("Ensure components are stopped when the closers are notified", t, func() {
+		c := &component{running: make(chan struct{})}
+
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		So(watcher.Go(c, yama.RestartPolicy{MaxRestarts: 1}), ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		time.Sleep(10 * time.Millisecond)
+		So(c.Starts(), ShouldEqual, 1)
+	})
+
+	Convey("Ensure invalid restart policies are rejected", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		So(watcher.Go(&component{}, yama.RestartPolicy{MaxRestarts: -1}), ShouldBeError)
+		So(watcher.Go(nil, yama.RestartPolicy{}), ShouldBeError)
+	})
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config holds the settings of a Watcher instance in a form that can be part
+// of an application's configuration file; durations are strings such as
+// "30s", and signals are names such as "SIGTERM".  Empty fields leave the
+// corresponding settings alone, and the other fields override the settings of
+// the registered profile, if any.
+type Config struct {
+	Profile    string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Timeout    string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Signals    []string `json:"signals,omitempty" yaml:"signals,omitempty"`
+	DrainDelay string   `json:"drainDelay,omitempty" yaml:"drainDelay,omitempty"`
+	Escalation string   `json:"escalation,omitempty" yaml:"escalation,omitempty"`
+}
+
+// Options returns the options that specify the settings of the configuration,
+// or an error if one of its fields is invalid.
+func (c *Config) Options() ([]Option, error) {
+	var options []Option
+
+	if c.Profile != "" {
+		if _, ok := lookupProfile(c.Profile); !ok {
+			return nil, fmt.Errorf("unknown profile %q", c.Profile)
+		}
+
+		options = append(options, WithProfile(c.Profile))
+	}
+
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", c.Timeout, err)
+		}
+
+		options = append(options, WithTimeout(d))
+	}
+
+	if len(c.Signals) > 0 {
+		signals, err := parseSignals(strings.Join(c.Signals, ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid signals %q: %w", c.Signals, err)
+		}
+
+		options = append(options, WatchingSignals(signals...))
+	}
+
+	if c.DrainDelay != "" {
+		d, err := time.ParseDuration(c.DrainDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid drain delay %q: %w", c.DrainDelay, err)
+		}
+
+		options = append(options, WithDrainDelay(d))
+	}
+
+	if c.Escalation != "" {
+		e, err := parseEscalation(c.Escalation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid escalation %q: %w", c.Escalation, err)
+		}
+
+		options = append(options, WithEscalation(e))
+	}
+
+	return options, nil
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"encoding/json"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestConfig(t *testing.T) {
+
+	Convey("Ensure configurations are turned into options", t, func() {
+		var c yama.Config
+		err := json.Unmarshal([]byte(`{
+			"timeout": "30s",
+			"signals": ["SIGTERM", "SIGINT"],
+			"drainDelay": "5s",
+			"escalation": "exit"
+		}`), &c)
+		So(err, ShouldBeNil)
+
+		options, err := c.Options()
+		So(err, ShouldBeNil)
+
+		s := &yama.Settings{}
+		for _, option := range options {
+			option.Apply(s)
+		}
+
+		So(s.TimeOut, ShouldEqual, 30*time.Second)
+		So(s.Signals, ShouldResemble, []os.Signal{syscall.SIGTERM, syscall.SIGINT})
+		So(s.DrainDelay, ShouldEqual, 5*time.Second)
+		So(s.Escalation, ShouldEqual, yama.ExitingOnRepeat)
+	})
+
+	Convey("Ensure empty configurations have no options", t, func() {
+		options, err := (&yama.Config{}).Options()
+		So(err, ShouldBeNil)
+		So(options, ShouldBeEmpty)
+	})
+
+	Convey("Ensure invalid configurations are reported", t, func() {
+		_, err := (&yama.Config{Timeout: "soon"}).Options()
+		So(err, ShouldBeError)
+
+		_, err = (&yama.Config{Signals: []string{"SIGNOPE"}}).Options()
+		So(err, ShouldBeError)
+	})
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// confirmation holds the settings of the confirmation of interrupts.
+type confirmation struct {
+	prompt  string
+	timeout time.Duration
+	in      io.Reader
+	out     io.Writer
+	reading sync.Once
+	answers chan string
+}
+
+// confirmInterrupt reports whether the signal triggers the notification of
+// the closers: signals other than interrupts always do, and interrupts do
+// once confirmed, if confirmation is configured.
+func (w *watcher) confirmInterrupt(sig os.Signal) bool {
+	c := &w.confirmation
+	if c.prompt == "" || sig != os.Interrupt {
+		return true
+	}
+
+	c.reading.Do(func() { go c.read() })
+
+	// drop the answer given before the prompt, if any
+	select {
+	case <-c.answers:
+	default:
+	}
+
+	_, _ = fmt.Fprint(c.out, c.prompt+" ")
+
+	timer := w.clock.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case answer := <-c.answers:
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	case <-w.signals:
+		_, _ = fmt.Fprintln(c.out)
+		return true
+	case <-w.done:
+		_, _ = fmt.Fprintln(c.out)
+		return true
+	case <-w.stop:
+		return false
+	case <-timer.C():
+		_, _ = fmt.Fprintln(c.out)
+		return false
+	}
+}
+
+// read the answers until the input is closed.
+func (c *confirmation) read() {
+	scanner := bufio.NewScanner(c.in)
+
+	for scanner.Scan() {
+		c.answers <- scanner.Text()
+	}
+}
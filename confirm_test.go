@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+// confirmingWith replaces the standard input and error of the confirmation of
+// interrupts.
+type confirmingWith struct {
+	in  io.Reader
+	out io.Writer
+}
+
+func (c confirmingWith) Apply(o *yama.Settings) {
+	o.ConfirmationIn = c.in
+	o.ConfirmationOut = c.out
+}
+
+// terminal is the input and output of the confirmation of interrupts.
+type terminal struct {
+	in      *io.PipeWriter
+	written chan string
+	options []yama.Option
+}
+
+func newTerminal() *terminal {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	t := &terminal{in: inW, written: make(chan string, 10)}
+	t.options = []yama.Option{confirmingWith{in: inR, out: outW}}
+
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := outR.Read(buf)
+			if err != nil {
+				return
+			}
+			t.written <- string(buf[:n])
+		}
+	}()
+
+	return t
+}
+
+func TestInterruptConfirmation(t *testing.T) {
+
+	Convey("Ensure confirmed interrupts trigger the watcher", t, func() {
+		term := newTerminal()
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(append([]yama.Option{
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithInterruptConfirmation("really quit? [y/N]", time.Minute),
+			yama.WithClosers(closers.Closer("editor"))}, term.options...)...)
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(os.Interrupt)
+		So(<-term.written, ShouldEqual, "really quit? [y/N] ")
+
+		_, err = term.in.Write([]byte("y\n"))
+		So(err, ShouldBeNil)
+
+		So(watcher.Wait(), ShouldBeNil)
+		So(closers.Invoked(), ShouldResemble, []string{"editor"})
+	})
+
+	Convey("Ensure a second interrupt confirms the first", t, func() {
+		term := newTerminal()
+		watcher, err := yama.NewWatcher(append([]yama.Option{
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithInterruptConfirmation("really quit?", time.Minute)}, term.options...)...)
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(os.Interrupt)
+		So(<-term.written, ShouldEqual, "really quit? ")
+
+		watcher.Simulate(os.Interrupt)
+		So(<-term.written, ShouldEqual, "\n")
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure interrupts that are not confirmed are ignored", t, func() {
+		term := newTerminal()
+		clock := yamatest.NewClock(time.Now())
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(append([]yama.Option{
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithClock(clock),
+			yama.WithInterruptConfirmation("really quit?", time.Minute),
+			yama.WithClosers(closers.Closer("editor"))}, term.options...)...)
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(os.Interrupt)
+		So(<-term.written, ShouldEqual, "really quit? ")
+
+		_, err = term.in.Write([]byte("n\n"))
+		So(err, ShouldBeNil)
+		time.Sleep(10 * time.Millisecond) // let the watcher receive the answer
+
+		watcher.Simulate(os.Interrupt)
+		So(<-term.written, ShouldEqual, "really quit? ")
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		So(<-term.written, ShouldEqual, "\n")
+
+		So(closers.Invoked(), ShouldBeEmpty)
+		So(watcher.Close(), ShouldBeNil)
+		So(closers.Invoked(), ShouldResemble, []string{"editor"})
+	})
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrDefaultInUse is returned when setting the default watcher once it has
+// been set.
+var ErrDefaultInUse = errors.New("default watcher already in use")
+
+// ErrNoDefault is returned when the default watcher is used before main() has
+// set it.
+var ErrNoDefault = errors.New("default watcher not set")
+
+// defaultWatcher is the process-wide watcher returned by Default().
+var defaultWatcher struct {
+	mu sync.Mutex
+	w  *Watcher
+}
+
+// Default returns the process-wide default watcher, so that libraries deep in
+// the dependency tree can register their closers without the application
+// passing a watcher to them.  The default watcher is never created by
+// libraries, so that the signals are only captured once main() opts in, with
+// SetDefault() or Watch(); until then, ErrNoDefault is returned.
+func Default() (*Watcher, error) {
+	defaultWatcher.mu.Lock()
+	defer defaultWatcher.mu.Unlock()
+
+	if defaultWatcher.w == nil {
+		return nil, ErrNoDefault
+	}
+
+	return defaultWatcher.w, nil
+}
+
+// SetDefault sets the process-wide default watcher, e.g. a watcher created by
+// main() with the options of the application; it must be called once, or
+// ErrDefaultInUse is returned.
+func SetDefault(w *Watcher) error {
+	if w == nil {
+		return errors.New("watcher must not be nil")
+	}
+
+	defaultWatcher.mu.Lock()
+	defer defaultWatcher.mu.Unlock()
+
+	if defaultWatcher.w != nil {
+		return ErrDefaultInUse
+	}
+
+	defaultWatcher.w = w
+
+	return nil
+}
+
+// Watch creates a watcher with the options, e.g. WatchingEvents(Interrupt,
+// Terminate), and sets it as the process-wide default watcher, for main() to
+// opt in without keeping the watcher.  ErrDefaultInUse is returned, and no
+// watcher is created, if the default watcher is already set.
+func Watch(options ...Option) (*Watcher, error) {
+	defaultWatcher.mu.Lock()
+	defer defaultWatcher.mu.Unlock()
+
+	if defaultWatcher.w != nil {
+		return nil, ErrDefaultInUse
+	}
+
+	w, err := NewWatcher(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultWatcher.w = w
+
+	return w, nil
+}
+
+// resetDefault forgets the default watcher, for tests to set it again.
+func resetDefault() {
+	defaultWatcher.mu.Lock()
+	defaultWatcher.w = nil
+	defaultWatcher.mu.Unlock()
+}
+
+// Register registers a closer with the default watcher, see AddCloser().
+func Register(closer io.Closer) error {
+	w, err := Default()
+	if err != nil {
+		return err
+	}
+
+	return w.AddCloser(closer)
+}
+
+// Wait waits for the default watcher to notify its closers, see
+// Watcher.Wait().
+func Wait() error {
+	w, err := Default()
+	if err != nil {
+		return err
+	}
+
+	return w.Wait()
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestDefault(t *testing.T) {
+
+	Convey("Ensure closers registered globally are notified by the default watcher", t, func() {
+		yama.ResetDefault()
+		defer yama.ResetDefault()
+
+		signals := yamatest.NewSignals()
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		So(yama.SetDefault(nil), ShouldBeError)
+		So(yama.SetDefault(watcher), ShouldBeNil)
+		w, err := yama.Default()
+		So(err, ShouldBeNil)
+		So(w, ShouldEqual, watcher)
+		So(yama.SetDefault(watcher), ShouldEqual, yama.ErrDefaultInUse)
+
+		So(yama.Register(closers.Closer("cache")), ShouldBeNil)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(yama.Wait(), ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "cache")
+	})
+
+	Convey("Ensure there is no default watcher until main sets one", t, func() {
+		yama.ResetDefault()
+		defer yama.ResetDefault()
+
+		closers := yamatest.NewClosers()
+
+		w, err := yama.Default()
+		So(err, ShouldEqual, yama.ErrNoDefault)
+		So(w, ShouldBeNil)
+		So(yama.Register(closers.Closer("cache")), ShouldEqual, yama.ErrNoDefault)
+		So(yama.Wait(), ShouldEqual, yama.ErrNoDefault)
+	})
+
+	Convey("Ensure Watch creates the default watcher", t, func() {
+		yama.ResetDefault()
+		defer yama.ResetDefault()
+
+		signals := yamatest.NewSignals()
+		closers := yamatest.NewClosers()
+		watcher, err := yama.Watch(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		w, err := yama.Default()
+		So(err, ShouldBeNil)
+		So(w, ShouldEqual, watcher)
+
+		_, err = yama.Watch(yama.WithSignalSource(signals))
+		So(err, ShouldEqual, yama.ErrDefaultInUse)
+
+		So(yama.Register(closers.Closer("cache")), ShouldBeNil)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(yama.Wait(), ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "cache")
+	})
+
+	Convey("Ensure Watch reports invalid options without setting the default watcher", t, func() {
+		yama.ResetDefault()
+		defer yama.ResetDefault()
+
+		_, err := yama.Watch(yama.WithClock(nil))
+		So(err, ShouldBeError)
+
+		_, err = yama.Default()
+		So(err, ShouldEqual, yama.ErrNoDefault)
+	})
+}
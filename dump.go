@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// String returns a one-line description of the instance: its signals,
+// timeout, number of closers, and state.
+func (w *watcher) String() string {
+	w.mu.Lock()
+	count := len(w.closers)
+	w.mu.Unlock()
+
+	return fmt.Sprintf("yama.Watcher{signals: %v, timeout: %v, closers: %d, state: %v}",
+		signalNames(w.watched), w.timeout, count, w.state())
+}
+
+// Dump returns a multi-line description of the instance: its settings, its
+// closers in registration order, and its state.  Closers are named by their
+// Name() or String() method, if any, or else by their type.
+func (w *watcher) Dump() string {
+	w.mu.Lock()
+	closers := append([]io.Closer(nil), w.closers...)
+	w.mu.Unlock()
+
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "state: %v\n", w.state())
+	_, _ = fmt.Fprintf(&b, "signals: %v\n", signalNames(w.watched))
+	_, _ = fmt.Fprintf(&b, "timeout: %v\n", w.timeout)
+	_, _ = fmt.Fprintf(&b, "drain delay: %v\n", w.drainDelay)
+	_, _ = fmt.Fprintf(&b, "concurrency: %v\n", concurrencyName(w.concurrency))
+	_, _ = fmt.Fprintf(&b, "escalation: %v\n", escalationName(w.escalation))
+	_, _ = fmt.Fprintf(&b, "closers: %d\n", len(closers))
+
+	for i, closer := range closers {
+		_, _ = fmt.Fprintf(&b, "  %d. %v\n", i+1, closerName(closer))
+	}
+
+	return b.String()
+}
+
+// state returns the name of the state of the instance.
+func (w *watcher) state() string {
+	select {
+	case <-w.finished:
+		return "shut down"
+	default:
+	}
+
+	if w.ctx.Err() != nil {
+		return "shutting down"
+	}
+
+	return "watching"
+}
+
+func signalNames(signals []os.Signal) string {
+	if len(signals) == 0 {
+		return "none"
+	}
+
+	names := make([]string, len(signals))
+	for i, sig := range signals {
+		names[i] = sig.String()
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func concurrencyName(n int) string {
+	if n == 0 {
+		return "unlimited"
+	}
+
+	return fmt.Sprint(n)
+}
+
+func escalationName(e Escalation) string {
+	switch e {
+	case IgnoringRepeats:
+		return "ignore"
+	case ExitingOnRepeat:
+		return "exit"
+	default:
+		return fmt.Sprintf("Escalation(%d)", int(e))
+	}
+}
+
+// closerName returns the name of the closer, given by its Name() or String()
+// method, if any, or else by its type.
+func closerName(closer io.Closer) string {
+	switch c := closer.(type) {
+	case interface{ Name() string }:
+		return c.Name()
+	case fmt.Stringer:
+		return c.String()
+	default:
+		return fmt.Sprintf("%T", closer)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestDump(t *testing.T) {
+
+	Convey("Ensure watchers describe themselves", t, func() {
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithTimeout(time.Minute),
+			yama.WithConcurrency(2),
+			yama.WithClosers(yamatest.CloserSpy("db"), yama.FnAsCloser(func() {})))
+		So(err, ShouldBeNil)
+
+		So(fmt.Sprint(watcher), ShouldEqual,
+			"yama.Watcher{signals: interrupt, timeout: 1m0s, closers: 2, state: watching}")
+		So(watcher.Dump(), ShouldEqual, `state: watching
+signals: interrupt
+timeout: 1m0s
+drain delay: 0s
+concurrency: 2
+escalation: ignore
+closers: 2
+  1. db
+  2. *yama.fnWrapper
+`)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(watcher.String(), ShouldContainSubstring, "state: shut down")
+	})
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// FromEnv returns an Option that specifies the settings found in environment
+// variables whose names start with the prefix and an underscore, overriding
+// the settings of the options that precede it:
+//
+//   - PREFIX_TIMEOUT: the timeout, e.g. 30s
+//   - PREFIX_SIGNALS: the signals to watch, e.g. SIGTERM,SIGINT
+//   - PREFIX_DRAIN_DELAY: the drain delay, e.g. 5s
+//   - PREFIX_ESCALATION: what to do on repeated signals, ignore or exit
+//
+// Invalid values are reported when the Watcher instance is created.
+func FromEnv(prefix string) Option {
+	return fromEnv{prefix: prefix}
+}
+
+type fromEnv struct{ prefix string }
+
+func (f fromEnv) Apply(o *Settings) {
+	lookup := func(name string) (string, bool) {
+		return os.LookupEnv(f.prefix + "_" + name)
+	}
+
+	fail := func(name, value string, err error) {
+		o.errs = append(o.errs, fmt.Errorf("invalid %v_%v %q: %w", f.prefix, name, value, err))
+	}
+
+	if v, ok := lookup("TIMEOUT"); ok {
+		if d, err := time.ParseDuration(v); err != nil {
+			fail("TIMEOUT", v, err)
+		} else {
+			o.TimeOut = d
+		}
+	}
+
+	if v, ok := lookup("SIGNALS"); ok {
+		if signals, err := parseSignals(v); err != nil {
+			fail("SIGNALS", v, err)
+		} else {
+			o.Signals = signals
+		}
+	}
+
+	if v, ok := lookup("DRAIN_DELAY"); ok {
+		if d, err := time.ParseDuration(v); err != nil {
+			fail("DRAIN_DELAY", v, err)
+		} else {
+			o.DrainDelay = d
+		}
+	}
+
+	if v, ok := lookup("ESCALATION"); ok {
+		if e, err := parseEscalation(v); err != nil {
+			fail("ESCALATION", v, err)
+		} else {
+			o.Escalation = e
+		}
+	}
+}
+
+// parseEscalation parses the name of an escalation policy, ignore or exit.
+func parseEscalation(name string) (Escalation, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "ignore":
+		return IgnoringRepeats, nil
+	case "exit":
+		return ExitingOnRepeat, nil
+	default:
+		return IgnoringRepeats, fmt.Errorf("unknown escalation %v", name)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestFromEnv(t *testing.T) {
+
+	Convey("Ensure settings are read from the environment", t, func() {
+		for name, value := range map[string]string{
+			"TEST_YAMA_TIMEOUT":     "30s",
+			"TEST_YAMA_SIGNALS":     "SIGTERM,int",
+			"TEST_YAMA_DRAIN_DELAY": "5s",
+			"TEST_YAMA_ESCALATION":  "exit",
+		} {
+			So(os.Setenv(name, value), ShouldBeNil)
+			defer func(name string) { _ = os.Unsetenv(name) }(name)
+		}
+
+		s := &yama.Settings{TimeOut: yama.DefaultTimeout}
+		yama.FromEnv("TEST_YAMA").Apply(s)
+
+		So(s.TimeOut, ShouldEqual, 30*time.Second)
+		So(s.Signals, ShouldResemble, []os.Signal{syscall.SIGTERM, syscall.SIGINT})
+		So(s.DrainDelay, ShouldEqual, 5*time.Second)
+		So(s.Escalation, ShouldEqual, yama.ExitingOnRepeat)
+	})
+
+	Convey("Ensure unset variables leave the settings alone", t, func() {
+		s := &yama.Settings{TimeOut: time.Minute}
+		yama.FromEnv("TEST_YAMA").Apply(s)
+
+		So(s.TimeOut, ShouldEqual, time.Minute)
+		So(s.Signals, ShouldBeNil)
+	})
+
+	Convey("Ensure invalid values are reported", t, func() {
+		So(os.Setenv("TEST_YAMA_SIGNALS", "SIGNOPE"), ShouldBeNil)
+		defer func() { _ = os.Unsetenv("TEST_YAMA_SIGNALS") }()
+
+		_, err := yama.NewWatcher(yama.FromEnv("TEST_YAMA"))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "invalid TEST_YAMA_SIGNALS \"SIGNOPE\": unknown signal SIGNOPE")
+	})
+
+	Convey("Ensure all the invalid values are reported", t, func() {
+		So(os.Setenv("TEST_YAMA_TIMEOUT", "soon"), ShouldBeNil)
+		So(os.Setenv("TEST_YAMA_SIGNALS", "SIGNOPE"), ShouldBeNil)
+		defer func() {
+			_ = os.Unsetenv("TEST_YAMA_TIMEOUT")
+			_ = os.Unsetenv("TEST_YAMA_SIGNALS")
+		}()
+
+		_, err := yama.NewWatcher(yama.FromEnv("TEST_YAMA"))
+		So(err, ShouldHaveSameTypeAs, &yama.ErrMultiple{})
+		So(err.Error(), ShouldContainSubstring, "invalid TEST_YAMA_TIMEOUT \"soon\"")
+		So(err.Error(), ShouldContainSubstring, "invalid TEST_YAMA_SIGNALS \"SIGNOPE\"")
+	})
+}
+
+func TestEscalation(t *testing.T) {
+
+	Convey("Ensure repeated signals exit the process", t, func() {
+		signals := yamatest.NewSignals()
+		codes := make(chan int, 1)
+		release := make(chan struct{})
+		defer close(release)
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithEscalation(yama.ExitingOnRepeat),
+			yama.WithExitFunc(func(code int) { codes <- code }),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })))
+		So(err, ShouldBeNil)
+		defer watcher.Stop()
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		time.Sleep(10 * time.Millisecond)
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+
+		So(<-codes, ShouldEqual, 1)
+	})
+}
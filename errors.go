@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrMultiple is an error that aggregates several errors, e.g. the error
+// returned when serving traffic and the error returned by a watcher.
+type ErrMultiple struct {
+	Errors []error
+}
+
+func (e *ErrMultiple) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether any of the aggregated errors matches target.
+func (e *ErrMultiple) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first aggregated error that matches target.
+func (e *ErrMultiple) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JoinErrors aggregates the non-nil errors passed to it.  It returns nil if
+// all the errors are nil, the error itself if only one is not nil, and an
+// *ErrMultiple otherwise.
+func JoinErrors(errs ...error) error {
+	var nonNil []error
+
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	default:
+		return &ErrMultiple{Errors: nonNil}
+	}
+}
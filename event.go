@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"os"
+)
+
+// Event is a platform-neutral event that asks a process to shut down, or to
+// reload, and that is delivered by the signals that the platform uses for it.
+type Event int
+
+const (
+	// Interrupt is the interruption of the process by the user, e.g. with
+	// Ctrl-C: os.Interrupt on all platforms.
+	Interrupt Event = iota + 1
+
+	// Terminate is the request to terminate the process, e.g. by a service
+	// manager: SIGTERM, on Windows when the console is closed, or the user
+	// logs off, or the system shuts down, and the hangup note on Plan 9.
+	Terminate
+
+	// Reload is the request to reload the configuration: SIGHUP on Unix; it
+	// is not supported on other platforms.
+	Reload
+
+	// ConsoleClose is the closing of the terminal, or console, of the process:
+	// SIGHUP on Unix, the close event (SIGTERM) on Windows, and the hangup note
+	// on Plan 9.
+	ConsoleClose
+
+	// SessionEnd is the end of the session of the user, or the shutdown of
+	// the system: SIGHUP and SIGTERM on Unix, which systemd and login sessions
+	// use, the logoff and shutdown events (SIGTERM) of console applications on
+	// Windows, whose GUI applications should use HandlingSessionEnd() instead,
+	// and the unloading of the page on js.
+	SessionEnd
+)
+
+var eventNames = map[Event]string{
+	Interrupt:    "interrupt",
+	Terminate:    "terminate",
+	Reload:       "reload",
+	ConsoleClose: "console close",
+	SessionEnd:   "session end",
+}
+
+func (e Event) String() string {
+	if name, ok := eventNames[e]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Event(%d)", int(e))
+}
+
+// Signals returns the signals that deliver the event on the platform, or an
+// error if the platform does not support it.
+func (e Event) Signals() ([]os.Signal, error) {
+	signals, ok := eventSignals[e]
+	if !ok || len(signals) == 0 {
+		return nil, fmt.Errorf("event %v is not supported on this platform", e)
+	}
+
+	return append([]os.Signal(nil), signals...), nil
+}
+
+// WatchingEvents returns an Option that specifies the events to watch, like
+// WatchingSignals() does with the signals that deliver them on the platform,
+// so that cross-platform programs do not need build tags to pick signals.
+// Events that are delivered by the same signal, such as Reload and
+// ConsoleClose on Unix, cannot be told apart.  Events that the platform does
+// not support are reported by NewWatcher().
+func WatchingEvents(events ...Event) Option {
+	return watchingEvents{events: events}
+}
+
+type watchingEvents struct{ events []Event }
+
+func (w watchingEvents) Apply(o *Settings) {
+	var signals []os.Signal
+
+	for _, e := range w.events {
+		mapped, err := e.Signals()
+		if err != nil {
+			o.errs = append(o.errs, err)
+
+			continue
+		}
+
+		for _, sig := range mapped {
+			if !watches(signals, sig) {
+				signals = append(signals, sig)
+			}
+		}
+	}
+
+	o.Signals = signals
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestEvents(t *testing.T) {
+
+	Convey("Ensure watched events are delivered by the signals of the platform", t, func() {
+		signals := yamatest.NewSignals()
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingEvents(yama.Interrupt, yama.Terminate),
+			yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(os.Interrupt), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure the signals of events are not duplicated", t, func() {
+		interrupt, err := yama.Interrupt.Signals()
+		So(err, ShouldBeNil)
+		So(interrupt, ShouldResemble, []os.Signal{os.Interrupt})
+
+		watcher, err := yama.NewWatcher(yama.WatchingEvents(yama.Interrupt, yama.Terminate, yama.Interrupt))
+		So(err, ShouldBeNil)
+		watcher.Stop()
+	})
+
+	Convey("Ensure unknown events are reported", t, func() {
+		So(yama.Event(0).String(), ShouldEqual, "Event(0)")
+		So(yama.SessionEnd.String(), ShouldEqual, "session end")
+
+		_, err := yama.NewWatcher(yama.WatchingEvents(yama.Interrupt, yama.Event(0)))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "event Event(0) is not supported on this platform")
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WithStatusEvents returns an Option that specifies where to write the
+// lifecycle events of the shutdown, for process supervisors, as newline
+// delimited JSON objects with the time, the event, and, depending on the
+// event, the signal, the closer, and the error: "triggered" once the closers
+// are notified, with the signal that occurred, if any, "closing" and "closed"
+// around the call of each closer, "timedout" if the timeout fires, and
+// "completed" once the shutdown completed, with the error that Wait() returns.
+func WithStatusEvents(out io.Writer) Option {
+	return withStatusEvents{out: out}
+}
+
+// WithStatusFD returns an Option that writes the lifecycle events of the
+// shutdown, as WithStatusEvents() does, to the inherited file descriptor, e.g.
+// 3 for the first file passed by the supervisor.
+func WithStatusFD(fd uintptr) Option {
+	return withStatusEvents{out: os.NewFile(fd, "status")}
+}
+
+type withStatusEvents struct{ out io.Writer }
+
+func (w withStatusEvents) Apply(o *Settings) {
+	o.StatusEvents = w.out
+}
+
+// statusEvent is a lifecycle event of the shutdown.
+type statusEvent struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Signal string    `json:"signal,omitempty"`
+	Closer string    `json:"closer,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// events writes the lifecycle events of the shutdown; a nil instance writes
+// nothing.
+type events struct {
+	mu    sync.Mutex
+	out   io.Writer
+	clock Clock
+}
+
+func newEvents(out io.Writer, clock Clock) *events {
+	if out == nil {
+		return nil
+	}
+
+	return &events{out: out, clock: clock}
+}
+
+// emit writes the event, each as a single write; write errors are ignored
+// since the shutdown must not depend on the supervisor.
+func (e *events) emit(event string, sig os.Signal, closer io.Closer, err error) {
+	if e == nil {
+		return
+	}
+
+	ev := statusEvent{Time: e.clock.Now().UTC(), Event: event}
+
+	if sig != nil {
+		ev.Signal = signalName(sig)
+	}
+
+	if closer != nil {
+		ev.Closer = closerName(closer)
+	}
+
+	if err != nil {
+		ev.Error = err.Error()
+	}
+
+	b, _ := json.Marshal(ev)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, _ = e.out.Write(append(b, '\n'))
+}
+
+// closeNotified calls the closer, like closeWithContext(), around its
+// "closing" and "closed" events, and tracks its status, which has the index
+// in the notified closers, or -1 if it is not tracked.
+func (w *watcher) closeNotified(ctx context.Context, i int, closer io.Closer) error {
+	return w.notifyCloser(i, closer, func() error { return closeWithContext(ctx, closer) })
+}
+
+// notifyCloser calls close, which closes the closer, around the closer's
+// "closing" and "closed" events, and tracks its status, which has the index.
+func (w *watcher) notifyCloser(i int, closer io.Closer, close func() error) error {
+	w.events.emit("closing", nil, closer, nil)
+	w.trackRunning(i)
+
+	err := close()
+
+	w.trackDone(i, err)
+	w.events.emit("closed", nil, closer, err)
+
+	return err
+}
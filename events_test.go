@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestStatusEvents(t *testing.T) {
+
+	Convey("Ensure the lifecycle events of the shutdown are written", t, func() {
+		r, w := io.Pipe()
+		signals := yamatest.NewSignals()
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(signals),
+			yama.WithClock(yamatest.NewClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))),
+			yama.WithClosers(yamatest.CloserSpy("db").WithError(io.ErrClosedPipe)),
+			yama.WithStatusEvents(w))
+		So(err, ShouldBeNil)
+
+		go func() {
+			_ = watcher.Wait()
+			_ = w.Close()
+		}()
+
+		So(signals.Send(os.Interrupt), ShouldBeTrue)
+
+		b, err := ioutil.ReadAll(r)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `{"time":"2021-06-01T12:00:00Z","event":"triggered","signal":"SIGINT"}
+{"time":"2021-06-01T12:00:00Z","event":"closing","closer":"db"}
+{"time":"2021-06-01T12:00:00Z","event":"closed","closer":"db","error":"io: read/write on closed pipe"}
+{"time":"2021-06-01T12:00:00Z","event":"completed"}
+`)
+	})
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"os"
+)
+
+// Reason is the reason the closers of a watcher are notified.
+type Reason int
+
+const (
+	// ClosedProgrammatically means that the watcher was closed, e.g. by
+	// Close(), a supervised child exiting, or the watchdog.
+	ClosedProgrammatically Reason = iota
+
+	// SignalReceived means that a watched signal occurred.
+	SignalReceived
+
+	// Rehearsal means that the watcher closed itself to rehearse the
+	// shutdown, as specified by WithChaosShutdown().
+	Rehearsal
+)
+
+// Exit codes returned by ExitCode() that are not derived from a signal.
+const (
+	ExitCodeOK       = 0
+	ExitCodeError    = 1
+	ExitCodeTimedOut = 124
+)
+
+// ExitCode returns the exit code of a process shut down for the reason, with
+// the signal that occurred, if any, and the error returned by the watcher,
+// following the conventions of shells and of the timeout command:
+// ExitCodeTimedOut if not all the closers completed within the timeout,
+// ExitCodeError for other errors, 128 plus the number of the signal if a
+// signal occurred, e.g. 130 for SIGINT and 143 for SIGTERM, and ExitCodeOK
+// otherwise.  It can be passed to WithExitCode().
+func ExitCode(reason Reason, sig os.Signal, err error) int {
+	var timedOut *ErrTimedOut
+
+	switch {
+	case errors.As(err, &timedOut):
+		return ExitCodeTimedOut
+	case err != nil:
+		return ExitCodeError
+	case reason == SignalReceived:
+		if n, ok := signalNumber(sig); ok {
+			return 128 + n
+		}
+
+		return ExitCodeError
+	default:
+		return ExitCodeOK
+	}
+}
+
+// exitCode is the default exit code function: zero if all the closers
+// completed within the timeout, and one otherwise.
+func exitCode(_ Reason, _ os.Signal, err error) int {
+	if err != nil {
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
+
+// Reason returns the reason the closers are, or were, notified, and the
+// signal that occurred, if any; ClosedProgrammatically is returned if they
+// aren't.
+func (w *watcher) Reason() (Reason, os.Signal) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.received != nil {
+		return SignalReceived, w.received
+	}
+
+	if w.rehearsal {
+		return Rehearsal, nil
+	}
+
+	return ClosedProgrammatically, nil
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestExitCode(t *testing.T) {
+
+	Convey("Ensure exit codes follow the conventions of shells", t, func() {
+		So(yama.ExitCode(yama.ClosedProgrammatically, nil, nil), ShouldEqual, 0)
+		So(yama.ExitCode(yama.SignalReceived, syscall.SIGINT, nil), ShouldEqual, 130)
+		So(yama.ExitCode(yama.SignalReceived, syscall.SIGTERM, nil), ShouldEqual, 143)
+		So(yama.ExitCode(yama.SignalReceived, syscall.SIGTERM, errors.New("failed")), ShouldEqual, 1)
+		So(yama.ExitCode(yama.SignalReceived, syscall.SIGTERM, &yama.ErrTimedOut{}), ShouldEqual, 124)
+	})
+
+	Convey("Ensure the exit code is used when exiting after shutdown", t, func() {
+		codes := make(chan int, 1)
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.ExitingAfterShutdown(),
+			yama.WithExitCode(yama.ExitCode),
+			yama.WithExitFunc(func(code int) { codes <- code }))
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(syscall.SIGTERM)
+		So(<-codes, ShouldEqual, 143)
+
+		reason, sig := watcher.Reason()
+		So(reason, ShouldEqual, yama.SignalReceived)
+		So(sig, ShouldEqual, os.Signal(syscall.SIGTERM))
+	})
+
+	Convey("Ensure watchers that are closed report it", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+
+		reason, sig := watcher.Reason()
+		So(reason, ShouldEqual, yama.ClosedProgrammatically)
+		So(sig, ShouldBeNil)
+	})
+}
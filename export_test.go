@@ -0,0 +1,29 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama
+
+// ResetDefault forgets the default watcher, so that each test sets its own.
+var ResetDefault = resetDefault
+
+// DispatchCapacity returns the buffer of the channel that the dispatcher
+// registered with the signal package.
+func DispatchCapacity() int {
+	dispatch.mu.Lock()
+	defer dispatch.mu.Unlock()
+
+	return cap(dispatch.c)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"flag"
+	"strings"
+)
+
+// ConfigFlags holds the configuration of a Watcher instance set by
+// command-line flags; its Options() method returns the options that specify
+// the settings of the flags that were set.
+type ConfigFlags struct {
+	Config
+}
+
+// RegisterFlags registers the flags that configure a Watcher instance with the
+// flag set, their names starting with the prefix, e.g. "" or "api-":
+//
+//   - shutdown-timeout: the timeout, e.g. 30s
+//   - shutdown-signals: the signals to watch, e.g. SIGTERM,SIGINT
+//   - shutdown-drain-delay: the drain delay, e.g. 5s
+//   - shutdown-escalation: what to do on repeated signals, ignore or exit
+//
+// The flags can be registered with a pflag flag set by registering them with a
+// standard flag set first, and then adding it with AddGoFlagSet().
+func RegisterFlags(fs *flag.FlagSet, prefix string) *ConfigFlags {
+	c := &ConfigFlags{}
+
+	fs.StringVar(&c.Timeout, prefix+"shutdown-timeout", "", "time allowed for the shutdown, e.g. 30s")
+	fs.Var((*namesValue)(&c.Signals), prefix+"shutdown-signals", "comma-separated signals that trigger the shutdown, e.g. SIGTERM,SIGINT")
+	fs.StringVar(&c.DrainDelay, prefix+"shutdown-drain-delay", "", "delay before the shutdown starts, e.g. 5s")
+	fs.StringVar(&c.Escalation, prefix+"shutdown-escalation", "", "what to do on repeated signals, ignore or exit")
+
+	return c
+}
+
+// namesValue is a flag.Value of comma-separated names.
+type namesValue []string
+
+func (v *namesValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return strings.Join(*v, ",")
+}
+
+func (v *namesValue) Set(s string) error {
+	*v = nil
+
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*v = append(*v, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestRegisterFlags(t *testing.T) {
+
+	Convey("Ensure flags are turned into options", t, func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := yama.RegisterFlags(fs, "api-")
+
+		err := fs.Parse([]string{
+			"--api-shutdown-timeout=30s",
+			"--api-shutdown-signals=SIGTERM, SIGINT",
+			"--api-shutdown-drain-delay", "5s",
+			"--api-shutdown-escalation=exit",
+		})
+		So(err, ShouldBeNil)
+
+		options, err := flags.Options()
+		So(err, ShouldBeNil)
+
+		s := &yama.Settings{}
+		for _, option := range options {
+			option.Apply(s)
+		}
+
+		So(s.TimeOut, ShouldEqual, 30*time.Second)
+		So(s.Signals, ShouldResemble, []os.Signal{syscall.SIGTERM, syscall.SIGINT})
+		So(s.DrainDelay, ShouldEqual, 5*time.Second)
+		So(s.Escalation, ShouldEqual, yama.ExitingOnRepeat)
+	})
+
+	Convey("Ensure unset flags have no options", t, func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := yama.RegisterFlags(fs, "")
+		So(fs.Parse(nil), ShouldBeNil)
+
+		options, err := flags.Options()
+		So(err, ShouldBeNil)
+		So(options, ShouldBeEmpty)
+	})
+
+	Convey("Ensure invalid flags are reported", t, func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		flags := yama.RegisterFlags(fs, "")
+		So(fs.Parse([]string{"--shutdown-timeout=soon"}), ShouldBeNil)
+
+		_, err := flags.Options()
+		So(err, ShouldBeError)
+	})
+}
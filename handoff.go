@@ -0,0 +1,213 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// The handoff protocol is a header, made of handoffMagic and handoffVersion,
+// followed by the states, each a length-prefixed name and a length-prefixed
+// blob, and ended by handoffEnd in place of the length of a name.
+const (
+	handoffMagic   = "yama"
+	handoffVersion = 1
+	handoffEnd     = ^uint32(0)
+)
+
+// handoffMaxBlob is the largest name or blob that is handed off, so that a
+// corrupt length does not make the new process allocate gigabytes.
+const handoffMaxBlob = 256 << 20
+
+// ErrHandoffVersion is returned when taking over state handed off by a parent
+// process using an unsupported version of the handoff protocol.
+var ErrHandoffVersion = errors.New("unsupported handoff version")
+
+// state is a named state handed off to the new process on upgrades.
+type state struct {
+	name string
+	get  func() ([]byte, error)
+}
+
+// HandOff registers a named state to hand off to the new process on upgrades.
+// The function is called once the new process is ready and the watcher's
+// closers have been notified, so that the state is final; the blob is opaque
+// and should carry its own version if its format can change.  Handing off the
+// states is bounded by the watcher's timeout.
+func (u *Upgrader) HandOff(name string, get func() ([]byte, error)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.states = append(u.states, state{name: name, get: get})
+}
+
+// TakeOver waits for the states handed off by the parent process, if any, and
+// returns them by name.  It should be called once the process is ready, since
+// the parent hands off its states after its closers have been notified.
+func (u *Upgrader) TakeOver(ctx context.Context) (map[string][]byte, error) {
+	if u.handoff == nil {
+		return nil, nil
+	}
+
+	conn, err := net.FileConn(u.handoff)
+	_ = u.handoff.Close()
+	u.handoff = nil
+
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	states, err := readStates(bufio.NewReader(conn))
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return states, err
+}
+
+// handOff writes the registered states to the new process.
+func (u *Upgrader) handOff(conn net.Conn) error {
+	u.mu.Lock()
+	states := append([]state(nil), u.states...)
+	u.mu.Unlock()
+
+	// the deadline of the socket follows the clock of the OS, whatever the
+	// clock of the watcher
+	_ = conn.SetWriteDeadline(time.Now().Add(u.w.timeout))
+
+	w := bufio.NewWriter(conn)
+
+	if _, err := w.WriteString(handoffMagic); err != nil {
+		return err
+	}
+
+	if err := w.WriteByte(handoffVersion); err != nil {
+		return err
+	}
+
+	for _, s := range states {
+		blob, err := s.get()
+		if err != nil {
+			return fmt.Errorf("state %v: %w", s.name, err)
+		}
+
+		if err := writeBlob(w, []byte(s.name)); err != nil {
+			return err
+		}
+
+		if err := writeBlob(w, blob); err != nil {
+			return err
+		}
+	}
+
+	if err := binary.Write(w, binary.BigEndian, handoffEnd); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func writeBlob(w io.Writer, blob []byte) error {
+	if len(blob) > handoffMaxBlob {
+		return fmt.Errorf("handoff of %d bytes exceeds %d bytes", len(blob), handoffMaxBlob)
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint32(len(blob))); err != nil {
+		return err
+	}
+
+	_, err := w.Write(blob)
+
+	return err
+}
+
+func readStates(r io.Reader) (map[string][]byte, error) {
+	header := make([]byte, len(handoffMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if string(header[:len(handoffMagic)]) != handoffMagic || header[len(handoffMagic)] != handoffVersion {
+		return nil, ErrHandoffVersion
+	}
+
+	states := make(map[string][]byte)
+
+	for {
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+
+		if n == handoffEnd {
+			return states, nil
+		}
+
+		name, err := readBlob(r, n)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+
+		blob, err := readBlob(r, n)
+		if err != nil {
+			return nil, err
+		}
+
+		states[string(name)] = blob
+	}
+}
+
+// readBlob reads a blob of n bytes, once checked that it is not too large.
+func readBlob(r io.Reader, n uint32) ([]byte, error) {
+	if n > handoffMaxBlob {
+		return nil, fmt.Errorf("handoff of %d bytes exceeds %d bytes", n, handoffMaxBlob)
+	}
+
+	blob := make([]byte, n)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return nil, err
+	}
+
+	return blob, nil
+}
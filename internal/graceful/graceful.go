@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package graceful holds the serving logic shared by the framework helpers.
+package graceful // import "l7e.io/yama/internal/graceful"
+
+import (
+	"context"
+	"net/http"
+
+	"l7e.io/yama"
+)
+
+// Run registers shutdown as a closer of the watcher and then calls serve,
+// which is expected to block until shutdown is called.  If serve fails for any
+// other reason the watcher is closed, since the application can no longer
+// serve traffic.  Once serve returns, Run waits for the watcher and returns
+// the errors of serve, shutdown, and the watcher.
+func Run(w yama.Shutdowner, serve func() error, shutdown func(ctx context.Context) error) error {
+	shutdownErrs := make(chan error, 1)
+
+	err := w.AddCloser(yama.ContextFnAsCloser(func(ctx context.Context) error {
+		err := shutdown(ctx)
+		shutdownErrs <- err
+
+		return err
+	}))
+	if err != nil {
+		return err
+	}
+
+	err = serve()
+	if err == http.ErrServerClosed {
+		err = nil
+	} else {
+		_ = w.Close()
+	}
+
+	waitErr := w.Wait()
+
+	// the shutdown may not have completed if the watcher timed out
+	var shutdownErr error
+	select {
+	case shutdownErr = <-shutdownErrs:
+	default:
+	}
+
+	return yama.JoinErrors(err, shutdownErr, waitErr)
+}
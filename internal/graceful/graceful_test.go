@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graceful_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/internal/graceful"
+)
+
+func TestRun(t *testing.T) {
+
+	Convey("Ensure shutdown is called when the watcher is closed", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		stopped := make(chan struct{})
+		hasDeadline := false
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_ = watcher.Close()
+		}()
+
+		err = graceful.Run(watcher,
+			func() error {
+				<-stopped
+				return http.ErrServerClosed
+			},
+			func(ctx context.Context) error {
+				_, hasDeadline = ctx.Deadline()
+				close(stopped)
+				return nil
+			})
+		So(err, ShouldBeNil)
+		So(hasDeadline, ShouldBeTrue)
+	})
+
+	Convey("Ensure serve and shutdown errors are aggregated", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		serveErr := errors.New("address in use")
+		shutdownErr := errors.New("shutdown failed")
+
+		err = graceful.Run(watcher,
+			func() error { return serveErr },
+			func(ctx context.Context) error { return shutdownErr })
+		So(err, ShouldHaveSameTypeAs, &yama.ErrMultiple{})
+		So(errors.Is(err, serveErr), ShouldBeTrue)
+		So(errors.Is(err, shutdownErr), ShouldBeTrue)
+	})
+}
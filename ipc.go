@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"io"
+	"strings"
+)
+
+// MunmapCloser returns a closer that unmaps the memory mapping, e.g. as returned
+// by syscall.Mmap(), or by syscall.MapViewOfFile() on Windows.
+func MunmapCloser(b []byte) io.Closer {
+	return &ipcCloser{name: "munmap", close: func() error { return munmap(b) }}
+}
+
+// ShmUnlinkCloser returns a closer that unlinks the POSIX shared memory object
+// with the name, as passed to shm_open(), so that it does not outlive the
+// process; objects that do not exist are ignored.  The closers of POSIX IPC
+// resources should be registered with WithEmergencyClosers() so that they are
+// unlinked even when the timeout fires.
+func ShmUnlinkCloser(name string) io.Closer {
+	return &ipcCloser{name: "shm_unlink " + name, close: func() error { return unlinkShm(shmName(name)) }}
+}
+
+// SemUnlinkCloser returns a closer that unlinks the POSIX named semaphore with
+// the name, as passed to sem_open(), so that it does not outlive the process;
+// semaphores that do not exist are ignored.
+func SemUnlinkCloser(name string) io.Closer {
+	return &ipcCloser{name: "sem_unlink " + name, close: func() error { return unlinkShm("sem." + shmName(name)) }}
+}
+
+// shmName returns the name of a POSIX IPC resource without its leading slash.
+func shmName(name string) string {
+	return strings.TrimPrefix(name, "/")
+}
+
+type ipcCloser struct {
+	name  string
+	close func() error
+}
+
+func (c *ipcCloser) Close() error {
+	return c.close()
+}
+
+func (c *ipcCloser) String() string {
+	return c.name
+}
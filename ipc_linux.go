@@ -0,0 +1,35 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// unlinkShm removes the file of a POSIX IPC resource, which glibc and musl
+// create in /dev/shm.
+func unlinkShm(name string) error {
+	if err := os.Remove(filepath.Join("/dev/shm", name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
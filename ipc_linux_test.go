@@ -0,0 +1,63 @@
+//go:build linux
+// +build linux
+
+package yama_test
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestIPCClosers(t *testing.T) {
+
+	Convey("Ensure POSIX IPC resources are unlinked and mappings unmapped", t, func() {
+		name := fmt.Sprintf("yama-%d", os.Getpid())
+		shm := filepath.Join("/dev/shm", name)
+		sem := filepath.Join("/dev/shm", "sem."+name)
+
+		for _, path := range []string{shm, sem} {
+			f, err := os.Create(path)
+			So(err, ShouldBeNil)
+			So(f.Close(), ShouldBeNil)
+		}
+
+		b, err := syscall.Mmap(-1, 0, 4096, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+		So(err, ShouldBeNil)
+
+		watcher, err := yama.NewWatcher(yama.WithEmergencyClosers(time.Second,
+			yama.MunmapCloser(b), yama.ShmUnlinkCloser("/"+name), yama.SemUnlinkCloser(name)))
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+
+		for _, path := range []string{shm, sem} {
+			_, err := os.Stat(path)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		}
+
+		So(yama.ShmUnlinkCloser(name).Close(), ShouldBeNil)
+	})
+}
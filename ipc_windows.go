@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func munmap(b []byte) error {
+	if len(b) == 0 {
+		return syscall.EINVAL
+	}
+
+	return syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(&b[0])))
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrLocked is returned when locking a file that is locked by another
+// process, or by another lock of the same process.
+var ErrLocked = errors.New("file is locked")
+
+// LockFile acquires an exclusive advisory lock on the file, which is created if
+// needed, e.g. to prevent several instances of a program from running at once,
+// and returns a closer that releases it.  The closer should be registered with
+// WithEmergencyClosers() so that the lock is released even when the timeout
+// fires; the lock is also released if the process exits without releasing it.
+func LockFile(path string) (io.Closer, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the locks of the process are tracked since, on some platforms, locks
+	// are held by the process, and closing a file of a locked path releases
+	// its lock
+	lockedMu.Lock()
+	defer lockedMu.Unlock()
+
+	if locked[abs] {
+		return nil, fmt.Errorf("lock %v: %w", path, ErrLocked)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock %v: %w", path, err)
+	}
+
+	locked[abs] = true
+
+	return &fileLock{f: f, path: abs}, nil
+}
+
+var (
+	lockedMu sync.Mutex
+	locked   = make(map[string]bool)
+)
+
+type fileLock struct {
+	f    *os.File
+	path string
+}
+
+// Close releases the lock by closing the file.
+func (l *fileLock) Close() error {
+	lockedMu.Lock()
+	defer lockedMu.Unlock()
+
+	// the path may have been locked again once the lock was released
+	err := l.f.Close()
+	if err == nil {
+		delete(locked, l.path)
+	}
+
+	return err
+}
+
+func (l *fileLock) String() string {
+	return "lock " + l.f.Name()
+}
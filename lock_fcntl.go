@@ -0,0 +1,40 @@
+//go:build solaris || aix
+// +build solaris aix
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile locks the file with fcntl(), since Solaris, illumos, and AIX have
+// no flock(); fcntl() locks are held by the process rather than by the file,
+// and are released when any file of the process for the same path is closed,
+// which LockFile() avoids by never opening a path that the process locked.
+func lockFile(f *os.File) error {
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: 0}
+
+	err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk)
+	if err == syscall.EAGAIN || err == syscall.EACCES {
+		return ErrLocked
+	}
+
+	return err
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestLockFile(t *testing.T) {
+
+	Convey("Ensure files can only be locked once at a time", t, func() {
+		dir, err := ioutil.TempDir("", "yama")
+		So(err, ShouldBeNil)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		path := filepath.Join(dir, "app.lock")
+
+		lock, err := yama.LockFile(path)
+		So(err, ShouldBeNil)
+
+		_, err = yama.LockFile(path)
+		So(errors.Is(err, yama.ErrLocked), ShouldBeTrue)
+
+		watcher, err := yama.NewWatcher(yama.WithEmergencyClosers(time.Second, lock))
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+
+		lock, err = yama.LockFile(path)
+		So(err, ShouldBeNil)
+		So(lock.Close(), ShouldBeNil)
+	})
+}
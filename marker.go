@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// UncleanShutdown reports whether the marker file, see WithShutdownMarker(),
+// was left by a previous instance that did not shut down cleanly, i.e. that
+// crashed or was killed while shutting down, or whose closers did not complete
+// within the timeout.
+func UncleanShutdown(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// markShutdown writes the marker file, if any; the file is written atomically
+// so that an instance crashing while writing it never leaves a partial marker.
+func (w *watcher) markShutdown() {
+	if w.marker == "" {
+		return
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(w.marker), filepath.Base(w.marker)+".*")
+	if err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintf(tmp, "pid %d shutting down since %v\n", os.Getpid(), w.clock.Now().Format(time.RFC3339))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.marker)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// unmarkShutdown removes the marker file, if any, when the closers completed
+// within the timeout.
+func (w *watcher) unmarkShutdown() {
+	if w.marker == "" || w.err != nil {
+		return
+	}
+
+	_ = os.Remove(w.marker)
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestShutdownMarker(t *testing.T) {
+
+	Convey("Ensure the marker is only present while shutting down", t, func() {
+		dir, err := ioutil.TempDir("", "yama")
+		So(err, ShouldBeNil)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		marker := filepath.Join(dir, "shutdown")
+		marked := make(chan bool, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithShutdownMarker(marker),
+			yama.WithClosers(yama.FnAsCloser(func() {
+				unclean, _ := yama.UncleanShutdown(marker)
+				marked <- unclean
+			})))
+		So(err, ShouldBeNil)
+
+		unclean, err := yama.UncleanShutdown(marker)
+		So(err, ShouldBeNil)
+		So(unclean, ShouldBeFalse)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(<-marked, ShouldBeTrue)
+
+		unclean, err = yama.UncleanShutdown(marker)
+		So(err, ShouldBeNil)
+		So(unclean, ShouldBeFalse)
+	})
+
+	Convey("Ensure the marker is left when closers time out", t, func() {
+		dir, err := ioutil.TempDir("", "yama")
+		So(err, ShouldBeNil)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		marker := filepath.Join(dir, "shutdown")
+		clock := yamatest.NewClock(time.Now())
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Second),
+			yama.WithShutdownMarker(marker),
+			yama.WithClosers(yamatest.CloserSpy("slow").WithClock(clock).WithDelay(time.Hour)))
+		So(err, ShouldBeNil)
+
+		go func() {
+			clock.BlockUntil(2)
+			clock.Advance(time.Second)
+		}()
+
+		So(watcher.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+
+		unclean, err := yama.UncleanShutdown(marker)
+		So(err, ShouldBeNil)
+		So(unclean, ShouldBeTrue)
+	})
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultCgroupDir is the directory of the cgroup v2 of a container, whose
+// memory is watched unless another directory is specified.
+const DefaultCgroupDir = "/sys/fs/cgroup"
+
+// CgroupMemory specifies how the memory of a cgroup v2 is watched.
+type CgroupMemory struct {
+	// Dir is the directory of the cgroup, DefaultCgroupDir if empty.
+	Dir string
+
+	// Interval is the interval at which memory.events, and memory.pressure
+	// if a pressure is specified, are read.
+	Interval time.Duration
+
+	// Pressure is the share of time, in percent averaged over ten seconds,
+	// during which all the tasks of the cgroup were stalled on memory, the
+	// full avg10 of memory.pressure, at or above which the memory is under
+	// pressure; zero only watches for OOM kills.
+	Pressure float64
+
+	// Sustained is how long the memory must stay under pressure for the
+	// watcher to be closed.
+	Sustained time.Duration
+}
+
+// ErrMemoryPressure is the error reported by a watcher closed because the
+// memory of its cgroup ran out, as specified by WatchingCgroupMemory().
+type ErrMemoryPressure struct {
+	// OOMKills is the number of processes of the cgroup that the kernel killed
+	// since the watcher was created, or zero if the memory was under
+	// pressure.
+	OOMKills int
+
+	// Pressure is the full avg10 of memory.pressure when last read.
+	Pressure float64
+}
+
+func (e *ErrMemoryPressure) Error() string {
+	if e.OOMKills > 0 {
+		return fmt.Sprintf("cgroup memory exhausted: %d processes killed by the OOM killer", e.OOMKills)
+	}
+
+	return fmt.Sprintf("cgroup memory under pressure: all tasks stalled %.2f%% of the time", e.Pressure)
+}
+
+// WatchingCgroupMemory returns an Option that specifies that the Watcher
+// instance watches the memory of a cgroup v2 on Linux, that of the container
+// by default, and closes itself when the OOM killer killed one of its
+// processes, or when its memory has been under pressure for long enough,
+// before the kernel kills the main process outright; this catches the
+// pressure of the container that monitoring the heap of the runtime cannot
+// see.  The error that Wait() returns then reports an ErrMemoryPressure.
+// Creating the instance fails if the files of the cgroup cannot be read.
+func WatchingCgroupMemory(memory CgroupMemory) Option {
+	return watchingCgroupMemory{memory: memory}
+}
+
+type watchingCgroupMemory struct{ memory CgroupMemory }
+
+func (w watchingCgroupMemory) Apply(o *Settings) {
+	o.CgroupMemory = w.memory
+}
+
+// cgroupMemoryErrors returns the errors of the settings of the watch of the
+// memory of a cgroup.
+func cgroupMemoryErrors(c CgroupMemory) []error {
+	var errs []error
+
+	if c.Interval == 0 {
+		return nil
+	}
+
+	if !cgroupSupported {
+		errs = append(errs, errors.New("cgroup memory cannot be watched on this platform"))
+	}
+
+	if c.Interval < 0 {
+		errs = append(errs, fmt.Errorf("cgroup memory interval %v must be positive", c.Interval))
+	}
+
+	if c.Pressure < 0 || c.Pressure > 100 {
+		errs = append(errs, fmt.Errorf("cgroup memory pressure %v must be between 0 and 100", c.Pressure))
+	}
+
+	if c.Sustained < 0 {
+		errs = append(errs, fmt.Errorf("cgroup memory pressure duration %v must not be negative", c.Sustained))
+	}
+
+	return errs
+}
@@ -0,0 +1,140 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cgroupSupported = true
+
+// watchCgroupMemory reads the OOM kills, and the memory pressure if needed, of
+// the cgroup, and then starts a goroutine that closes the instance when they
+// show that its memory ran out, until the instance is closed or stopped.
+func (w *watcher) watchCgroupMemory(memory CgroupMemory) error {
+	dir := memory.Dir
+	if dir == "" {
+		dir = DefaultCgroupDir
+	}
+
+	kills, err := readOOMKills(dir)
+	if err != nil {
+		return err
+	}
+
+	if memory.Pressure > 0 {
+		if _, err := readMemoryPressure(dir); err != nil {
+			return err
+		}
+	}
+
+	go w.watchMemory(dir, memory, kills)
+
+	return nil
+}
+
+func (w *watcher) watchMemory(dir string, memory CgroupMemory, kills int) {
+	// when the memory came under pressure, if it is
+	var since time.Time
+
+	for {
+		timer := w.clock.NewTimer(memory.Interval)
+
+		select {
+		case now := <-timer.C():
+			if n, err := readOOMKills(dir); err == nil && n > kills {
+				_ = w.closeWithCause(&ErrMemoryPressure{OOMKills: n - kills})
+				return
+			}
+
+			if memory.Pressure == 0 {
+				continue
+			}
+
+			pressure, err := readMemoryPressure(dir)
+			if err != nil || pressure < memory.Pressure {
+				since = time.Time{}
+				continue
+			}
+
+			if since.IsZero() {
+				since = now
+			}
+
+			if now.Sub(since) >= memory.Sustained {
+				_ = w.closeWithCause(&ErrMemoryPressure{Pressure: pressure})
+				return
+			}
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		case <-w.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// readOOMKills returns the number of processes of the cgroup killed by the OOM
+// killer, the oom_kill field of memory.events.
+func readOOMKills(dir string) (int, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return strconv.Atoi(fields[1])
+		}
+	}
+
+	return 0, fmt.Errorf("no oom_kill in %s", filepath.Join(dir, "memory.events"))
+}
+
+// readMemoryPressure returns the share of time during which all the tasks of
+// the cgroup were stalled on memory, the full avg10 of memory.pressure.
+func readMemoryPressure(dir string) (float64, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "memory.pressure"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "full" {
+			continue
+		}
+
+		for _, field := range fields[1:] {
+			if v := strings.TrimPrefix(field, "avg10="); v != field {
+				return strconv.ParseFloat(v, 64)
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("no full avg10 in %s", filepath.Join(dir, "memory.pressure"))
+}
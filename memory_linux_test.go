@@ -0,0 +1,109 @@
+//go:build linux
+// +build linux
+
+package yama_test
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestCgroupMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yama")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	writeEvents := func(kills int) {
+		events := fmt.Sprintf("low 0\nhigh 0\nmax 3\noom 1\noom_kill %d\noom_group_kill 0\n", kills)
+		So(ioutil.WriteFile(filepath.Join(dir, "memory.events"), []byte(events), 0o600), ShouldBeNil)
+	}
+
+	writePressure := func(full float64) {
+		pressure := fmt.Sprintf("some avg10=%.2f avg60=0.00 avg300=0.00 total=0\nfull avg10=%.2f avg60=0.00 avg300=0.00 total=0\n", full, full)
+		So(ioutil.WriteFile(filepath.Join(dir, "memory.pressure"), []byte(pressure), 0o600), ShouldBeNil)
+	}
+
+	Convey("Ensure the watcher is closed when a process is killed by the OOM killer", t, func() {
+		writeEvents(2)
+
+		clock := yamatest.NewClock(time.Now())
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WatchingCgroupMemory(yama.CgroupMemory{Dir: dir, Interval: time.Second}))
+		So(err, ShouldBeNil)
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		clock.BlockUntil(1)
+
+		writeEvents(3)
+		clock.Advance(time.Second)
+
+		var pressure *yama.ErrMemoryPressure
+		So(errors.As(watcher.Wait(), &pressure), ShouldBeTrue)
+		So(pressure.OOMKills, ShouldEqual, 1)
+	})
+
+	Convey("Ensure the watcher is closed when the memory is under sustained pressure", t, func() {
+		writeEvents(0)
+		writePressure(0)
+
+		clock := yamatest.NewClock(time.Now())
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WatchingCgroupMemory(yama.CgroupMemory{Dir: dir, Interval: 5 * time.Second, Pressure: 50, Sustained: 10 * time.Second}))
+		So(err, ShouldBeNil)
+
+		writePressure(80)
+
+		for i := 0; i < 2; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(5 * time.Second)
+		}
+
+		clock.BlockUntil(1)
+		So(watcher.Status().Triggered, ShouldBeFalse)
+
+		clock.Advance(5 * time.Second)
+
+		var pressure *yama.ErrMemoryPressure
+		So(errors.As(watcher.Wait(), &pressure), ShouldBeTrue)
+		So(pressure.Pressure, ShouldEqual, 80)
+	})
+
+	Convey("Ensure watching the memory of a missing cgroup fails", t, func() {
+		_, err := yama.NewWatcher(yama.WatchingCgroupMemory(yama.CgroupMemory{Dir: filepath.Join(dir, "missing"), Interval: time.Second}))
+		So(err, ShouldBeError)
+
+		_, err = yama.NewWatcher(yama.WatchingCgroupMemory(yama.CgroupMemory{Dir: dir, Interval: time.Second, Pressure: 150}))
+		So(err, ShouldBeError)
+	})
+}
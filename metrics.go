@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MetricsFlusher is a flusher that pushes the final metrics of the process,
+// including the report of the shutdown, so that short-lived processes, e.g.
+// batch jobs, don't lose the metrics of their last scrape interval.  It is
+// specified with WithFlushers(), and is given the report of the shutdown as it
+// stands when the flushers are called.
+//
+// The report is pushed as the yama_shutdown_* metrics, followed by the metrics
+// written by Gather, to the Prometheus Pushgateway at URL, which replaces the
+// metrics of the job's group; OTLP exporters are flushed by Flush.
+type MetricsFlusher struct {
+	// URL is the URL of the Pushgateway; empty means no metrics are pushed.
+	URL string
+
+	// Job is the job label of the pushed metrics; empty means the name of
+	// the executable.
+	Job string
+
+	// Client is the client that pushes the metrics; nil means
+	// http.DefaultClient.
+	Client *http.Client
+
+	// Gather, if not nil, writes the metrics of the process in the Prometheus
+	// text format, e.g. with expfmt from the metrics of a registry.
+	Gather func(w io.Writer) error
+
+	// Flush, if not nil, is called with the report, before the metrics are
+	// pushed, e.g. to record it with an OpenTelemetry meter and then call
+	// the ForceFlush() method of the meter provider.
+	Flush func(ctx context.Context, report Report) error
+}
+
+// Close pushes the metrics with an empty report, when the flusher isn't
+// called by a watcher.
+func (m *MetricsFlusher) Close() error {
+	return m.flush(context.Background(), Report{})
+}
+
+// CloseContext is like Close(), within the deadline of the context.
+func (m *MetricsFlusher) CloseContext(ctx context.Context) error {
+	return m.flush(ctx, Report{})
+}
+
+func (m *MetricsFlusher) flush(ctx context.Context, report Report) error {
+	var err error
+
+	if m.Flush != nil {
+		err = m.Flush(ctx, report)
+	}
+
+	if m.URL != "" {
+		err = JoinErrors(err, m.push(ctx, report))
+	}
+
+	return err
+}
+
+// push pushes the report, and the gathered metrics, to the Pushgateway.
+func (m *MetricsFlusher) push(ctx context.Context, report Report) error {
+	var body bytes.Buffer
+
+	writeReportMetrics(&body, report)
+
+	if m.Gather != nil {
+		if err := m.Gather(&body); err != nil {
+			return err
+		}
+	}
+
+	job := m.Job
+	if job == "" {
+		job = filepath.Base(os.Args[0])
+	}
+
+	target := strings.TrimSuffix(m.URL, "/") + "/metrics/job/" + url.PathEscape(job)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway responded %v", resp.Status)
+	}
+
+	return nil
+}
+
+// writeReportMetrics writes the report in the Prometheus text format.
+func writeReportMetrics(w io.Writer, report Report) {
+	gauge := func(name, help string, value float64) {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, value)
+	}
+
+	var start float64
+	if !report.Start.IsZero() {
+		start = float64(report.Start.UnixNano()) / 1e9
+	}
+
+	gauge("yama_shutdown_start_time_seconds", "Time at which the closers started being notified.", start)
+	gauge("yama_shutdown_duration_seconds", "Duration of the notification of the closers.", report.Duration().Seconds())
+	gauge("yama_shutdown_closers", "Number of closers that were notified.", float64(report.Closers))
+	gauge("yama_shutdown_uncompleted_closers", "Number of closers that didn't complete within the timeout.", float64(len(report.Uncompleted)))
+	gauge("yama_shutdown_slow_closers", "Number of closers that didn't complete within the closer timeout.", float64(len(report.Slow)))
+}
+
+// withReport returns the flushers, with the metrics flushers given the report
+// of the shutdown.
+func (w *watcher) withReport(flushers []io.Closer) []io.Closer {
+	var withReport []io.Closer
+
+	for i, flusher := range flushers {
+		m, ok := flusher.(*MetricsFlusher)
+		if !ok {
+			continue
+		}
+
+		if withReport == nil {
+			withReport = append([]io.Closer(nil), flushers...)
+		}
+
+		report := w.report(w.clock.Now())
+		withReport[i] = ContextFnAsCloser(func(ctx context.Context) error { return m.flush(ctx, report) })
+	}
+
+	if withReport == nil {
+		return flushers
+	}
+
+	return withReport
+}
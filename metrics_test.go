@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestMetricsFlusher(t *testing.T) {
+
+	Convey("Ensure the final metrics are pushed with the report of the shutdown", t, func() {
+		type push struct {
+			method, path, contentType, body string
+		}
+		pushes := make(chan push, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			pushes <- push{r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(body)}
+		}))
+		defer server.Close()
+
+		reports := make(chan yama.Report, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClosers(yamatest.CloserSpy("db"), yamatest.CloserSpy("cache")),
+			yama.WithFlushers(5*time.Second, &yama.MetricsFlusher{
+				URL: server.URL,
+				Job: "nightly batch",
+				Gather: func(w io.Writer) error {
+					_, err := fmt.Fprintln(w, "rows_processed_total 42")
+					return err
+				},
+				Flush: func(ctx context.Context, report yama.Report) error {
+					reports <- report
+					return nil
+				},
+			}))
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+
+		report := <-reports
+		So(report.Closers, ShouldEqual, 2)
+		So(report.Start.IsZero(), ShouldBeFalse)
+
+		p := <-pushes
+		So(p.method, ShouldEqual, http.MethodPut)
+		So(p.path, ShouldEqual, "/metrics/job/nightly batch")
+		So(p.contentType, ShouldStartWith, "text/plain")
+		So(p.body, ShouldContainSubstring, "# TYPE yama_shutdown_closers gauge\nyama_shutdown_closers 2\n")
+		So(p.body, ShouldContainSubstring, "\nyama_shutdown_uncompleted_closers 0\n")
+		So(p.body, ShouldEndWith, "\nrows_processed_total 42\n")
+	})
+
+	Convey("Ensure the closers that timed out are pushed", t, func() {
+		bodies := make(chan string, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies <- string(body)
+		}))
+		defer server.Close()
+
+		release := make(chan struct{})
+		defer close(release)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })),
+			yama.WithFlushers(5*time.Second, &yama.MetricsFlusher{URL: server.URL}))
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldNotBeNil)
+
+		So(<-bodies, ShouldContainSubstring, "\nyama_shutdown_uncompleted_closers 1\n")
+	})
+
+	Convey("Ensure pushes rejected by the Pushgateway fail", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		flusher := &yama.MetricsFlusher{URL: server.URL, Job: "batch"}
+		So(flusher.Close(), ShouldBeError, "pushgateway responded 400 Bad Request")
+	})
+}
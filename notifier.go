@@ -0,0 +1,320 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Notification is a lifecycle event of the shutdown, as sent to a Notifier:
+// "triggered" once the closers are notified, with the signal that occurred, if
+// any, "timedout" if the timeout fires, and "completed" once the shutdown
+// completed, with the error that Wait() returns.  Rehearsal tells whether the
+// shutdown is a rehearsal, as specified by WithChaosShutdown().
+type Notification struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Signal    string    `json:"signal,omitempty"`
+	Elapsed   string    `json:"elapsed,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Rehearsal bool      `json:"rehearsal,omitempty"`
+}
+
+// Notifier is notified of the lifecycle events of the shutdown, e.g. to page
+// a human when the shutdown times out; the deadline of the context is the end
+// of the notification timeout.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// WithNotifiers returns an Option that specifies the notifiers of the
+// lifecycle events of the shutdown.  They are notified concurrently, without
+// delaying the closers, and each is notified of the events in order, each
+// within the timeout; the callers of Wait() are only unblocked once they have
+// been notified of the completion, or the timeout has fired.  Their errors are
+// not reported.
+func WithNotifiers(timeout time.Duration, notifiers ...Notifier) Option {
+	return withNotifiers{timeout: timeout, notifiers: notifiers}
+}
+
+type withNotifiers struct {
+	timeout   time.Duration
+	notifiers []Notifier
+}
+
+func (w withNotifiers) Apply(o *Settings) {
+	o.NotifyTimeOut = w.timeout
+	o.Notifiers = w.notifiers
+}
+
+// sendNotification queues the event for the notifiers, which are each
+// notified of the events one at a time, in order, without delaying the
+// caller; finishNotifications waits for them.  Both are only called by the
+// goroutine notifying the closers.
+func (w *watcher) sendNotification(event string, sig os.Signal, err error) {
+	if len(w.notifiers) == 0 || w.notifyFinished {
+		return
+	}
+
+	now := w.clock.Now()
+	n := Notification{Time: now.UTC(), Event: event}
+
+	if sig != nil {
+		n.Signal = signalName(sig)
+	}
+
+	if err != nil {
+		n.Error = err.Error()
+	}
+
+	if reason, _ := w.Reason(); reason == Rehearsal {
+		n.Rehearsal = true
+	}
+
+	w.mu.Lock()
+	if event != "triggered" && !w.started.IsZero() {
+		n.Elapsed = now.Sub(w.started).String()
+	}
+	w.mu.Unlock()
+
+	if w.notifyQueues == nil {
+		for _, notifier := range w.notifiers {
+			// the shutdown has at most three events
+			q := make(chan Notification, 3)
+			w.notifyQueues = append(w.notifyQueues, q)
+			w.notifications.Add(1)
+
+			go func(notifier Notifier) {
+				defer w.notifications.Done()
+
+				for n := range q {
+					w.notifyWithin(notifier, n)
+				}
+			}(notifier)
+		}
+	}
+
+	for _, q := range w.notifyQueues {
+		q <- n
+	}
+}
+
+// finishNotifications waits for the notifiers to be notified of the events
+// sent so far; later events are not sent.
+func (w *watcher) finishNotifications() {
+	if w.notifyFinished {
+		return
+	}
+
+	w.notifyFinished = true
+
+	for _, q := range w.notifyQueues {
+		close(q)
+	}
+
+	w.notifications.Wait()
+}
+
+// notifyWithin notifies the notifier and waits for it, at most for the
+// notification timeout, or for what is left of the budget of the closers if
+// it ends first; once the budget is spent, e.g. for the timedout event, the
+// notification timeout alone bounds the notifier.  The context of the notifier
+// carries the watcher's clock.
+func (w *watcher) notifyWithin(notifier Notifier, n Notification) {
+	now := w.clock.Now()
+	deadline := now.Add(w.notifyTimeout)
+
+	w.mu.Lock()
+	if now.Before(w.budget) && w.budget.Before(deadline) {
+		deadline = w.budget
+	}
+	w.mu.Unlock()
+
+	ctx := newDeadlineContext(deadline)
+	defer ctx.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		_ = notifier.Notify(contextWithClock(ctx, w.clock), n)
+		close(done)
+	}()
+
+	timer := w.clock.NewTimer(deadline.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		ctx.expire()
+	case <-done:
+	}
+}
+
+// WebhookNotifier is a Notifier that posts the notifications to a URL, as JSON
+// objects, or, when created by NewSlackNotifier(), as Slack messages.
+type WebhookNotifier struct {
+	// URL is the URL of the webhook.
+	URL string
+
+	// Client is the client that posts the notifications; nil means
+	// http.DefaultClient.
+	Client *http.Client
+
+	// Events, if not empty, are the only events that are posted, e.g.
+	// "timedout" to only page on timeouts.
+	Events []string
+
+	// Retries is the number of times a failed post is retried, Backoff
+	// apart, as long as the deadline of the context leaves time for it.
+	// Posts rejected with a 4xx status, other than 429, are not retried.
+	Retries int
+	Backoff time.Duration
+
+	format func(n Notification) ([]byte, error)
+}
+
+// NewSlackNotifier creates a WebhookNotifier that posts the notifications to
+// the URL of a Slack incoming webhook, or of any service that accepts
+// Slack-compatible messages.
+func NewSlackNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, format: slackMessage}
+}
+
+// slackMessage formats the notification as a Slack message.
+func slackMessage(n Notification) ([]byte, error) {
+	event := n.Event
+	if event == "timedout" {
+		event = "timed out"
+	}
+
+	if n.Rehearsal {
+		event = "rehearsal " + event
+	}
+
+	host, _ := os.Hostname()
+	text := fmt.Sprintf("%s on %s: shutdown %s", filepath.Base(os.Args[0]), host, event)
+
+	if n.Signal != "" {
+		text += " by " + n.Signal
+	}
+
+	if n.Elapsed != "" {
+		text += " after " + n.Elapsed
+	}
+
+	if n.Error != "" {
+		text += ": " + n.Error
+	}
+
+	return json.Marshal(struct {
+		Text string `json:"text"`
+	}{text})
+}
+
+// Notify posts the notification, retrying as configured.
+func (h *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	if !h.posts(n.Event) {
+		return nil
+	}
+
+	format := h.format
+	if format == nil {
+		format = func(n Notification) ([]byte, error) { return json.Marshal(n) }
+	}
+
+	body, err := format(n)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		var retry bool
+
+		if retry, err = h.post(ctx, body); err == nil || !retry || attempt >= h.Retries {
+			return err
+		}
+
+		// the deadline is measured with the clock of the watcher, whose
+		// context carries it
+		clock := clockOf(ctx)
+
+		if deadline, ok := ctx.Deadline(); ok && deadline.Sub(clock.Now()) < h.Backoff {
+			return err
+		}
+
+		t := clock.NewTimer(h.Backoff)
+
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+}
+
+func (h *WebhookNotifier) posts(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// post posts the body once, and reports whether it should be retried if it
+// failed.
+func (h *WebhookNotifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("webhook responded %v", resp.Status)
+
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []string
+	errors []string
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, n yama.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, n.Event)
+	r.errors = append(r.errors, n.Error)
+
+	return nil
+}
+
+func TestNotifiers(t *testing.T) {
+
+	Convey("Ensure the notifiers are notified before Wait() returns", t, func() {
+		notifier := &recordingNotifier{}
+
+		watcher, err := yama.NewWatcher(
+			yama.WithNotifiers(time.Second, notifier),
+			yama.WithClosers(yamatest.CloserSpy("db")))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+
+		notifier.mu.Lock()
+		defer notifier.mu.Unlock()
+		So(notifier.events, ShouldResemble, []string{"triggered", "completed"})
+	})
+
+	Convey("Ensure the notifiers are notified of timeouts", t, func() {
+		notifier := &recordingNotifier{}
+		release := make(chan struct{})
+		defer close(release)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithNotifiers(time.Second, notifier),
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldNotBeNil)
+
+		notifier.mu.Lock()
+		defer notifier.mu.Unlock()
+		So(notifier.events, ShouldResemble, []string{"triggered", "timedout", "completed"})
+		So(notifier.errors, ShouldResemble, []string{"", "", "closers timed out"})
+	})
+
+	Convey("Ensure slow notifiers do not block the shutdown past their timeout", t, func() {
+		blocked := make(chan struct{})
+		defer close(blocked)
+
+		watcher, err := yama.NewWatcher(yama.WithNotifiers(10*time.Millisecond,
+			notifierFunc(func(ctx context.Context, _ yama.Notification) error {
+				<-blocked
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure notifiers are validated", t, func() {
+		_, err := yama.NewWatcher(yama.WithNotifiers(0, &recordingNotifier{}, nil))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "notifier #1 must not be null")
+		So(err.Error(), ShouldContainSubstring, "notify timeout 0s must be positive")
+	})
+}
+
+type notifierFunc func(ctx context.Context, n yama.Notification) error
+
+func (f notifierFunc) Notify(ctx context.Context, n yama.Notification) error {
+	return f(ctx, n)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+
+	server := func(codes ...int) (*httptest.Server, *[]string) {
+		var mu sync.Mutex
+		var bodies []string
+
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			b, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(b))
+
+			if len(bodies) <= len(codes) {
+				rw.WriteHeader(codes[len(bodies)-1])
+			}
+		})), &bodies
+	}
+
+	ctx := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(context.Background(), 5*time.Second)
+	}
+
+	Convey("Ensure failed posts are retried", t, func() {
+		s, bodies := server(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		defer s.Close()
+
+		c, cancel := ctx()
+		defer cancel()
+
+		n := &yama.WebhookNotifier{URL: s.URL, Retries: 2, Backoff: time.Millisecond}
+		So(n.Notify(c, yama.Notification{Event: "timedout"}), ShouldBeNil)
+		So(*bodies, ShouldHaveLength, 3)
+
+		var got yama.Notification
+		So(json.Unmarshal([]byte((*bodies)[2]), &got), ShouldBeNil)
+		So(got.Event, ShouldEqual, "timedout")
+	})
+
+	Convey("Ensure rejected posts are not retried", t, func() {
+		s, bodies := server(http.StatusBadRequest)
+		defer s.Close()
+
+		c, cancel := ctx()
+		defer cancel()
+
+		n := &yama.WebhookNotifier{URL: s.URL, Retries: 2}
+		So(n.Notify(c, yama.Notification{Event: "timedout"}), ShouldBeError, "webhook responded 400 Bad Request")
+		So(*bodies, ShouldHaveLength, 1)
+	})
+
+	Convey("Ensure retries respect the deadline", t, func() {
+		s, bodies := server(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+		defer s.Close()
+
+		c, cancel := ctx()
+		defer cancel()
+
+		n := &yama.WebhookNotifier{URL: s.URL, Retries: 2, Backoff: time.Minute}
+		So(n.Notify(c, yama.Notification{Event: "timedout"}), ShouldNotBeNil)
+		So(*bodies, ShouldHaveLength, 1)
+	})
+
+	Convey("Ensure retries are measured with the clock of the watcher", t, func() {
+		s, bodies := server(http.StatusServiceUnavailable)
+		defer s.Close()
+
+		clock := yamatest.NewClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		n := &yama.WebhookNotifier{URL: s.URL, Events: []string{"triggered"}, Retries: 1, Backoff: time.Second}
+
+		watcher, err := yama.NewWatcher(yama.WithClock(clock), yama.WithNotifiers(10*time.Second, n))
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+
+		// the timeout of the notification, and the backoff
+		clock.BlockUntil(2)
+		clock.Advance(time.Second)
+
+		So(watcher.Wait(), ShouldBeNil)
+		So(*bodies, ShouldHaveLength, 2)
+	})
+
+	Convey("Ensure only the configured events are posted", t, func() {
+		s, bodies := server()
+		defer s.Close()
+
+		c, cancel := ctx()
+		defer cancel()
+
+		n := &yama.WebhookNotifier{URL: s.URL, Events: []string{"timedout"}}
+		So(n.Notify(c, yama.Notification{Event: "completed"}), ShouldBeNil)
+		So(*bodies, ShouldBeEmpty)
+	})
+
+	Convey("Ensure Slack messages describe the shutdown", t, func() {
+		s, bodies := server()
+		defer s.Close()
+
+		c, cancel := ctx()
+		defer cancel()
+
+		n := yama.NewSlackNotifier(s.URL)
+		So(n.Notify(c, yama.Notification{Event: "timedout", Elapsed: "30s"}), ShouldBeNil)
+		So(*bodies, ShouldHaveLength, 1)
+
+		var msg struct{ Text string }
+		So(json.Unmarshal([]byte((*bodies)[0]), &msg), ShouldBeNil)
+		So(msg.Text, ShouldEndWith, ": shutdown timed out after 30s")
+	})
+}
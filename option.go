@@ -17,16 +17,355 @@
 package yama // import "l7e.io/yama"
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"os"
 	"time"
 )
 
 // Settings holds information needed to construct an instance of Watcher.
 type Settings struct {
-	Signals []os.Signal
-	TimeOut time.Duration
-	Closers []io.Closer
+	Signals        []os.Signal
+	RestartSignals []os.Signal
+	CountedSignals []os.Signal
+	TagSignals     []TagSignal
+	SignalPolicies map[os.Signal]SignalPolicy
+
+	CombiningWindow    time.Duration
+	SignalCombinations []SignalCombination
+
+	CoalescingWindow time.Duration
+	TimeOut          time.Duration
+
+	CloserTimeOut      time.Duration
+	AbandonSlowClosers bool
+
+	EmergencyClosers []io.Closer
+	EmergencyTimeOut time.Duration
+
+	Flushers     []io.Closer
+	FlushTimeOut time.Duration
+
+	LastCloser  io.Closer
+	LastTimeOut time.Duration
+
+	Notifiers     []Notifier
+	NotifyTimeOut time.Duration
+
+	Closers  []io.Closer
+	Starters []Starter
+
+	TriggerContexts []context.Context
+
+	Source SignalSource
+	Clock  Clock
+
+	Concurrency int
+	DrainDelay  time.Duration
+	Synchronous bool
+
+	WatchdogInterval time.Duration
+	WatchdogGrace    time.Duration
+	WatchdogStacks   io.Writer
+
+	CgroupMemory CgroupMemory
+
+	ChaosProbability float64
+	ChaosWindow      DailyWindow
+
+	ShutdownMarker string
+	PIDFile        string
+
+	DryRun       io.Writer
+	SignalEcho   io.Writer
+	StatusEvents io.Writer
+
+	OnSuspend func()
+	OnResume  func()
+
+	SleepInterval   time.Duration
+	OnSystemSuspend func(at time.Time)
+	OnSystemResume  func(slept time.Duration)
+	ExcludeSleep    bool
+
+	SessionEndReason string
+
+	InterruptHint    string
+	InterruptHintOut io.Writer
+
+	Progress         io.Writer
+	ProgressInterval time.Duration
+
+	BudgetAlert     func(alert BudgetAlert)
+	BudgetFractions []float64
+
+	ConfirmationPrompt  string
+	ConfirmationTimeout time.Duration
+	ConfirmationIn      io.Reader
+	ConfirmationOut     io.Writer
+
+	AllowDuplicates bool
+
+	ExitFunc          func(code int)
+	ExitAfterShutdown bool
+	ExitCode          func(reason Reason, sig os.Signal, err error) int
+	ReraiseSignal     bool
+	Escalation        Escalation
+	TimeoutPolicy     TimeoutPolicy
+
+	forwards []func(sig os.Signal)
+	profiles []string
+	errs     []error
+}
+
+// Clone returns a copy of the settings that does not share their lists with
+// them, so that options can be applied to either without affecting the other;
+// the closers, notifiers, and other values in the lists are shared.
+func (s *Settings) Clone() *Settings {
+	c := *s
+
+	c.Signals = append([]os.Signal(nil), s.Signals...)
+	c.RestartSignals = append([]os.Signal(nil), s.RestartSignals...)
+	c.CountedSignals = append([]os.Signal(nil), s.CountedSignals...)
+	c.EmergencyClosers = append([]io.Closer(nil), s.EmergencyClosers...)
+	c.Flushers = append([]io.Closer(nil), s.Flushers...)
+	c.BudgetFractions = append([]float64(nil), s.BudgetFractions...)
+	c.Notifiers = append([]Notifier(nil), s.Notifiers...)
+	c.Closers = append([]io.Closer(nil), s.Closers...)
+	c.Starters = append([]Starter(nil), s.Starters...)
+	c.TriggerContexts = append([]context.Context(nil), s.TriggerContexts...)
+	c.forwards = append(([]func(sig os.Signal))(nil), s.forwards...)
+	c.errs = append([]error(nil), s.errs...)
+
+	c.SignalCombinations = nil
+	for _, sc := range s.SignalCombinations {
+		c.SignalCombinations = append(c.SignalCombinations, SignalCombination{Signals: append([]os.Signal(nil), sc.Signals...), Policy: sc.Policy})
+	}
+
+	c.TagSignals = nil
+	for _, ts := range s.TagSignals {
+		c.TagSignals = append(c.TagSignals, TagSignal{Signal: ts.Signal, Tags: append([]string(nil), ts.Tags...)})
+	}
+
+	if s.SignalPolicies != nil {
+		c.SignalPolicies = make(map[os.Signal]SignalPolicy, len(s.SignalPolicies))
+		for sig, policy := range s.SignalPolicies {
+			c.SignalPolicies[sig] = policy
+		}
+	}
+
+	return &c
+}
+
+// validate the settings, returning all the problems found.
+func (s *Settings) validate() error {
+	// the errors of the options are reported with the other problems
+	errs := append([]error(nil), s.errs...)
+
+	for i, closer := range s.Closers {
+		if closer == nil {
+			errs = append(errs, fmt.Errorf("closer #%d must not be null", i))
+		} else if j := indexOfCloser(s.Closers[:i], closer); j >= 0 && !s.AllowDuplicates {
+			errs = append(errs, fmt.Errorf("closer #%d is closer #%d: %w", i, j, ErrDuplicateCloser))
+		}
+	}
+
+	seen := make(map[os.Signal]bool, len(s.Signals))
+	for _, sig := range s.Signals {
+		if sig == nil {
+			errs = append(errs, errors.New("signal must not be null"))
+		} else if seen[sig] {
+			errs = append(errs, fmt.Errorf("signal %v must not be watched more than once", sig))
+		}
+
+		seen[sig] = true
+	}
+
+	for _, sig := range s.RestartSignals {
+		if sig == nil {
+			errs = append(errs, errors.New("restart signal must not be null"))
+		} else if watches(s.Signals, sig) {
+			errs = append(errs, fmt.Errorf("restart signal %v must not be watched", sig))
+		}
+	}
+
+	for _, sig := range s.CountedSignals {
+		if sig == nil {
+			errs = append(errs, errors.New("counted signal must not be null"))
+		} else if watches(s.Signals, sig) || watches(s.RestartSignals, sig) {
+			errs = append(errs, fmt.Errorf("counted signal %v must not be watched", sig))
+		}
+	}
+
+	for _, ts := range s.TagSignals {
+		if ts.Signal == nil {
+			errs = append(errs, errors.New("tag signal must not be null"))
+		} else if watches(s.Signals, ts.Signal) || watches(s.RestartSignals, ts.Signal) || watches(s.CountedSignals, ts.Signal) {
+			errs = append(errs, fmt.Errorf("tag signal %v must not be watched", ts.Signal))
+		}
+
+		if len(ts.Tags) == 0 {
+			errs = append(errs, fmt.Errorf("tag signal %v must have tags", ts.Signal))
+		}
+	}
+
+	if (s.OnSuspend != nil || s.OnResume != nil) && !suspendSupported {
+		errs = append(errs, errors.New("suspend hooks are not supported on this platform"))
+	}
+
+	if s.SleepInterval < 0 || ((s.OnSystemSuspend != nil || s.OnSystemResume != nil || s.ExcludeSleep) && s.SleepInterval == 0) {
+		errs = append(errs, fmt.Errorf("sleep detection interval %v must be positive", s.SleepInterval))
+	}
+
+	if _, ok := s.Clock.(SleepClock); s.SleepInterval > 0 && s.Clock != nil && !ok {
+		errs = append(errs, errors.New("clock must implement SleepClock to detect sleep"))
+	}
+
+	if s.CoalescingWindow < 0 {
+		errs = append(errs, fmt.Errorf("coalescing window %v must not be negative", s.CoalescingWindow))
+	}
+
+	if s.TimeOut <= 0 {
+		errs = append(errs, fmt.Errorf("timeout %v must be positive", s.TimeOut))
+	}
+
+	if s.CloserTimeOut < 0 || s.CloserTimeOut > s.TimeOut {
+		errs = append(errs, fmt.Errorf("closer timeout %v must be between zero and the timeout %v", s.CloserTimeOut, s.TimeOut))
+	}
+
+	for sig := range s.SignalPolicies {
+		if !watches(s.Signals, sig) {
+			errs = append(errs, fmt.Errorf("signal %v of a policy must be watched", sig))
+		}
+	}
+
+	for _, sig := range s.Signals {
+		policy, ok := s.SignalPolicies[sig]
+		if !ok || policy.Immediate {
+			continue
+		}
+
+		errs = append(errs, policyErrors(policy, fmt.Sprintf("signal %v", sig), s.CloserTimeOut)...)
+	}
+
+	if s.CombiningWindow < 0 || (len(s.SignalCombinations) > 0 && s.CombiningWindow == 0) {
+		errs = append(errs, fmt.Errorf("combining window %v must be positive", s.CombiningWindow))
+	}
+
+	for _, c := range s.SignalCombinations {
+		if len(c.Signals) < 2 {
+			errs = append(errs, fmt.Errorf("combination %v must have at least two signals", c))
+		}
+
+		if len(combinationSignals([]SignalCombination{c}, s.Signals)) == len(c.Signals) {
+			errs = append(errs, fmt.Errorf("combination %v must have a watched signal", c))
+		}
+
+		if !c.Policy.Immediate {
+			errs = append(errs, policyErrors(c.Policy, fmt.Sprintf("combination %v", c), s.CloserTimeOut)...)
+		}
+	}
+
+	for i, closer := range s.EmergencyClosers {
+		if closer == nil {
+			errs = append(errs, fmt.Errorf("emergency closer #%d must not be null", i))
+		}
+	}
+
+	if len(s.EmergencyClosers) > 0 && s.EmergencyTimeOut <= 0 {
+		errs = append(errs, fmt.Errorf("emergency timeout %v must be positive", s.EmergencyTimeOut))
+	}
+
+	for i, flusher := range s.Flushers {
+		if flusher == nil {
+			errs = append(errs, fmt.Errorf("flusher #%d must not be null", i))
+		}
+	}
+
+	if len(s.Flushers) > 0 && s.FlushTimeOut <= 0 {
+		errs = append(errs, fmt.Errorf("flush timeout %v must be positive", s.FlushTimeOut))
+	}
+
+	for i, notifier := range s.Notifiers {
+		if notifier == nil {
+			errs = append(errs, fmt.Errorf("notifier #%d must not be null", i))
+		}
+	}
+
+	if len(s.Notifiers) > 0 && s.NotifyTimeOut <= 0 {
+		errs = append(errs, fmt.Errorf("notify timeout %v must be positive", s.NotifyTimeOut))
+	}
+
+	if s.LastCloser != nil && s.LastTimeOut <= 0 {
+		errs = append(errs, fmt.Errorf("last closer timeout %v must be positive", s.LastTimeOut))
+	}
+
+	for i, starter := range s.Starters {
+		if starter.Start == nil {
+			errs = append(errs, fmt.Errorf("starter #%d %q must have a start function", i, starter.Name))
+		}
+	}
+
+	if s.Source == nil {
+		errs = append(errs, errors.New("signal source must not be null"))
+	}
+
+	if s.Clock == nil {
+		errs = append(errs, errors.New("clock must not be null"))
+	}
+
+	if s.ExitFunc == nil {
+		errs = append(errs, errors.New("exit function must not be null"))
+	}
+
+	if s.ExitCode == nil {
+		errs = append(errs, errors.New("exit code function must not be null"))
+	}
+
+	if s.DrainDelay < 0 || (s.DrainDelay > 0 && s.DrainDelay >= s.TimeOut) {
+		errs = append(errs, fmt.Errorf("drain delay %v must be between zero and the timeout %v", s.DrainDelay, s.TimeOut))
+	}
+
+	if s.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("concurrency %d must not be negative", s.Concurrency))
+	}
+
+	if s.ConfirmationPrompt != "" && s.ConfirmationTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("confirmation timeout %v must be positive", s.ConfirmationTimeout))
+	}
+
+	if s.Progress != nil && s.ProgressInterval <= 0 {
+		errs = append(errs, fmt.Errorf("progress interval %v must be positive", s.ProgressInterval))
+	}
+
+	if len(s.BudgetFractions) > 0 && s.BudgetAlert == nil {
+		errs = append(errs, errors.New("budget alert hook must not be null"))
+	}
+
+	for _, fraction := range s.BudgetFractions {
+		if !(fraction > 0 && fraction < 1) {
+			errs = append(errs, fmt.Errorf("budget fraction %v must be between 0 and 1", fraction))
+		}
+	}
+
+	if s.WatchdogInterval < 0 || s.WatchdogGrace < 0 {
+		errs = append(errs, errors.New("watchdog interval and grace must not be negative"))
+	}
+
+	errs = append(errs, cgroupMemoryErrors(s.CgroupMemory)...)
+
+	if s.ChaosProbability < 0 || math.IsInf(s.ChaosProbability, 0) || math.IsNaN(s.ChaosProbability) {
+		errs = append(errs, fmt.Errorf("chaos probability %v must be a non-negative number", s.ChaosProbability))
+	}
+
+	if w := s.ChaosWindow; w.From < 0 || w.From >= 24*time.Hour || w.To < 0 || w.To >= 24*time.Hour {
+		errs = append(errs, fmt.Errorf("chaos window %v-%v must be within the day", w.From, w.To))
+	}
+
+	return JoinErrors(errs...)
 }
 
 // A Option is an option for a Watcher watcher.
@@ -35,6 +374,8 @@ type Option interface {
 }
 
 // WatchingSignals returns an Option that specifies the OS signals to capture.
+// If no signals are specified, the Watcher instance does not capture any
+// signals and its closers are only called when it is closed.
 func WatchingSignals(signals ...os.Signal) Option {
 	return watchingSignals{signals: signals}
 }
@@ -47,7 +388,9 @@ func (w watchingSignals) Apply(o *Settings) {
 
 // WithTimeout returns an Option that specifies the timeout used when calling
 // closers when a signal is captured or the Watcher instance is closed.  The
-// default timeout is ten seconds.
+// timeout is a single deadline for all the closers, measured from the start of
+// their notification, regardless of how many complete before it.  The default
+// timeout is ten seconds.
 func WithTimeout(timeout time.Duration) Option {
 	return withTimeout{timeout: timeout}
 }
@@ -58,6 +401,35 @@ func (w withTimeout) Apply(o *Settings) {
 	o.TimeOut = w.timeout
 }
 
+// WithCloserTimeout returns an Option that specifies the time a closer has
+// to complete before it is reported as slow, by an ErrSlowClosers error, while
+// the other closers keep running within the watcher's timeout.  The context
+// passed to closers that implement ContextCloser expires at the end of the
+// closer timeout.  Closer timeouts are ignored when closing synchronously.
+func WithCloserTimeout(d time.Duration) Option {
+	return withCloserTimeout{d: d}
+}
+
+type withCloserTimeout struct{ d time.Duration }
+
+func (w withCloserTimeout) Apply(o *Settings) {
+	o.CloserTimeOut = w.d
+}
+
+// AbandoningSlowClosers returns an Option that specifies that closers that
+// exceed the closer timeout are abandoned: they are not waited for, nor
+// reported as uncompleted, and the next closers can be called in their stead
+// when the concurrency is limited.
+func AbandoningSlowClosers() Option {
+	return abandoningSlowClosers{}
+}
+
+type abandoningSlowClosers struct{}
+
+func (abandoningSlowClosers) Apply(o *Settings) {
+	o.AbandonSlowClosers = true
+}
+
 // WithClosers returns an Option that specifies the closers to call when a
 // signal is captured or the Watcher instance is closed.  Closers are only
 // called once.
@@ -70,3 +442,584 @@ type withClosers struct{ closers []io.Closer }
 func (w withClosers) Apply(o *Settings) {
 	o.Closers = w.closers
 }
+
+// WithEmergencyClosers returns an Option that specifies closers that are
+// always called once the other closers have completed, or the timeout has
+// fired, e.g. to flush a crash log; they are called concurrently and have
+// their own timeout, which should be short.  Their errors, and whether they
+// complete within their timeout, are not reported.
+func WithEmergencyClosers(timeout time.Duration, closers ...io.Closer) Option {
+	return withEmergencyClosers{timeout: timeout, closers: closers}
+}
+
+type withEmergencyClosers struct {
+	timeout time.Duration
+	closers []io.Closer
+}
+
+func (w withEmergencyClosers) Apply(o *Settings) {
+	o.EmergencyTimeOut = w.timeout
+	o.EmergencyClosers = w.closers
+}
+
+// WithFlushers returns an Option that specifies closers that flush loggers,
+// metrics exporters, or tracers, e.g. with zap's Sync(), so that what happened
+// during the shutdown is not lost.  They are called last, once the other
+// closers and the emergency closers have completed, or the timeouts have fired,
+// one at a time in the order they are specified, and have their own timeout,
+// which should be short.  Their errors are not reported.
+func WithFlushers(timeout time.Duration, flushers ...io.Closer) Option {
+	return withFlushers{timeout: timeout, flushers: flushers}
+}
+
+type withFlushers struct {
+	timeout  time.Duration
+	flushers []io.Closer
+}
+
+func (w withFlushers) Apply(o *Settings) {
+	o.FlushTimeOut = w.timeout
+	o.Flushers = w.flushers
+}
+
+// WithLastCloser returns an Option that specifies a closer that is called
+// after everything else, including the flushers, typically the debug HTTP
+// server, so that goroutine profiles can still be pulled from a process whose
+// shutdown hangs.  The closer has its own timeout, and its error is not reported.
+func WithLastCloser(timeout time.Duration, closer io.Closer) Option {
+	return withLastCloser{timeout: timeout, closer: closer}
+}
+
+type withLastCloser struct {
+	timeout time.Duration
+	closer  io.Closer
+}
+
+func (w withLastCloser) Apply(o *Settings) {
+	o.LastTimeOut = w.timeout
+	o.LastCloser = w.closer
+}
+
+// WithSignalSource returns an Option that specifies the source of the signals
+// the Watcher instance watches.  The default source relays the signals of the
+// OS; tests can specify a source that delivers signals in-process.
+func WithSignalSource(source SignalSource) Option {
+	return withSignalSource{source: source}
+}
+
+type withSignalSource struct{ source SignalSource }
+
+func (w withSignalSource) Apply(o *Settings) {
+	o.Source = w.source
+}
+
+// WithClock returns an Option that specifies the clock used to time the
+// notification of closers.  The default clock is the clock of the OS; tests
+// can specify a clock that they advance instantly.
+func WithClock(clock Clock) Option {
+	return withClock{clock: clock}
+}
+
+type withClock struct{ clock Clock }
+
+func (w withClock) Apply(o *Settings) {
+	o.Clock = w.clock
+}
+
+// WithConcurrency returns an Option that specifies the maximum number of
+// closers that are called concurrently when a signal is captured or the Watcher
+// instance is closed.  The remaining closers are called as earlier ones
+// complete; closers that have not been called by the end of the timeout are not
+// called and are reported as uncompleted.  The default, zero, calls all the
+// closers concurrently.
+func WithConcurrency(n int) Option {
+	return withConcurrency{n: n}
+}
+
+type withConcurrency struct{ n int }
+
+func (w withConcurrency) Apply(o *Settings) {
+	o.Concurrency = w.n
+}
+
+// ClosingSynchronously returns an Option that specifies that the closers are
+// called one at a time, in registration order, on the goroutine that notifies
+// them, without spawning any goroutine, e.g. in environments where goroutines
+// are expensive.  The timeout is only checked between closers: once it has
+// passed, the remaining closers are not called.  The concurrency is ignored.
+func ClosingSynchronously() Option {
+	return closingSynchronously{}
+}
+
+type closingSynchronously struct{}
+
+func (closingSynchronously) Apply(o *Settings) {
+	o.Synchronous = true
+}
+
+// WithDrainDelay returns an Option that specifies how long the Watcher instance
+// waits, once a signal is captured or the instance is closed, before calling
+// the closers, e.g. to let load balancers stop routing requests to the
+// process.  The delay is part of the timeout; closers are called with what is
+// left of it.  The default is no delay.
+func WithDrainDelay(delay time.Duration) Option {
+	return withDrainDelay{delay: delay}
+}
+
+type withDrainDelay struct{ delay time.Duration }
+
+func (w withDrainDelay) Apply(o *Settings) {
+	o.DrainDelay = w.delay
+}
+
+// WithWatchdog returns an Option that specifies that the application calls the
+// Watcher instance's Heartbeat() method at least every interval; if it has not
+// been called for longer than the interval and the grace period, e.g. because
+// the application's main loop is wedged, the instance is closed.  The default
+// is no watchdog.
+func WithWatchdog(interval, grace time.Duration) Option {
+	return withWatchdog{interval: interval, grace: grace}
+}
+
+type withWatchdog struct{ interval, grace time.Duration }
+
+func (w withWatchdog) Apply(o *Settings) {
+	o.WatchdogInterval = w.interval
+	o.WatchdogGrace = w.grace
+}
+
+// DumpingStacksOnWatchdog returns an Option that specifies a writer to which the
+// stacks of all goroutines are dumped when the watchdog closes the Watcher
+// instance, to help find out why the application stopped calling Heartbeat().
+func DumpingStacksOnWatchdog(out io.Writer) Option {
+	return dumpingStacksOnWatchdog{out: out}
+}
+
+type dumpingStacksOnWatchdog struct{ out io.Writer }
+
+func (d dumpingStacksOnWatchdog) Apply(o *Settings) {
+	o.WatchdogStacks = d.out
+}
+
+// WithShutdownMarker returns an Option that specifies the path of a marker
+// file, written when a signal is captured or the Watcher instance is closed,
+// and removed once all the closers have completed within the timeout.  A
+// marker left by a previous instance, see UncleanShutdown(), means that it did
+// not shut down cleanly.
+func WithShutdownMarker(path string) Option {
+	return withShutdownMarker{path: path}
+}
+
+type withShutdownMarker struct{ path string }
+
+func (w withShutdownMarker) Apply(o *Settings) {
+	o.ShutdownMarker = w.path
+}
+
+// WithPIDFile returns an Option that specifies a file to which the process ID
+// is written when the Watcher instance is created, and which is removed once
+// its closers have been notified.  Creating the instance fails with
+// ErrAlreadyRunning if the file belongs to another process that is still
+// running; a file left by a process that is no longer running is replaced.
+func WithPIDFile(path string) Option {
+	return withPIDFile{path: path}
+}
+
+type withPIDFile struct{ path string }
+
+func (w withPIDFile) Apply(o *Settings) {
+	o.PIDFile = w.path
+}
+
+// DryRun returns an Option that specifies that, when a watched signal occurs
+// or the Watcher instance is closed, the shutdown plan is written to out
+// instead of notifying the closers.
+func DryRun(out io.Writer) Option {
+	return dryRun{out: out}
+}
+
+type dryRun struct{ out io.Writer }
+
+func (d dryRun) Apply(o *Settings) {
+	o.DryRun = d.out
+}
+
+// WithSignalEcho returns an Option that specifies a writer, e.g. os.Stderr,
+// to which a timestamped note is written when a watched signal triggers the
+// notification of the closers.
+func WithSignalEcho(out io.Writer) Option {
+	return withSignalEcho{out: out}
+}
+
+type withSignalEcho struct{ out io.Writer }
+
+func (w withSignalEcho) Apply(o *Settings) {
+	o.SignalEcho = w.out
+}
+
+// DefaultInterruptHint is the hint written by WithInterruptHint() when no hint
+// is specified.
+const DefaultInterruptHint = "shutting down, press Ctrl-C again to force quit"
+
+// WithInterruptHint returns an Option that specifies a hint written to out,
+// e.g. os.Stderr, when an interrupt, i.e. Ctrl-C, triggers the notification of
+// the closers, or DefaultInterruptHint if the hint is empty.  Since another
+// interrupt is handled according to the escalation policy, the hint is only
+// written when the escalation of the shutdown is ExitingOnRepeat.
+func WithInterruptHint(out io.Writer, hint string) Option {
+	if hint == "" {
+		hint = DefaultInterruptHint
+	}
+
+	return withInterruptHint{out: out, hint: hint}
+}
+
+type withInterruptHint struct {
+	out  io.Writer
+	hint string
+}
+
+func (w withInterruptHint) Apply(o *Settings) {
+	o.InterruptHintOut = w.out
+	o.InterruptHint = w.hint
+}
+
+// WithInterruptConfirmation returns an Option that specifies that an
+// interrupt, i.e. Ctrl-C, only triggers the notification of the closers once
+// confirmed: the prompt is written to the standard error, and the closers are
+// notified if the answer read from the standard input is y or yes, or if
+// another signal occurs, within the timeout.  Otherwise, the interrupt is
+// ignored.
+func WithInterruptConfirmation(prompt string, timeout time.Duration) Option {
+	return withInterruptConfirmation{prompt: prompt, timeout: timeout}
+}
+
+type withInterruptConfirmation struct {
+	prompt  string
+	timeout time.Duration
+}
+
+func (w withInterruptConfirmation) Apply(o *Settings) {
+	o.ConfirmationPrompt = w.prompt
+	o.ConfirmationTimeout = w.timeout
+	o.ConfirmationIn = os.Stdin
+	o.ConfirmationOut = os.Stderr
+}
+
+// ShowingProgress returns an Option that specifies a writer, e.g. os.Stderr,
+// to which the time left and the closers that have not completed are written
+// every interval while the closers are notified.  On a terminal, the status
+// line is updated in place; otherwise, a line is written each time.
+func ShowingProgress(out io.Writer, interval time.Duration) Option {
+	return showingProgress{out: out, interval: interval}
+}
+
+type showingProgress struct {
+	out      io.Writer
+	interval time.Duration
+}
+
+func (s showingProgress) Apply(o *Settings) {
+	o.Progress = s.out
+	o.ProgressInterval = s.interval
+}
+
+// AllowingDuplicateClosers returns an Option that specifies that a closer can
+// be registered more than once, in which case it is called once for each
+// registration, possibly concurrently.  Otherwise, registering a closer that
+// is already registered is an ErrDuplicateCloser error.
+func AllowingDuplicateClosers() Option {
+	return allowingDuplicateClosers{}
+}
+
+type allowingDuplicateClosers struct{}
+
+func (allowingDuplicateClosers) Apply(o *Settings) {
+	o.AllowDuplicates = true
+}
+
+// WithExitFunc returns an Option that specifies the function called when the
+// Watcher instance terminates the process, e.g. when exiting after shutdown.
+// The default function is os.Exit(); tests can specify a function that records
+// the exit code, and embedders one that observes or vetoes the termination.
+func WithExitFunc(exit func(code int)) Option {
+	return withExitFunc{exit: exit}
+}
+
+type withExitFunc struct{ exit func(code int) }
+
+func (w withExitFunc) Apply(o *Settings) {
+	o.ExitFunc = w.exit
+}
+
+// ExitingAfterShutdown returns an Option that specifies that the process exits
+// once the closers have been notified; unless specified by WithExitCode(), the
+// exit code is zero if all the closers completed within the timeout, and one
+// otherwise.
+func ExitingAfterShutdown() Option {
+	return exitingAfterShutdown{}
+}
+
+type exitingAfterShutdown struct{}
+
+func (exitingAfterShutdown) Apply(o *Settings) {
+	o.ExitAfterShutdown = true
+}
+
+// WithExitCode returns an Option that specifies the function that computes
+// the exit code of the process when exiting after shutdown, e.g. ExitCode().
+func WithExitCode(code func(reason Reason, sig os.Signal, err error) int) Option {
+	return withExitCode{code: code}
+}
+
+type withExitCode struct {
+	code func(reason Reason, sig os.Signal, err error) int
+}
+
+func (w withExitCode) Apply(o *Settings) {
+	o.ExitCode = w.code
+}
+
+// WithReraiseSignal returns an Option that specifies that, once the closers
+// have been notified after a watched signal occurred, the handling of the
+// signal is reset to its default and the signal is sent again to the process,
+// so that the process is terminated by the signal, as the service manager or
+// the parent process may expect.  The handling of the signal is reset for the
+// whole process.  Signals cannot be raised on Windows, where the option has no
+// effect.
+func WithReraiseSignal() Option {
+	return withReraiseSignal{}
+}
+
+type withReraiseSignal struct{}
+
+func (withReraiseSignal) Apply(o *Settings) {
+	o.ReraiseSignal = true
+}
+
+// ForwardingSignals returns an Option that specifies processes to which the
+// watched signals are forwarded, including the one that triggers the
+// notification of the closers, before the closers are notified.  Options can
+// be combined to forward the signals to several processes and process groups;
+// to forward the signals instead of notifying closers, register no closers.
+func ForwardingSignals(pids ...int) Option {
+	return forwardingSignals(func(sig os.Signal) {
+		for _, pid := range pids {
+			if p, err := os.FindProcess(pid); err == nil {
+				_ = p.Signal(sig)
+			}
+		}
+	})
+}
+
+// ForwardingSignalsToGroup returns an Option that specifies a process group to
+// which the watched signals are forwarded, like ForwardingSignals().  Windows
+// has no signals; a CTRL_BREAK event is sent to the console process group
+// instead, which must have been created with the CREATE_NEW_PROCESS_GROUP flag.
+func ForwardingSignalsToGroup(pgid int) Option {
+	return forwardingSignals(func(sig os.Signal) {
+		_ = signalGroup(pgid, sig)
+	})
+}
+
+// Escalation specifies what a Watcher instance does when a watched signal
+// occurs while its closers are being notified.
+type Escalation int
+
+const (
+	// IgnoringRepeats ignores the signal; this is the default.
+	IgnoringRepeats Escalation = iota
+
+	// ExitingOnRepeat exits the process immediately, with an exit code of
+	// one, e.g. when an impatient user interrupts the process again.
+	ExitingOnRepeat
+)
+
+// CountingSignals returns an Option that specifies OS signals that are
+// ignored, rather than having their default effect, and only counted, as
+// reported by SignalStats(), e.g. to find out who keeps sending SIGHUP to the
+// process.  The signals must not be watched.
+func CountingSignals(signals ...os.Signal) Option {
+	return countingSignals{signals: signals}
+}
+
+type countingSignals struct{ signals []os.Signal }
+
+func (c countingSignals) Apply(o *Settings) {
+	o.CountedSignals = c.signals
+}
+
+// WithSuspendHooks returns an Option that specifies functions called when the
+// process is about to be stopped by SIGTSTP, e.g. when the user presses
+// Ctrl-Z, and when it continues on SIGCONT, e.g. to release external leases
+// before being suspended and to reacquire them afterwards; either can be nil.
+// The process is stopped once onStop returns.  onCont is also called when the
+// process continues after having been stopped by SIGSTOP, without onStop.
+// Suspend hooks are not supported on Windows.
+func WithSuspendHooks(onStop, onCont func()) Option {
+	return withSuspendHooks{onStop: onStop, onCont: onCont}
+}
+
+type withSuspendHooks struct{ onStop, onCont func() }
+
+func (w withSuspendHooks) Apply(o *Settings) {
+	o.OnSuspend = w.onStop
+	o.OnResume = w.onCont
+}
+
+// HandlingSessionEnd returns an Option that closes the Watcher instance when
+// the Windows session ends, e.g. when the user logs off, for GUI applications
+// that have no console and are not services, and so are not signalled; the end
+// of the session is blocked, with the reason shown to the user, until the
+// closers have been notified.  It has no effect on other platforms, whose
+// applications are signalled when the session ends.
+func HandlingSessionEnd(reason string) Option {
+	return handlingSessionEnd{reason: reason}
+}
+
+type handlingSessionEnd struct{ reason string }
+
+func (h handlingSessionEnd) Apply(o *Settings) {
+	o.SessionEndReason = h.reason
+}
+
+// CoalescingSignals returns an Option that specifies a window within which a
+// watched signal that repeats the previous one, e.g. when a supervisor sends
+// SIGTERM several times, is coalesced with it: it is neither forwarded nor
+// escalated, but counted, as reported by Coalesced().  Each coalesced signal
+// extends the window, so that a burst of signals counts as one.
+func CoalescingSignals(window time.Duration) Option {
+	return coalescingSignals{window: window}
+}
+
+type coalescingSignals struct{ window time.Duration }
+
+func (c coalescingSignals) Apply(o *Settings) {
+	o.CoalescingWindow = c.window
+}
+
+// WithEscalation returns an Option that specifies what the Watcher instance
+// does when a watched signal occurs while its closers are being notified.
+func WithEscalation(escalation Escalation) Option {
+	return withEscalation{escalation: escalation}
+}
+
+type withEscalation struct{ escalation Escalation }
+
+func (w withEscalation) Apply(o *Settings) {
+	o.Escalation = w.escalation
+}
+
+// SignalPolicy is the shutdown policy of a watched signal, which replaces the
+// timeout, the drain delay, and the escalation of the Watcher instance when
+// the signal triggers the shutdown.
+type SignalPolicy struct {
+	TimeOut    time.Duration
+	DrainDelay time.Duration
+	Escalation Escalation
+
+	// Immediate skips the drain delay and the closers: only the emergency
+	// closers, the flushers, and the last closer are called, e.g. to still
+	// flush a write-ahead log, and the process then exits, with the code
+	// returned by the exit code function; the timeout is not used.
+	Immediate bool
+}
+
+// policyErrors returns the errors of the shutdown policy of what, e.g. a
+// signal.
+func policyErrors(policy SignalPolicy, of string, closerTimeout time.Duration) []error {
+	var errs []error
+
+	if policy.TimeOut <= 0 || policy.TimeOut < closerTimeout {
+		errs = append(errs, fmt.Errorf("timeout %v of %s must be positive and at least the closer timeout %v", policy.TimeOut, of, closerTimeout))
+	}
+
+	if policy.DrainDelay < 0 || (policy.DrainDelay > 0 && policy.DrainDelay >= policy.TimeOut) {
+		errs = append(errs, fmt.Errorf("drain delay %v of %s must be between zero and its timeout %v", policy.DrainDelay, of, policy.TimeOut))
+	}
+
+	return errs
+}
+
+// WithImmediateSignals returns an Option that specifies the watched signals,
+// e.g. SIGQUIT, whose shutdowns are immediate, as specified by
+// SignalPolicy.Immediate, while the other signals remain graceful.
+func WithImmediateSignals(signals ...os.Signal) Option {
+	return withImmediateSignals{signals: signals}
+}
+
+type withImmediateSignals struct{ signals []os.Signal }
+
+func (w withImmediateSignals) Apply(o *Settings) {
+	for _, sig := range w.signals {
+		withSignalPolicy{sig: sig, policy: SignalPolicy{Immediate: true}}.Apply(o)
+	}
+}
+
+// WithSignalPolicy returns an Option that specifies the shutdown policy of a
+// watched signal, e.g. a short timeout for SIGINT, which developers send, and
+// a long one, with a drain delay, for SIGTERM, which orchestrators send; the
+// shutdowns triggered otherwise, e.g. by Close(), follow the settings of the
+// instance.
+func WithSignalPolicy(sig os.Signal, policy SignalPolicy) Option {
+	return withSignalPolicy{sig: sig, policy: policy}
+}
+
+type withSignalPolicy struct {
+	sig    os.Signal
+	policy SignalPolicy
+}
+
+func (w withSignalPolicy) Apply(o *Settings) {
+	if o.SignalPolicies == nil {
+		o.SignalPolicies = make(map[os.Signal]SignalPolicy)
+	}
+
+	o.SignalPolicies[w.sig] = w.policy
+}
+
+// TimeoutPolicy specifies what a Watcher instance does with the closers that
+// have not completed when its timeout fires.
+type TimeoutPolicy int
+
+const (
+	// CancellingOnTimeout cancels the contexts of the closers, and leaves them
+	// running; this is the default.
+	CancellingOnTimeout TimeoutPolicy = iota
+
+	// LeavingRunningOnTimeout leaves the closers running without cancelling
+	// their contexts.
+	LeavingRunningOnTimeout
+
+	// DumpingStacksOnTimeout writes the stacks of all goroutines to the
+	// standard error, and then exits the process with an exit code of one.
+	DumpingStacksOnTimeout
+
+	// ExitingOnTimeout exits the process immediately, with an exit code of
+	// one.
+	ExitingOnTimeout
+)
+
+// WithTimeoutPolicy returns an Option that specifies what the Watcher instance
+// does with the closers that have not completed when its timeout fires.
+func WithTimeoutPolicy(policy TimeoutPolicy) Option {
+	return withTimeoutPolicy{policy: policy}
+}
+
+type withTimeoutPolicy struct{ policy TimeoutPolicy }
+
+func (w withTimeoutPolicy) Apply(o *Settings) {
+	o.TimeoutPolicy = w.policy
+}
+
+// forwardingSignals returns an Option that adds a function to which the
+// watched signals are forwarded.
+func forwardingSignals(forward func(sig os.Signal)) Option {
+	return withForward{forward: forward}
+}
+
+type withForward struct{ forward func(sig os.Signal) }
+
+func (w withForward) Apply(o *Settings) {
+	o.forwards = append(o.forwards, w.forward)
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrAlreadyRunning is returned when creating a watcher whose PID file, see
+// WithPIDFile(), belongs to a process that is still running.
+var ErrAlreadyRunning = errors.New("process already running")
+
+// writePIDFile writes the process ID to the file, unless the file belongs to
+// another process that is still running; a file left by a process that is no
+// longer running is stale, and is replaced.  A process started by an upgrade
+// takes the file over from its parent.
+func writePIDFile(path string) error {
+	if pid, err := readPIDFile(path); err == nil && pid != os.Getpid() && processAlive(pid) {
+		if os.Getenv(upgradeEnv) == "" || pid != os.Getppid() {
+			return fmt.Errorf("pid file %v of process %d: %w", path, pid, ErrAlreadyRunning)
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(tmp, "%d\n", os.Getpid())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+func readPIDFile(path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// removePIDFile removes the PID file, if any, unless it was taken over by
+// another process, e.g. by an upgrade.
+func (w *watcher) removePIDFile() {
+	if w.pidFile == "" {
+		return
+	}
+
+	if pid, err := readPIDFile(w.pidFile); err == nil && pid == os.Getpid() {
+		_ = os.Remove(w.pidFile)
+	}
+}
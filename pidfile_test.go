@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestPIDFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yama")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "app.pid")
+
+	Convey("Ensure the PID file lives as long as the watcher", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithPIDFile(path))
+		So(err, ShouldBeNil)
+
+		b, err := ioutil.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, strconv.Itoa(os.Getpid())+"\n")
+
+		So(watcher.Close(), ShouldBeNil)
+
+		_, err = os.Stat(path)
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+
+	Convey("Ensure stale PID files are replaced", t, func() {
+		So(ioutil.WriteFile(path, []byte("999999999\n"), 0o600), ShouldBeNil)
+
+		watcher, err := yama.NewWatcher(yama.WithPIDFile(path))
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure PID files of running processes are not replaced", t, func() {
+		So(ioutil.WriteFile(path, []byte(fmt.Sprintln(os.Getppid())), 0o600), ShouldBeNil)
+		defer func() { _ = os.Remove(path) }()
+
+		_, err := yama.NewWatcher(yama.WithPIDFile(path))
+		So(errors.Is(err, yama.ErrAlreadyRunning), ShouldBeTrue)
+	})
+}
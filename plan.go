@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Plan returns the ordered plan of the shutdown that the instance would
+// perform with its current closers, without executing anything: the drain
+// delay, the inline closers in registration order, and then the other
+// closers, each within the closer timeout, if any, and all within the timeout;
+// and then the emergency closers, the flushers, and the last closer, within
+// their own timeouts.
+func (w *watcher) Plan() string {
+	w.mu.Lock()
+	closers := append([]io.Closer(nil), w.closers...)
+	w.mu.Unlock()
+
+	var inline, others []io.Closer
+
+	for _, closer := range closers {
+		if _, ok := closer.(InlineCloser); ok {
+			inline = append(inline, closer)
+		} else {
+			others = append(others, closer)
+		}
+	}
+
+	var b strings.Builder
+	step := 0
+
+	printf := func(format string, a ...interface{}) {
+		_, _ = fmt.Fprintf(&b, format, a...)
+	}
+
+	if w.drainDelay > 0 {
+		step++
+		printf("%d. wait for the drain delay of %v\n", step, w.drainDelay)
+	}
+
+	if len(inline) > 0 {
+		step++
+		printf("%d. close in order:\n", step)
+
+		for _, closer := range inline {
+			printf("   - %v\n", closerName(closer))
+		}
+	}
+
+	if len(others) > 0 {
+		step++
+		if w.synchronous {
+			printf("%d. close one at a time, in order, until the timeout:\n", step)
+		} else if w.concurrency > 0 && w.concurrency < len(others) {
+			printf("%d. close concurrently, %d at a time, in order:\n", step, w.concurrency)
+		} else {
+			printf("%d. close concurrently:\n", step)
+		}
+
+		for _, closer := range others {
+			printf("   - %v\n", closerName(closer))
+		}
+	}
+
+	if w.closerTimeout > 0 && !w.synchronous {
+		printf("each within %v, ", w.closerTimeout)
+	}
+
+	printf("all within %v\n", w.timeout)
+
+	if len(w.emergency) > 0 {
+		step++
+		printf("%d. call the emergency closers, within %v:\n", step, w.emergencyTimeout)
+
+		for _, closer := range w.emergency {
+			printf("   - %v\n", closerName(closer))
+		}
+	}
+
+	if len(w.flushers) > 0 {
+		step++
+		printf("%d. flush in order, within %v:\n", step, w.flushTimeout)
+
+		for _, closer := range w.flushers {
+			printf("   - %v\n", closerName(closer))
+		}
+	}
+
+	if w.last != nil {
+		step++
+		printf("%d. close last, within %v:\n   - %v\n", step, w.lastTimeout, closerName(w.last))
+	}
+
+	return b.String()
+}
+
+// PlanDOT returns the plan of the shutdown, like Plan(), as a Graphviz DOT
+// graph: the stages of the shutdown, including the emergency closers, the
+// flushers, and the last closer, are clusters of closers, linked in the order
+// they are performed, and the closers that are called one at a time are
+// linked in order.
+func (w *watcher) PlanDOT() string {
+	w.mu.Lock()
+	closers := append([]io.Closer(nil), w.closers...)
+	w.mu.Unlock()
+
+	var inline, others []io.Closer
+
+	for _, closer := range closers {
+		if _, ok := closer.(InlineCloser); ok {
+			inline = append(inline, closer)
+		} else {
+			others = append(others, closer)
+		}
+	}
+
+	var stages []planStage
+
+	if len(inline) > 0 {
+		stages = append(stages, planStage{label: "close in order", inOrder: true, closers: inline})
+	}
+
+	if len(others) > 0 {
+		stage := planStage{label: "close concurrently", closers: others}
+
+		if w.synchronous {
+			stage = planStage{label: "close one at a time", inOrder: true, closers: others}
+		} else if w.concurrency > 0 && w.concurrency < len(others) {
+			stage.label = fmt.Sprintf("close concurrently, %d at a time", w.concurrency)
+		}
+
+		if w.closerTimeout > 0 && !w.synchronous {
+			stage.label += fmt.Sprintf(", each within %v", w.closerTimeout)
+		}
+
+		stages = append(stages, stage)
+	}
+
+	if len(w.emergency) > 0 {
+		stages = append(stages, planStage{
+			label:   fmt.Sprintf("emergency closers, within %v", w.emergencyTimeout),
+			closers: w.emergency,
+		})
+	}
+
+	if len(w.flushers) > 0 {
+		stages = append(stages, planStage{
+			label:   fmt.Sprintf("flushers, within %v", w.flushTimeout),
+			inOrder: true,
+			closers: w.flushers,
+		})
+	}
+
+	if w.last != nil {
+		stages = append(stages, planStage{
+			label:   fmt.Sprintf("last closer, within %v", w.lastTimeout),
+			closers: []io.Closer{w.last},
+		})
+	}
+
+	var b strings.Builder
+
+	printf := func(format string, a ...interface{}) {
+		_, _ = fmt.Fprintf(&b, format, a...)
+	}
+
+	printf("digraph shutdown {\n")
+	printf("\tcompound=true;\n\trankdir=LR;\n\tnode [shape=box];\n")
+	printf("\tlabel=%v;\n", dotQuote(fmt.Sprintf("all within %v", w.timeout)))
+
+	// the node that the next stage is linked from, and its cluster, if any
+	from, tail := "", ""
+
+	if w.drainDelay > 0 {
+		from = "drain"
+		printf("\n\tdrain [label=%v];\n", dotQuote(fmt.Sprintf("wait for the drain delay of %v", w.drainDelay)))
+	}
+
+	n := 0
+
+	for i, stage := range stages {
+		cluster := fmt.Sprintf("cluster_%d", i+1)
+
+		printf("\n\tsubgraph %v {\n\t\tlabel=%v;\n", cluster, dotQuote(stage.label))
+
+		first := n + 1
+		for _, closer := range stage.closers {
+			n++
+			printf("\t\tn%d [label=%v];\n", n, dotQuote(closerName(closer)))
+		}
+
+		if stage.inOrder {
+			for j := first; j < n; j++ {
+				printf("\t\tn%d -> n%d;\n", j, j+1)
+			}
+		}
+
+		printf("\t}\n")
+
+		if from != "" {
+			attrs := "lhead=" + cluster
+			if tail != "" {
+				attrs = "ltail=" + tail + ", " + attrs
+			}
+
+			printf("\t%v -> n%d [%v];\n", from, first, attrs)
+		}
+
+		from, tail = fmt.Sprintf("n%d", n), cluster
+	}
+
+	printf("}\n")
+
+	return b.String()
+}
+
+// planStage is a stage of the shutdown, in its DOT graph.
+type planStage struct {
+	label   string
+	inOrder bool
+	closers []io.Closer
+}
+
+// dotQuote returns the DOT string literal of s.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// writePlan writes the plan instead of notifying the closers, in dry-run mode.
+func (w *watcher) writePlan() {
+	w.mu.Lock()
+	w.shutdown = true
+	w.mu.Unlock()
+
+	_, _ = io.WriteString(w.dryRun, w.Plan())
+}
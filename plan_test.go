@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestPlan(t *testing.T) {
+
+	Convey("Ensure the plan lists the steps of the shutdown", t, func() {
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(time.Minute),
+			yama.WithDrainDelay(5*time.Second),
+			yama.WithConcurrency(1),
+			yama.WithClosers(
+				yamatest.CloserSpy("db"),
+				yama.InlineFnAsCloser(func() {}),
+				yamatest.CloserSpy("cache")))
+		So(err, ShouldBeNil)
+		defer watcher.Stop()
+
+		So(watcher.Plan(), ShouldEqual, `1. wait for the drain delay of 5s
+2. close in order:
+   - *yama.inlineFnWrapper
+3. close concurrently, 1 at a time, in order:
+   - db
+   - cache
+all within 1m0s
+`)
+	})
+
+	Convey("Ensure the plan lists the emergency closers, the flushers, and the last closer", t, func() {
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(time.Minute),
+			yama.WithClosers(yamatest.CloserSpy("db")),
+			yama.WithEmergencyClosers(5*time.Second, yamatest.CloserSpy("wal")),
+			yama.WithFlushers(time.Second, yamatest.CloserSpy("exporter"), yamatest.CloserSpy("logger")),
+			yama.WithLastCloser(time.Second, yamatest.CloserSpy("debug")))
+		So(err, ShouldBeNil)
+		defer watcher.Stop()
+
+		So(watcher.Plan(), ShouldEqual, `1. close concurrently:
+   - db
+all within 1m0s
+2. call the emergency closers, within 5s:
+   - wal
+3. flush in order, within 1s:
+   - exporter
+   - logger
+4. close last, within 1s:
+   - debug
+`)
+	})
+
+	Convey("Ensure dry runs write the plan without notifying the closers", t, func() {
+		db := yamatest.CloserSpy("db")
+		out := &bytes.Buffer{}
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(time.Minute),
+			yama.WithClosers(db),
+			yama.DryRun(out))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(db, yamatest.ShouldNotHaveBeenClosed)
+		So(out.String(), ShouldEqual, "1. close concurrently:\n   - db\nall within 1m0s\n")
+		So(watcher.AddCloser(db), ShouldEqual, yama.ErrShutdown)
+	})
+
+	Convey("Ensure the plan can be exported as a DOT graph", t, func() {
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(time.Minute),
+			yama.WithDrainDelay(5*time.Second),
+			yama.WithClosers(yamatest.CloserSpy("db"), yamatest.CloserSpy(`"cache"`)),
+			yama.WithFlushers(time.Second, yamatest.CloserSpy("exporter"), yamatest.CloserSpy("logger")),
+			yama.WithLastCloser(time.Second, yamatest.CloserSpy("debug")))
+		So(err, ShouldBeNil)
+		defer watcher.Stop()
+
+		So(watcher.PlanDOT(), ShouldEqual, `digraph shutdown {
+	compound=true;
+	rankdir=LR;
+	node [shape=box];
+	label="all within 1m0s";
+
+	drain [label="wait for the drain delay of 5s"];
+
+	subgraph cluster_1 {
+		label="close concurrently";
+		n1 [label="db"];
+		n2 [label="\"cache\""];
+	}
+	drain -> n1 [lhead=cluster_1];
+
+	subgraph cluster_2 {
+		label="flushers, within 1s";
+		n3 [label="exporter"];
+		n4 [label="logger"];
+		n3 -> n4;
+	}
+	n2 -> n3 [ltail=cluster_1, lhead=cluster_2];
+
+	subgraph cluster_3 {
+		label="last closer, within 1s";
+		n5 [label="debug"];
+	}
+	n4 -> n5 [ltail=cluster_2, lhead=cluster_3];
+}
+`)
+	})
+}
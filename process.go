@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ProcessCloser returns a closer that terminates the started command: the
+// child process is asked to terminate, with SIGTERM on Unix and taskkill on
+// Windows, and is killed if it has not exited within the grace period, or by
+// the deadline of the context when notified by a watcher.  The closer waits
+// for the child to exit, and returns the error returned by cmd.Wait(), e.g.
+// an *exec.ExitError with the child's exit status; the command must not be
+// waited for otherwise.
+func ProcessCloser(cmd *exec.Cmd, termGrace time.Duration) io.Closer {
+	return &processCloser{cmd: cmd, grace: termGrace, exited: make(chan struct{})}
+}
+
+type processCloser struct {
+	cmd    *exec.Cmd
+	grace  time.Duration
+	wait   sync.Once
+	exited chan struct{}
+	err    error
+}
+
+func (p *processCloser) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+func (p *processCloser) CloseContext(ctx context.Context) error {
+	p.wait.Do(func() {
+		go func() {
+			p.err = p.cmd.Wait()
+			close(p.exited)
+		}()
+	})
+
+	select {
+	case <-p.exited:
+		return p.err
+	default:
+	}
+
+	_ = terminate(p.cmd.Process)
+
+	grace := time.NewTimer(p.grace)
+	defer grace.Stop()
+
+	select {
+	case <-p.exited:
+		return p.err
+	case <-grace.C:
+	case <-ctx.Done():
+	}
+
+	_ = p.cmd.Process.Kill()
+	<-p.exited
+
+	return p.err
+}
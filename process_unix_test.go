@@ -0,0 +1,66 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package yama_test
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestProcessCloser(t *testing.T) {
+
+	Convey("Ensure the child is terminated", t, func() {
+		cmd := exec.Command("sleep", "60")
+		So(cmd.Start(), ShouldBeNil)
+
+		err := yama.ProcessCloser(cmd, time.Minute).Close()
+		So(err, ShouldHaveSameTypeAs, &exec.ExitError{})
+		So(cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(), ShouldEqual, syscall.SIGTERM)
+	})
+
+	Convey("Ensure the child is killed after the grace period", t, func() {
+		cmd := exec.Command("sh", "-c", "trap '' TERM; echo ready; exec sleep 60")
+		stdout, err := cmd.StdoutPipe()
+		So(err, ShouldBeNil)
+		So(cmd.Start(), ShouldBeNil)
+
+		_, err = stdout.Read(make([]byte, 6)) // wait until the trap is set
+		So(err, ShouldBeNil)
+
+		_ = yama.ProcessCloser(cmd, 50*time.Millisecond).Close()
+		So(cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(), ShouldEqual, syscall.SIGKILL)
+	})
+
+	Convey("Ensure the exit status of exited children is returned", t, func() {
+		cmd := exec.Command("sh", "-c", "exit 3")
+		So(cmd.Start(), ShouldBeNil)
+		time.Sleep(50 * time.Millisecond)
+
+		err := yama.ProcessCloser(cmd, time.Minute).Close()
+		So(err, ShouldHaveSameTypeAs, &exec.ExitError{})
+		So(err.(*exec.ExitError).ExitCode(), ShouldEqual, 3)
+	})
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Profile is a named bundle of options, e.g. a preset for gRPC services or
+// for batch jobs, that can be registered, so that applications apply it by
+// name with WithProfile(), followed by their own options to override it.  A
+// Profile is itself an Option, and its options can include other profiles.
+type Profile struct {
+	Name    string
+	Options []Option
+}
+
+// Apply applies the options of the profile in order.
+func (p Profile) Apply(o *Settings) {
+	for _, name := range o.profiles {
+		if name == p.Name {
+			o.errs = append(o.errs, fmt.Errorf("profile %q includes itself", p.Name))
+
+			return
+		}
+	}
+
+	o.profiles = append(o.profiles, p.Name)
+	defer func() { o.profiles = o.profiles[:len(o.profiles)-1] }()
+
+	for i, option := range p.Options {
+		if option == nil {
+			o.errs = append(o.errs, fmt.Errorf("option #%d of profile %q must not be null", i, p.Name))
+
+			continue
+		}
+
+		option.Apply(o)
+	}
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = profilesByName(platformProfiles)
+)
+
+// profilesByName returns the profiles by name.
+func profilesByName(registered []Profile) map[string]Profile {
+	profiles := make(map[string]Profile, len(registered))
+
+	for _, p := range registered {
+		profiles[p.Name] = p
+	}
+
+	return profiles
+}
+
+// RegisterProfile registers the profile under its name, typically from the
+// init() function of the package that publishes it; names are unique.
+func RegisterProfile(p Profile) error {
+	if p.Name == "" {
+		return errors.New("profile name must not be empty")
+	}
+
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	if _, ok := profiles[p.Name]; ok {
+		return fmt.Errorf("profile %q is already registered", p.Name)
+	}
+
+	profiles[p.Name] = Profile{Name: p.Name, Options: append([]Option(nil), p.Options...)}
+
+	return nil
+}
+
+// Profiles returns the names of the registered profiles, sorted.
+func Profiles() []string {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func lookupProfile(name string) (Profile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	p, ok := profiles[name]
+
+	return p, ok
+}
+
+// WithProfile returns an Option that applies the options of the registered
+// profile; the options that follow it override its settings.  Unknown
+// profiles are reported by NewWatcher().
+func WithProfile(name string) Option {
+	return withProfile{name: name}
+}
+
+type withProfile struct{ name string }
+
+func (w withProfile) Apply(o *Settings) {
+	p, ok := lookupProfile(w.name)
+	if !ok {
+		o.errs = append(o.errs, fmt.Errorf("unknown profile %q", w.name))
+
+		return
+	}
+
+	p.Apply(o)
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func init() {
+	for _, p := range []yama.Profile{
+		{Name: "test-service", Options: []yama.Option{
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithTimeout(30 * time.Second),
+			yama.WithDrainDelay(5 * time.Second),
+		}},
+		{Name: "test-batch-job", Options: []yama.Option{
+			yama.WithProfile("test-service"),
+			yama.WithTimeout(time.Minute),
+		}},
+		{Name: "test-loop", Options: []yama.Option{yama.WithProfile("test-loop")}},
+	} {
+		if err := yama.RegisterProfile(p); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+
+	Convey("Ensure registered profiles apply their options, and can be overridden", t, func() {
+		s := &yama.Settings{}
+		yama.WithProfile("test-batch-job").Apply(s)
+		yama.WithDrainDelay(0).Apply(s)
+
+		So(s.Signals, ShouldResemble, []os.Signal{syscall.SIGTERM})
+		So(s.TimeOut, ShouldEqual, time.Minute)
+		So(s.DrainDelay, ShouldEqual, 0)
+
+		So(yama.Profiles(), ShouldContain, "test-batch-job")
+		So(yama.Profiles(), ShouldContain, "test-service")
+	})
+
+	Convey("Ensure profiles are registered once", t, func() {
+		So(yama.RegisterProfile(yama.Profile{Name: "test-service"}), ShouldBeError, "profile \"test-service\" is already registered")
+		So(yama.RegisterProfile(yama.Profile{}), ShouldBeError, "profile name must not be empty")
+	})
+
+	Convey("Ensure unknown and recursive profiles are reported", t, func() {
+		_, err := yama.NewWatcher(yama.WithProfile("test-unknown"))
+		So(err, ShouldBeError, "unknown profile \"test-unknown\"")
+
+		_, err = yama.NewWatcher(yama.WithProfile("test-loop"))
+		So(err, ShouldBeError, "profile \"test-loop\" includes itself")
+	})
+
+	Convey("Ensure configurations can name a profile", t, func() {
+		options, err := (&yama.Config{Profile: "test-service", Timeout: "10s"}).Options()
+		So(err, ShouldBeNil)
+
+		s := &yama.Settings{}
+		for _, option := range options {
+			option.Apply(s)
+		}
+
+		So(s.TimeOut, ShouldEqual, 10*time.Second)
+		So(s.DrainDelay, ShouldEqual, 5*time.Second)
+
+		_, err = (&yama.Config{Profile: "test-unknown"}).Options()
+		So(err, ShouldBeError, "unknown profile \"test-unknown\"")
+	})
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// showProgress writes the time left and the closers that have not completed
+// every interval, until stopped.  On a terminal, the status line is updated in
+// place, and cleared once stopped; otherwise, a line is written each time.
+func (w *watcher) showProgress(deadline time.Time, closers []io.Closer, closed []uint32, stop <-chan struct{}) {
+	tty := isTerminal(w.progress)
+	wrote := false
+
+	for {
+		timer := w.clock.NewTimer(w.progressInterval)
+
+		select {
+		case now := <-timer.C():
+			var waiting []string
+			for i, closer := range closers {
+				if atomic.LoadUint32(&closed[i]) == 0 {
+					waiting = append(waiting, closerName(closer))
+				}
+			}
+
+			left := deadline.Sub(now)
+			if left < 0 {
+				left = 0
+			}
+
+			line := fmt.Sprintf("shutting down: %v left, waiting for %v", left.Round(time.Second), strings.Join(waiting, ", "))
+
+			if tty {
+				_, _ = fmt.Fprint(w.progress, "\r\x1b[K"+line)
+			} else {
+				_, _ = fmt.Fprintln(w.progress, line)
+			}
+
+			wrote = true
+		case <-stop:
+			timer.Stop()
+
+			if tty && wrote {
+				_, _ = fmt.Fprint(w.progress, "\r\x1b[K")
+			}
+
+			return
+		}
+	}
+}
+
+// isTerminal reports whether the writer is a terminal.
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
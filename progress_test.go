@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestShowingProgress(t *testing.T) {
+
+	Convey("Ensure the progress of the closers is written periodically", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		release := make(chan struct{})
+		out := &bytes.Buffer{}
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.ShowingProgress(out, 10*time.Second),
+			yama.WithClosers(yama.ErrValFnAsCloser(func() error { <-release; return nil })))
+		So(err, ShouldBeNil)
+
+		go func() {
+			// the timeout and the progress
+			clock.BlockUntil(2)
+			clock.Advance(10 * time.Second)
+
+			// the progress is rearmed once written
+			clock.BlockUntil(2)
+			close(release)
+		}()
+
+		So(watcher.Close(), ShouldBeNil)
+		So(out.String(), ShouldEqual, "shutting down: 50s left, waiting for *yama.errValFnWrapper\n")
+	})
+
+	Convey("Ensure the progress interval is positive", t, func() {
+		_, err := yama.NewWatcher(yama.ShowingProgress(&bytes.Buffer{}, 0))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "progress interval 0s must be positive")
+	})
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// ErrPanicked is the error reported by a watcher closed because a handler
+// panicked with a fatal value.
+type ErrPanicked struct {
+	// Value is the value passed to panic().
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *ErrPanicked) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// RecoveringPanics returns a middleware that recovers the panics of the
+// handler and responds with 500 Internal Server Error.  If fatal reports that a
+// panic leaves the process in a corrupt state, the watcher is closed, without
+// waiting for the closers to be notified, and the error that Wait() returns
+// reports an ErrPanicked; a nil fatal deems all panics fatal.  Panics with
+// http.ErrAbortHandler are not recovered, so that they still abort the
+// response.
+func (w *watcher) RecoveringPanics(fatal func(value interface{}) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				if fatal == nil || fatal(v) {
+					// the closers, e.g. shutting down the server, wait for the
+					// handler to return
+					go func(cause error) { _ = w.closeWithCause(cause) }(&ErrPanicked{Value: v, Stack: debug.Stack()})
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
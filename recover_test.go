@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestRecoveringPanics(t *testing.T) {
+
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("corrupt") })
+
+	Convey("Ensure fatal panics close the watcher", t, func() {
+		db := yamatest.CloserSpy("db")
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(db))
+		So(err, ShouldBeNil)
+
+		rec := httptest.NewRecorder()
+		watcher.RecoveringPanics(nil)(panicking).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		So(rec.Code, ShouldEqual, http.StatusInternalServerError)
+
+		err = watcher.Wait()
+
+		var panicked *yama.ErrPanicked
+		So(errors.As(err, &panicked), ShouldBeTrue)
+		So(panicked.Value, ShouldEqual, "corrupt")
+		So(panicked.Error(), ShouldEqual, "panic: corrupt")
+		So(db, yamatest.ShouldHaveBeenClosed, 1)
+	})
+
+	Convey("Ensure panics that are not fatal only fail the request", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		fatal := func(v interface{}) bool { return v != "corrupt" }
+
+		done := make(chan error, 1)
+		watcher.NotifyDone(done)
+
+		rec := httptest.NewRecorder()
+		watcher.RecoveringPanics(fatal)(panicking).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		So(rec.Code, ShouldEqual, http.StatusInternalServerError)
+
+		select {
+		case <-done:
+			So("closed", ShouldBeEmpty)
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure aborted handlers are not recovered", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		aborting := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) })
+
+		So(func() {
+			watcher.RecoveringPanics(nil)(aborting).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}, ShouldPanicWith, http.ErrAbortHandler)
+		So(watcher.Close(), ShouldBeNil)
+	})
+}
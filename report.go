@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Report describes the notification of the closers of a watcher.
+type Report struct {
+	// Start and End are the times, given by the watcher's clock, at which the
+	// notification started and ended.
+	Start, End time.Time
+
+	// Closers is the number of closers that were notified.
+	Closers int
+
+	// Uncompleted are the closers that didn't complete within the timeout,
+	// and Slow those that didn't complete within the closer timeout, in
+	// registration order.
+	Uncompleted []io.Closer
+	Slow        []io.Closer
+
+	// Rehearsal tells whether the shutdown was a rehearsal, as specified by
+	// WithChaosShutdown().
+	Rehearsal bool
+}
+
+// Duration returns the duration of the notification.
+func (r Report) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Shutdown closes the instance, like Close(), and returns the report of the
+// notification of the closers and the error that Wait() returns.  If the
+// context is done first, Shutdown returns its error with an empty report
+// without waiting for the closers, which keep running, and being notified,
+// within the watcher's timeout; Wait() still blocks until they are done.
+func (w *watcher) Shutdown(ctx context.Context) (Report, error) {
+	done := make(chan struct{})
+
+	go func() {
+		_ = w.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return Report{}, ctx.Err()
+	}
+
+	w.mu.Lock()
+	ended := w.ended
+	w.mu.Unlock()
+
+	return w.report(ended), w.err
+}
+
+// report returns the report of the notification of the closers, ended at the
+// given time.
+func (w *watcher) report(end time.Time) Report {
+	w.mu.Lock()
+	report := Report{Start: w.started, End: end, Closers: w.notified}
+	w.mu.Unlock()
+
+	if reason, _ := w.Reason(); reason == Rehearsal {
+		report.Rehearsal = true
+	}
+
+	var timedOut *ErrTimedOut
+	if errors.As(w.err, &timedOut) {
+		report.Uncompleted = timedOut.Uncompleted
+	}
+
+	var slow *ErrSlowClosers
+	if errors.As(w.err, &slow) {
+		report.Slow = slow.Slow
+	}
+
+	return report
+}
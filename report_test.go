@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestShutdown(t *testing.T) {
+
+	Convey("Ensure shutting down reports the notification", t, func() {
+		clock := yamatest.NewClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		db := yamatest.CloserSpy("db")
+		slow := yama.FnAsCloser(func() { clock.Advance(time.Minute) })
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.ClosingSynchronously(),
+			yama.WithClosers(db, slow))
+		So(err, ShouldBeNil)
+
+		report, err := watcher.Shutdown(context.Background())
+		So(err, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(report.Closers, ShouldEqual, 2)
+		So(report.Uncompleted, ShouldResemble, []io.Closer{slow})
+		So(report.Slow, ShouldBeEmpty)
+		So(report.Duration(), ShouldEqual, time.Minute)
+	})
+
+	Convey("Ensure shutting down is bounded by the context", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(yama.FnAsCloser(func() { <-release })))
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		report, err := watcher.Shutdown(ctx)
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		So(report, ShouldResemble, yama.Report{})
+	})
+}
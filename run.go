@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"errors"
+)
+
+type watcherKey struct{}
+
+// Run creates a watcher with the options and calls run with a context that is
+// cancelled when a configured signal occurs or the parent context is
+// cancelled; the watcher can be retrieved from that context with
+// FromContext().  The watcher's closers are notified when run returns, a
+// configured signal occurs, or the parent context is cancelled.
+//
+// Run returns once both run has returned and the closers have been notified.
+// The returned error combines the error returned by run, unless it is the
+// cancellation of its context, and the error returned by the watcher.
+func Run(ctx context.Context, options []Option, run func(ctx context.Context) error) error {
+	w, err := NewWatcher(options...)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithValue(ctx, watcherKey{}, w))
+	defer cancel()
+
+	go func() {
+		select {
+		case <-w.ctx.Done():
+			cancel()
+		case <-runCtx.Done():
+			_ = w.Close()
+		}
+	}()
+
+	err = run(runCtx)
+	if errors.Is(err, context.Canceled) && runCtx.Err() != nil {
+		err = nil
+	}
+
+	_ = w.Close()
+
+	waitErr := w.Wait()
+	if waitErr != nil && errors.Is(err, waitErr) {
+		waitErr = nil
+	}
+
+	return JoinErrors(err, waitErr)
+}
+
+// NotifyContext creates a watcher with the options and returns a copy of the
+// parent context that is cancelled when a configured signal occurs, the
+// watcher is closed, or the parent context is cancelled, like
+// signal.NotifyContext(); the watcher can be retrieved from that context with
+// FromContext().  The watcher's closers are notified when a configured signal
+// occurs, the watcher is closed, or the parent context is cancelled.
+//
+// The stop function cancels the context and stops watching the signals, like
+// the watcher's Stop() method; the watcher can still be closed.  The options
+// are validated as by NewWatcher(), whose error is returned.
+func NotifyContext(parent context.Context, options ...Option) (ctx context.Context, stop func(), err error) {
+	w, err := NewWatcher(options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := watcherContext(parent, w)
+
+	return ctx, func() {
+		cancel()
+		w.Stop()
+	}, nil
+}
+
+// watcherContext returns a copy of the parent context, from which the watcher
+// can be retrieved, that is cancelled when the watcher is closed or stopped,
+// and closes the watcher when the parent context is cancelled.  The goroutine
+// that follows them only holds the state of the watcher, so that a watcher
+// that is no longer referenced, but by the context, is finalized, and stopped.
+func watcherContext(parent context.Context, w *Watcher) (context.Context, context.CancelFunc) {
+	cancelCtx, cancel := context.WithCancel(parent)
+	inner := w.watcher
+
+	go func() {
+		select {
+		case <-inner.ctx.Done():
+			cancel()
+		case <-inner.stop:
+			cancel()
+		case <-cancelCtx.Done():
+			if parent.Err() != nil {
+				_ = inner.Close()
+			}
+		}
+	}()
+
+	return context.WithValue(cancelCtx, watcherKey{}, w), cancel
+}
+
+// FromContext returns the watcher created by Run() or NotifyContext(), or nil
+// if the context was not derived from the one passed to run or returned by
+// NotifyContext().
+func FromContext(ctx context.Context) *Watcher {
+	w, _ := ctx.Value(watcherKey{}).(*Watcher)
+
+	return w
+}
+
+// WithTriggerContext returns an Option that specifies a context whose
+// cancellation notifies the closers of the Watcher instance, as a configured
+// signal would, for applications that already have a top-level context and
+// want the instance to follow it rather than own it.  Options can be combined
+// to follow several contexts.
+func WithTriggerContext(ctx context.Context) Option {
+	return withTriggerContext{ctx: ctx}
+}
+
+type withTriggerContext struct{ ctx context.Context }
+
+func (w withTriggerContext) Apply(o *Settings) {
+	if w.ctx == nil {
+		o.errs = append(o.errs, errors.New("trigger context must not be null"))
+
+		return
+	}
+
+	o.TriggerContexts = append(o.TriggerContexts, w.ctx)
+}
+
+// watchTriggerContext closes the instance when the context is cancelled, until
+// the instance is closed or stopped.
+func (w *watcher) watchTriggerContext(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		_ = w.Close()
+	case <-w.ctx.Done():
+	case <-w.stop:
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestRun(t *testing.T) {
+
+	Convey("Ensure closers are notified when run returns", t, func() {
+		called := false
+		runErr := errors.New("run failed")
+
+		err := yama.Run(context.Background(),
+			[]yama.Option{yama.WithClosers(yama.FnAsCloser(func() { called = true }))},
+			func(ctx context.Context) error {
+				So(yama.FromContext(ctx), ShouldNotBeNil)
+				return runErr
+			})
+		So(err, ShouldEqual, runErr)
+		So(called, ShouldBeTrue)
+	})
+
+	Convey("Ensure run is cancelled when the parent context is cancelled", t, func() {
+		called := false
+		ctx, cancel := context.WithCancel(context.Background())
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+
+		err := yama.Run(ctx,
+			[]yama.Option{yama.WithClosers(yama.FnAsCloser(func() { called = true }))},
+			func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		So(err, ShouldBeNil)
+		So(called, ShouldBeTrue)
+	})
+
+	Convey("Ensure run is cancelled when the watcher is closed", t, func() {
+		err := yama.Run(context.Background(), nil, func(ctx context.Context) error {
+			go func() { _ = yama.FromContext(ctx).Close() }()
+			<-ctx.Done()
+			return nil
+		})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Ensure the errors of run and the watcher are combined", t, func() {
+		neverClose := yama.FnAsCloser(func() { time.Sleep(time.Second) })
+		runErr := errors.New("run failed")
+
+		err := yama.Run(context.Background(),
+			[]yama.Option{yama.WithTimeout(10 * time.Millisecond), yama.WithClosers(neverClose)},
+			func(ctx context.Context) error { return runErr })
+		So(errors.Is(err, runErr), ShouldBeTrue)
+
+		var timedOut *yama.ErrTimedOut
+		So(errors.As(err, &timedOut), ShouldBeTrue)
+		So(timedOut.Uncompleted, ShouldResemble, []io.Closer{neverClose})
+	})
+
+	Convey("Ensure the watcher's error is not repeated", t, func() {
+		neverClose := yama.FnAsCloser(func() { time.Sleep(time.Second) })
+
+		err := yama.Run(context.Background(),
+			[]yama.Option{yama.WithTimeout(10 * time.Millisecond), yama.WithClosers(neverClose)},
+			func(ctx context.Context) error {
+				w := yama.FromContext(ctx)
+				_ = w.Close()
+				return w.Wait()
+			})
+		So(err, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+	})
+
+	Convey("Ensure invalid options are reported", t, func() {
+		err := yama.Run(context.Background(), []yama.Option{yama.WithClosers(nil)},
+			func(ctx context.Context) error { return nil })
+		So(err, ShouldBeError)
+	})
+
+	Convey("Ensure there is no watcher in other contexts", t, func() {
+		So(yama.FromContext(context.Background()), ShouldBeNil)
+	})
+}
+
+func TestNotifyContext(t *testing.T) {
+
+	Convey("Ensure the context is cancelled when the watcher is closed", t, func() {
+		called := false
+		ctx, stop, err := yama.NotifyContext(context.Background(),
+			yama.WithClosers(yama.FnAsCloser(func() { called = true })))
+		So(err, ShouldBeNil)
+		defer stop()
+
+		watcher := yama.FromContext(ctx)
+		So(watcher, ShouldNotBeNil)
+		So(watcher.Close(), ShouldBeNil)
+		<-ctx.Done()
+		So(called, ShouldBeTrue)
+	})
+
+	Convey("Ensure cancelling the parent context closes the watcher", t, func() {
+		closed := make(chan struct{})
+		parent, cancel := context.WithCancel(context.Background())
+		ctx, stop, err := yama.NotifyContext(parent,
+			yama.WithClosers(yama.FnAsCloser(func() { close(closed) })))
+		So(err, ShouldBeNil)
+		defer stop()
+
+		cancel()
+		<-ctx.Done()
+		So(yama.FromContext(ctx).Wait(), ShouldBeNil)
+		<-closed
+	})
+
+	Convey("Ensure stopping cancels the context without closing the watcher", t, func() {
+		called := false
+		ctx, stop, err := yama.NotifyContext(context.Background(),
+			yama.WithClosers(yama.FnAsCloser(func() { called = true })))
+		So(err, ShouldBeNil)
+
+		stop()
+		<-ctx.Done()
+		So(called, ShouldBeFalse)
+
+		So(yama.FromContext(ctx).Close(), ShouldBeNil)
+		So(called, ShouldBeTrue)
+	})
+
+	Convey("Ensure invalid options are reported", t, func() {
+		ctx, stop, err := yama.NotifyContext(context.Background(), yama.WithClock(nil))
+		So(err, ShouldBeError)
+		So(ctx, ShouldBeNil)
+		So(stop, ShouldBeNil)
+	})
+
+	Convey("Ensure watchers that are no longer referenced are stopped, cancelling their context", t, func() {
+		done := func() <-chan struct{} {
+			ctx, _, err := yama.NotifyContext(context.Background(),
+				yama.WatchingSignals(os.Interrupt),
+				yama.WithSignalSource(yamatest.NewSignals()))
+			So(err, ShouldBeNil)
+
+			return ctx.Done()
+		}()
+
+		stopped := false
+		for i := 0; i < 100 && !stopped; i++ {
+			runtime.GC()
+
+			select {
+			case <-done:
+				stopped = true
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		So(stopped, ShouldBeTrue)
+	})
+}
+
+func TestWithTriggerContext(t *testing.T) {
+
+	Convey("Ensure cancelling the context notifies the closers", t, func() {
+		called := false
+		ctx, cancel := context.WithCancel(context.Background())
+		watcher, err := yama.NewWatcher(
+			yama.WithTriggerContext(ctx),
+			yama.WithClosers(yama.FnAsCloser(func() { called = true })))
+		So(err, ShouldBeNil)
+
+		cancel()
+		So(watcher.Wait(), ShouldBeNil)
+		So(called, ShouldBeTrue)
+	})
+
+	Convey("Ensure a nil context is reported", t, func() {
+		_, err := yama.NewWatcher(yama.WithTriggerContext(nil))
+		So(err, ShouldBeError)
+	})
+}
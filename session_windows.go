@@ -0,0 +1,184 @@
+//go:build windows
+// +build windows
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	wmDestroy         = 0x0002
+	wmClose           = 0x0010
+	wmQueryEndSession = 0x0011
+	wmEndSession      = 0x0016
+)
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+
+	procRegisterClassExW           = user32.NewProc("RegisterClassExW")
+	procUnregisterClassW           = user32.NewProc("UnregisterClassW")
+	procCreateWindowExW            = user32.NewProc("CreateWindowExW")
+	procDefWindowProcW             = user32.NewProc("DefWindowProcW")
+	procGetMessageW                = user32.NewProc("GetMessageW")
+	procTranslateMessage           = user32.NewProc("TranslateMessage")
+	procDispatchMessageW           = user32.NewProc("DispatchMessageW")
+	procPostMessageW               = user32.NewProc("PostMessageW")
+	procPostQuitMessage            = user32.NewProc("PostQuitMessage")
+	procShutdownBlockReasonCreate  = user32.NewProc("ShutdownBlockReasonCreate")
+	procShutdownBlockReasonDestroy = user32.NewProc("ShutdownBlockReasonDestroy")
+
+	procGetModuleHandleW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetModuleHandleW")
+)
+
+type wndClassEx struct {
+	size       uint32
+	style      uint32
+	wndProc    uintptr
+	clsExtra   int32
+	wndExtra   int32
+	instance   uintptr
+	icon       uintptr
+	cursor     uintptr
+	background uintptr
+	menuName   *uint16
+	className  *uint16
+	iconSm     uintptr
+}
+
+type msg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+	private uint32
+}
+
+// watchSessionEnd creates a hidden window, since message-only windows are not
+// sent the session messages, whose message loop runs on a goroutine locked to
+// its thread until the closers have been notified.  When the session ends,
+// the instance is closed, and the end of the session is blocked, with the
+// reason, until the closers have been notified.
+func (w *watcher) watchSessionEnd(reason string) error {
+	created := make(chan error, 1)
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		hwnd, class, err := w.createSessionWindow(reason)
+		created <- err
+
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			instance, _, _ := procGetModuleHandleW.Call(0)
+			_, _, _ = procUnregisterClassW.Call(uintptr(unsafe.Pointer(class)), instance)
+		}()
+
+		go func() {
+			<-w.finished
+			_, _, _ = procPostMessageW.Call(hwnd, wmClose, 0, 0)
+		}()
+
+		var m msg
+
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				return
+			}
+
+			_, _, _ = procTranslateMessage.Call(uintptr(unsafe.Pointer(&m)))
+			_, _, _ = procDispatchMessageW.Call(uintptr(unsafe.Pointer(&m)))
+		}
+	}()
+
+	return <-created
+}
+
+// createSessionWindow registers the window class and creates the hidden
+// window that is sent the session messages.
+func (w *watcher) createSessionWindow(reason string) (uintptr, *uint16, error) {
+	text, err := syscall.UTF16PtrFromString(reason)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	class, err := syscall.UTF16PtrFromString(fmt.Sprintf("yama-session-%p", w))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	instance, _, _ := procGetModuleHandleW.Call(0)
+
+	proc := func(hwnd, message, wParam, lParam uintptr) uintptr {
+		switch message {
+		case wmQueryEndSession:
+			_, _, _ = procShutdownBlockReasonCreate.Call(hwnd, uintptr(unsafe.Pointer(text)))
+			go func() { _ = w.Close() }()
+
+			return 1
+		case wmEndSession:
+			// the process can be terminated as soon as the message is handled
+			if wParam != 0 {
+				_ = w.Close()
+			}
+
+			_, _, _ = procShutdownBlockReasonDestroy.Call(hwnd)
+
+			return 0
+		case wmDestroy:
+			_, _, _ = procPostQuitMessage.Call(0)
+			return 0
+		}
+
+		r, _, _ := procDefWindowProcW.Call(hwnd, message, wParam, lParam)
+
+		return r
+	}
+
+	wc := wndClassEx{
+		wndProc:   syscall.NewCallback(proc),
+		instance:  instance,
+		className: class,
+	}
+	wc.size = uint32(unsafe.Sizeof(wc))
+
+	if r, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wc))); r == 0 {
+		return 0, nil, fmt.Errorf("register window class: %w", err)
+	}
+
+	hwnd, _, err := procCreateWindowExW.Call(0, uintptr(unsafe.Pointer(class)), uintptr(unsafe.Pointer(class)),
+		0, 0, 0, 0, 0, 0, 0, instance, 0)
+	if hwnd == 0 {
+		_, _, _ = procUnregisterClassW.Call(uintptr(unsafe.Pointer(class)), instance)
+		return 0, nil, fmt.Errorf("create window: %w", err)
+	}
+
+	return hwnd, class, nil
+}
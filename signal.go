@@ -0,0 +1,260 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignalSource delivers signals to a watcher; its methods have the same
+// semantics as signal.Notify() and signal.Stop().
+type SignalSource interface {
+	// Notify causes the source to relay the signals to c.  If no signals are
+	// provided, all signals are relayed to c.
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+
+	// Stop causes the source to stop relaying signals to c.
+	Stop(c chan<- os.Signal)
+}
+
+// osSignals relays the signals of the OS, through the dispatcher shared by all
+// the watchers of the process.
+type osSignals struct{}
+
+func (osSignals) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	dispatch.Notify(c, sig...)
+}
+
+func (osSignals) Stop(c chan<- os.Signal) {
+	dispatch.Stop(c)
+}
+
+var dispatch = &dispatcher{
+	channels: make(map[chan<- os.Signal][]os.Signal),
+	counts:   make(map[os.Signal]int),
+}
+
+// dispatcher multiplexes the watchers of the process over a single
+// registration with the signal package, so that independent watchers are not
+// affected by each other's registrations.  Like the signal package, signals
+// are relayed to every channel registered for them, without blocking.
+type dispatcher struct {
+	mu       sync.Mutex
+	c        chan os.Signal
+	channels map[chan<- os.Signal][]os.Signal
+	counts   map[os.Signal]int
+	all      int
+}
+
+func (d *dispatcher) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	listenEvents(d)
+
+	if len(sig) == 0 {
+		d.all++
+	}
+
+	for _, s := range sig {
+		d.counts[s]++
+	}
+
+	d.channels[c] = append(d.channels[c], sig...)
+
+	if d.c == nil || cap(d.c) < d.capacity() {
+		d.relisten()
+		return
+	}
+
+	signal.Notify(d.c, sig...)
+}
+
+func (d *dispatcher) Stop(c chan<- os.Signal) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	signals, ok := d.channels[c]
+	if !ok {
+		return
+	}
+
+	delete(d.channels, c)
+
+	released := false
+
+	if len(signals) == 0 {
+		d.all--
+		released = d.all == 0
+	}
+
+	for _, s := range signals {
+		if d.counts[s]--; d.counts[s] == 0 {
+			delete(d.counts, s)
+			released = true
+		}
+	}
+
+	if !released {
+		return
+	}
+
+	// the signal package cannot release some of the signals of a channel
+	d.relisten()
+}
+
+// relisten registers the signals with a new channel before the current one,
+// if any, is released, leaving no window in which they are not caught.
+func (d *dispatcher) relisten() {
+	old := d.c
+	d.c = nil
+
+	if d.all > 0 || len(d.counts) > 0 {
+		d.c = d.listen(d.capacity())
+
+		if d.all > 0 {
+			signal.Notify(d.c)
+		} else {
+			for s := range d.counts {
+				signal.Notify(d.c, s)
+			}
+		}
+	}
+
+	if old != nil {
+		signal.Stop(old)
+		close(old)
+	}
+}
+
+// capacity returns the buffer of the channel registered with the signal
+// package, which holds one of each distinct signal, so that the signal package
+// does not drop a signal while another one is relayed; when all the signals
+// are registered, those that are named are held.
+func (d *dispatcher) capacity() int {
+	n := len(d.counts)
+	if d.all > 0 && n < len(signals) {
+		n = len(signals)
+	}
+
+	if n == 0 {
+		n = 1
+	}
+
+	return n
+}
+
+// listen creates a channel whose signals are relayed until it is closed.
+func (d *dispatcher) listen(capacity int) chan os.Signal {
+	c := make(chan os.Signal, capacity)
+
+	go func() {
+		for sig := range c {
+			d.relay(sig)
+		}
+	}()
+
+	return c
+}
+
+func (d *dispatcher) relay(sig os.Signal) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for c, signals := range d.channels {
+		if len(signals) > 0 && !watches(signals, sig) {
+			continue
+		}
+
+		select {
+		case c <- sig:
+		default:
+		}
+	}
+}
+
+// watches reports whether sig is one of the signals watched by a watcher.
+func watches(signals []os.Signal, sig os.Signal) bool {
+	for _, s := range signals {
+		if s == sig {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reraise resets the handling of the signal to its default and sends it to
+// the process, and then gives the signal time to terminate the process; the
+// signal is delivered asynchronously, possibly to another thread.
+func reraise(sig os.Signal) {
+	signal.Reset(sig)
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return
+	}
+
+	if err := p.Signal(sig); err == nil {
+		time.Sleep(time.Second)
+	}
+}
+
+// signalName returns the name of the signal, e.g. SIGTERM, or its
+// description if it has no name.
+func signalName(sig os.Signal) string {
+	for name, s := range signals {
+		if s == sig {
+			return name
+		}
+	}
+
+	return sig.String()
+}
+
+// parseSignals parses a comma separated list of signal names, such as
+// "SIGTERM,SIGINT"; the names are case insensitive, and the SIG prefix is
+// optional; the signals that are not named after one, such as BEFOREUNLOAD
+// on js, are named without it.
+func parseSignals(names string) ([]os.Signal, error) {
+	var parsed []os.Signal
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		sig, ok := signals[name]
+		if !ok {
+			sig, ok = signals["SIG"+name]
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("unknown signal %v", name)
+		}
+
+		parsed = append(parsed, sig)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,47 @@
+//go:build aix
+// +build aix
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"syscall"
+)
+
+// platformSignals are the signals of AIX that can be watched besides the
+// common ones: SIGDANGER when paging space runs low, which precedes processes
+// being killed, SIGPWR on power failure, SIGMIGRATE on migration, and SIGXCPU
+// and SIGXFSZ when resource limits are exceeded.
+var platformSignals = map[string]os.Signal{
+	"SIGDANGER":  syscall.SIGDANGER,
+	"SIGPWR":     syscall.SIGPWR,
+	"SIGMIGRATE": syscall.SIGMIGRATE,
+	"SIGXCPU":    syscall.SIGXCPU,
+	"SIGXFSZ":    syscall.SIGXFSZ,
+}
+
+// platformProfiles are the profiles of AIX.  The SRC stops a subsystem with
+// SIGTERM, and when paging space runs low, the kernel kills the processes that
+// do not handle SIGDANGER first, so the src profile shuts down on either.
+var platformProfiles = []Profile{
+	{
+		Name:    "src",
+		Options: []Option{WatchingSignals(syscall.SIGTERM, syscall.SIGDANGER)},
+	},
+}
@@ -0,0 +1,56 @@
+//go:build aix
+// +build aix
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestPlatformSignals(t *testing.T) {
+
+	Convey("Ensure the signals of AIX can be watched by name", t, func() {
+		c := yama.Config{Signals: []string{"SIGDANGER", "pwr", "SIGMIGRATE", "SIGXCPU", "SIGXFSZ"}}
+
+		options, err := c.Options()
+		So(err, ShouldBeNil)
+
+		watcher, err := yama.NewWatcher(options...)
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure the src profile is registered and shuts down on SIGDANGER", t, func() {
+		So(yama.Profiles(), ShouldContain, "src")
+
+		signals := yamatest.NewSignals()
+
+		watcher, err := yama.NewWatcher(yama.WithProfile("src"), yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGDANGER), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+}
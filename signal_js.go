@@ -0,0 +1,115 @@
+//go:build js
+// +build js
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"syscall/js"
+)
+
+// pageEvent is a signal delivered when the event of a browser page occurs.
+type pageEvent string
+
+func (e pageEvent) String() string {
+	return string(e)
+}
+
+func (pageEvent) Signal() {}
+
+// Signals delivered by browsers on js, which are watched like the other
+// signals: BeforeUnload when the page is about to be unloaded, and PageHidden
+// when the page becomes hidden, e.g. when its tab is switched away from,
+// which may be the last event a mobile browser delivers before the page is
+// discarded.  Since the page is unloaded once the event has been handled, the
+// closers should complete promptly, e.g. be inline closers.
+var (
+	BeforeUnload os.Signal = pageEvent("beforeunload")
+	PageHidden   os.Signal = pageEvent("visibilitychange")
+)
+
+// signals are the signals that can be watched, by name; on Node.js, SIGINT,
+// SIGQUIT and SIGTERM are delivered by the process events, whose listeners do
+// not keep the process running.
+var signals = map[string]os.Signal{
+	"SIGINT":       syscall.SIGINT,
+	"SIGQUIT":      syscall.SIGQUIT,
+	"SIGTERM":      syscall.SIGTERM,
+	"BEFOREUNLOAD": BeforeUnload,
+	"PAGEHIDDEN":   PageHidden,
+}
+
+// eventSignals are the signals that deliver the events.
+var eventSignals = map[Event][]os.Signal{
+	Interrupt:  {os.Interrupt},
+	Terminate:  {syscall.SIGTERM},
+	SessionEnd: {BeforeUnload},
+}
+
+// signalGroup is not supported on js, which cannot start processes.
+func signalGroup(int, os.Signal) error {
+	return errors.New("signals are not supported on js")
+}
+
+var listening sync.Once
+
+// listenEvents installs the listeners of the page events, in browsers, or of
+// the process signals, on Node.js, the first time signals are watched; they
+// relay the events to the watchers of the dispatcher.
+func listenEvents(d *dispatcher) {
+	listening.Do(func() {
+		relay := func(sig os.Signal) js.Func {
+			return js.FuncOf(func(js.Value, []js.Value) interface{} {
+				d.relay(sig)
+				return nil
+			})
+		}
+
+		global := js.Global()
+
+		if process := global.Get("process"); process.Truthy() && process.Get("on").Truthy() {
+			for _, name := range []string{"SIGINT", "SIGQUIT", "SIGTERM"} {
+				process.Call("on", name, relay(signals[name]))
+			}
+		}
+
+		if window := global.Get("window"); window.Truthy() {
+			window.Call("addEventListener", "beforeunload", relay(BeforeUnload))
+		}
+
+		if document := global.Get("document"); document.Truthy() {
+			document.Call("addEventListener", "visibilitychange", js.FuncOf(func(js.Value, []js.Value) interface{} {
+				if document.Get("visibilityState").String() == "hidden" {
+					d.relay(PageHidden)
+				}
+				return nil
+			}))
+		}
+	})
+}
+
+// signalNumber returns the number of the signal, if it has one.
+func signalNumber(sig os.Signal) (int, bool) {
+	s, ok := sig.(syscall.Signal)
+
+	return int(s), ok
+}
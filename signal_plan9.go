@@ -0,0 +1,58 @@
+//go:build plan9
+// +build plan9
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// signals are the notes that can be watched, by name; Plan 9 has no signals,
+// but notes, which os/signal delivers as syscall.Note, and which are named
+// after the signals of the other ports, once each so that their name is
+// stable.  The kill note cannot be caught.
+var signals = map[string]os.Signal{
+	"SIGINT":  os.Interrupt,
+	"SIGHUP":  syscall.Note("hangup"),
+	"SIGALRM": syscall.Note("alarm"),
+}
+
+// eventSignals are the notes that deliver the events.
+var eventSignals = map[Event][]os.Signal{
+	Interrupt:    {os.Interrupt},
+	Terminate:    {syscall.Note("hangup")},
+	ConsoleClose: {syscall.Note("hangup")},
+}
+
+// signalGroup posts the note to the process group.
+func signalGroup(pgid int, sig os.Signal) error {
+	return ioutil.WriteFile("/proc/"+strconv.Itoa(pgid)+"/notepg", []byte(sig.String()), 0)
+}
+
+// signalNumber reports no number, since notes are not numbered.
+func signalNumber(os.Signal) (int, bool) {
+	return 0, false
+}
+
+// listenEvents does nothing, since all the notes are delivered by the signal
+// package.
+func listenEvents(*dispatcher) {}
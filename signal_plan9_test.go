@@ -0,0 +1,60 @@
+//go:build plan9
+// +build plan9
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestPlatformSignals(t *testing.T) {
+
+	Convey("Ensure the notes of Plan 9 can be watched by name", t, func() {
+		c := yama.Config{Signals: []string{"SIGINT", "hup", "SIGALRM"}}
+
+		options, err := c.Options()
+		So(err, ShouldBeNil)
+
+		watcher, err := yama.NewWatcher(options...)
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure the notes of Plan 9 have a single name", t, func() {
+		combination := yama.SignalCombination{
+			Signals: []os.Signal{os.Interrupt, syscall.Note("hangup")},
+			Policy:  yama.SignalPolicy{TimeOut: time.Second},
+		}
+
+		for i := 0; i < 10; i++ {
+			_, err := yama.NewWatcher(
+				yama.WatchingSignals(syscall.Note("alarm")),
+				yama.CombiningSignals(time.Second, combination))
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldContainSubstring, "combination SIGINT+SIGHUP must have a watched signal")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+//go:build solaris
+// +build solaris
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// platformSignals are the signals of Solaris and illumos that can be watched
+// besides the common ones: SIGPWR on power failure, SIGFREEZE and SIGTHAW
+// around checkpoints and suspends, and SIGXCPU and SIGXFSZ when resource
+// limits are exceeded.
+var platformSignals = map[string]os.Signal{
+	"SIGPWR":    syscall.SIGPWR,
+	"SIGFREEZE": syscall.SIGFREEZE,
+	"SIGTHAW":   syscall.SIGTHAW,
+	"SIGXCPU":   syscall.SIGXCPU,
+	"SIGXFSZ":   syscall.SIGXFSZ,
+}
+
+// platformProfiles are the profiles of Solaris and illumos.  SMF stops a
+// service by sending SIGTERM to every process of its contract, including the
+// children of the process, so the smf profile watches SIGTERM and SIGINT, and
+// coalesces the repeated signals, so that a process that supervises its
+// children does not escalate on the SIGTERM that they also received.
+var platformProfiles = []Profile{
+	{
+		Name: "smf",
+		Options: []Option{
+			WatchingSignals(syscall.SIGTERM, syscall.SIGINT),
+			CoalescingSignals(time.Second),
+		},
+	},
+}
@@ -0,0 +1,79 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// signals are the signals that can be watched, by name, including those of
+// the platform.
+var signals = withPlatformSignals(map[string]os.Signal{
+	"SIGHUP":   syscall.SIGHUP,
+	"SIGINT":   syscall.SIGINT,
+	"SIGQUIT":  syscall.SIGQUIT,
+	"SIGTERM":  syscall.SIGTERM,
+	"SIGUSR1":  syscall.SIGUSR1,
+	"SIGUSR2":  syscall.SIGUSR2,
+	"SIGPIPE":  syscall.SIGPIPE,
+	"SIGALRM":  syscall.SIGALRM,
+	"SIGWINCH": syscall.SIGWINCH,
+})
+
+// withPlatformSignals adds the signals of the platform to the signals.
+func withPlatformSignals(signals map[string]os.Signal) map[string]os.Signal {
+	for name, sig := range platformSignals {
+		signals[name] = sig
+	}
+
+	return signals
+}
+
+// eventSignals are the signals that deliver the events.
+var eventSignals = map[Event][]os.Signal{
+	Interrupt:    {os.Interrupt},
+	Terminate:    {syscall.SIGTERM},
+	Reload:       {syscall.SIGHUP},
+	ConsoleClose: {syscall.SIGHUP},
+	SessionEnd:   {syscall.SIGHUP, syscall.SIGTERM},
+}
+
+// signalGroup sends the signal to the process group.
+func signalGroup(pgid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return errors.New("unsupported signal")
+	}
+
+	return syscall.Kill(-pgid, s)
+}
+
+// listenEvents does nothing, since all the signals are delivered by the signal
+// package.
+func listenEvents(*dispatcher) {}
+
+// signalNumber returns the number of the signal, if it has one.
+func signalNumber(sig os.Signal) (int, bool) {
+	s, ok := sig.(syscall.Signal)
+
+	return int(s), ok
+}
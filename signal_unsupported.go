@@ -0,0 +1,52 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows && !js && !plan9
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows,!js,!plan9
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+// signals are the signals that can be watched, by name; the other ports, such
+// as wasip1, deliver no signals to the signal package, but the interrupt can
+// still be watched, e.g. with a SignalSource.
+var signals = map[string]os.Signal{
+	"SIGINT": os.Interrupt,
+}
+
+// eventSignals are the signals that deliver the events.
+var eventSignals = map[Event][]os.Signal{
+	Interrupt: {os.Interrupt},
+}
+
+// signalGroup is not supported on the other ports.
+func signalGroup(int, os.Signal) error {
+	return errors.New("signals are not supported on " + runtime.GOOS)
+}
+
+// listenEvents does nothing, since there are no events to listen to.
+func listenEvents(*dispatcher) {}
+
+// signalNumber reports no number, since the signals of the other ports are
+// not numbered.
+func signalNumber(os.Signal) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,74 @@
+//go:build windows
+// +build windows
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"syscall"
+)
+
+// signals are the signals that can be watched, by name.
+var signals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// eventSignals are the signals that deliver the events; the signal package
+// delivers the close, logoff, and shutdown events as SIGTERM.
+var eventSignals = map[Event][]os.Signal{
+	Interrupt:    {os.Interrupt},
+	Terminate:    {syscall.SIGTERM},
+	ConsoleClose: {syscall.SIGTERM},
+	SessionEnd:   {syscall.SIGTERM},
+}
+
+// signalGroup sends a CTRL_BREAK event to the console process group, whatever
+// the signal, since Windows has no signals.
+func signalGroup(pgid int, _ os.Signal) error {
+	d, err := syscall.LoadDLL("kernel32.dll")
+	if err != nil {
+		return err
+	}
+
+	proc, err := d.FindProc("GenerateConsoleCtrlEvent")
+	if err != nil {
+		return err
+	}
+
+	r, _, err := proc.Call(syscall.CTRL_BREAK_EVENT, uintptr(pgid))
+	if r == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// listenEvents does nothing, since all the signals are delivered by the signal
+// package.
+func listenEvents(*dispatcher) {}
+
+// signalNumber returns the number of the signal, if it has one.
+func signalNumber(sig os.Signal) (int, bool) {
+	s, ok := sig.(syscall.Signal)
+
+	return int(s), ok
+}
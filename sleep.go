@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"time"
+)
+
+// DetectingSleep returns an Option that specifies that the Watcher instance
+// checks every interval whether the system has slept, e.g. a laptop whose lid
+// was closed or an edge device that was suspended, which shows as a jump of
+// the wall clock, of more than the interval, ahead of the monotonic clock.
+// Sleep is only detected once the system has resumed, since a process cannot
+// observe the suspend itself: onSuspend is not called when the system
+// suspends, but after it resumed, right before onResume, with the time at
+// which the system was last seen awake, and onResume with how long it slept;
+// either can be nil.  Since the clock of the OS tells sleep from the wall
+// clock, a step of the wall clock forward by more than the interval, set by
+// hand or by NTP, is taken for sleep, while steps backward are ignored.  The
+// clock of the instance must implement SleepClock, as the clock of the OS
+// does.
+func DetectingSleep(interval time.Duration, onSuspend func(at time.Time), onResume func(slept time.Duration)) Option {
+	return detectingSleep{interval: interval, onSuspend: onSuspend, onResume: onResume}
+}
+
+type detectingSleep struct {
+	interval  time.Duration
+	onSuspend func(at time.Time)
+	onResume  func(slept time.Duration)
+}
+
+func (d detectingSleep) Apply(o *Settings) {
+	o.SleepInterval = d.interval
+	o.OnSystemSuspend = d.onSuspend
+	o.OnSystemResume = d.onResume
+}
+
+// ExcludingSleepFromBudget returns an Option that specifies that the time the
+// system is detected to have slept, by DetectingSleep(), once the shutdown is
+// triggered is added to the budget of the closers, as if ExtendDeadline() had
+// been called, so that closers are not reported as timed out because the
+// system slept in the middle of the drain.
+func ExcludingSleepFromBudget() Option {
+	return excludingSleepFromBudget{}
+}
+
+type excludingSleepFromBudget struct{}
+
+func (excludingSleepFromBudget) Apply(o *Settings) {
+	o.ExcludeSleep = true
+}
+
+// watchSleep checks every interval how long the system has slept, calls the
+// hooks when it has slept for more than the interval since the previous check,
+// and excludes that time from the budget if configured, until the instance is
+// stopped.
+func (w *watcher) watchSleep(clock SleepClock, interval time.Duration, onSuspend func(at time.Time), onResume func(slept time.Duration), exclude bool) {
+	awake, slept := clock.Now(), clock.Slept()
+
+	for {
+		timer := clock.NewTimer(interval)
+
+		select {
+		case now := <-timer.C():
+			total := clock.Slept()
+			d := total - slept
+			slept = total
+
+			if d > interval {
+				if exclude {
+					w.excludeSleep(d)
+				}
+
+				if onSuspend != nil {
+					onSuspend(awake)
+				}
+
+				if onResume != nil {
+					onResume(d)
+				}
+			}
+
+			awake = now
+		case <-w.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// excludeSleep moves the budget of the closers by the time the system slept,
+// if it has started and is not spent.
+func (w *watcher) excludeSleep(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.budget.IsZero() || w.budgetSpent {
+		return
+	}
+
+	w.budget = w.budget.Add(d)
+
+	if w.draining != nil {
+		w.draining.setDeadline(w.budget)
+	}
+}
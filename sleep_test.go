@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+// plainClock hides the Slept() method of the clock it wraps.
+type plainClock struct{ yama.Clock }
+
+func TestSleep(t *testing.T) {
+	epoch := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Ensure the hooks are called once the system has slept", t, func() {
+		clock := yamatest.NewClock(epoch)
+		suspended := make(chan time.Time, 1)
+		resumed := make(chan time.Duration, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.DetectingSleep(time.Minute,
+				func(at time.Time) { suspended <- at },
+				func(slept time.Duration) { resumed <- slept }))
+		So(err, ShouldBeNil)
+		defer watcher.Stop()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		clock.BlockUntil(1)
+		So(suspended, ShouldBeEmpty)
+
+		clock.Sleep(time.Hour)
+		So(<-suspended, ShouldEqual, epoch.Add(time.Minute))
+		So(<-resumed, ShouldEqual, time.Hour)
+	})
+
+	Convey("Ensure the time slept is excluded from the budget", t, func() {
+		clock := yamatest.NewClock(epoch)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(10*time.Second),
+			yama.WithDrainDelay(2*time.Second),
+			yama.DetectingSleep(500*time.Millisecond, nil, nil),
+			yama.ExcludingSleepFromBudget())
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+
+		clock.BlockUntil(2)
+		clock.Sleep(time.Second)
+
+		for watcher.Remaining() != 10*time.Second {
+			time.Sleep(time.Millisecond)
+		}
+
+		clock.Advance(time.Second)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure invalid sleep detections are rejected", t, func() {
+		_, err := yama.NewWatcher(yama.DetectingSleep(0, func(time.Time) {}, nil))
+		So(err, ShouldBeError)
+
+		_, err = yama.NewWatcher(yama.ExcludingSleepFromBudget())
+		So(err, ShouldBeError)
+
+		_, err = yama.NewWatcher(
+			yama.WithClock(plainClock{yamatest.NewClock(epoch)}),
+			yama.DetectingSleep(time.Minute, nil, nil))
+		So(err, ShouldBeError)
+	})
+}
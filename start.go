@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrStarted is returned when starting a watcher that was already started.
+var ErrStarted = errors.New("watcher has been started")
+
+// Starter is a start hook, run by Watcher.Start(), and the closer that stops
+// what it started.
+type Starter struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  io.Closer
+}
+
+// WithStarter returns an Option that specifies a start hook, and the closer,
+// which can be nil, that stops what it started; the option can be specified
+// several times, and the hooks are run in the order they are specified.
+func WithStarter(name string, start func(ctx context.Context) error, stop io.Closer) Option {
+	return withStarter{starter: Starter{Name: name, Start: start, Stop: stop}}
+}
+
+type withStarter struct{ starter Starter }
+
+func (w withStarter) Apply(o *Settings) {
+	o.Starters = append(o.Starters, w.starter)
+}
+
+// WithRestartSignals returns an Option that specifies the OS signals, e.g.
+// SIGHUP, that restart the application in-process once it has been started by
+// Watcher.Start(), e.g. to reload its configuration: the closers of the started
+// hooks are called in the reverse order, within the timeout, and the start
+// hooks are then run again, with a context that is cancelled when the closers
+// are notified.  If the restart fails, the watcher is closed and the error that
+// Wait() returns reports why.  The signals must not be watched.
+func WithRestartSignals(signals ...os.Signal) Option {
+	return withRestartSignals{signals: signals}
+}
+
+type withRestartSignals struct{ signals []os.Signal }
+
+func (w withRestartSignals) Apply(o *Settings) {
+	o.RestartSignals = w.signals
+}
+
+// Start runs the start hooks, one at a time in the order they were specified,
+// with the context; the closers of the started hooks are then called in the
+// reverse order when the closers are notified, so that the shutdown order
+// mirrors the startup order.  If a hook fails, the closers of the hooks
+// already started are called in the reverse order, within the timeout, and the
+// error of the hook is returned, and the instance can be started again.
+// ErrStarted is returned if the instance was already started, and ErrShutdown
+// if the closers are being, or have been, notified.
+func (w *watcher) Start(ctx context.Context) error {
+	w.mu.Lock()
+	shutdown, started := w.shutdown, w.components != nil
+	if !shutdown && !started {
+		w.components = &startedCloser{}
+	}
+	components := w.components
+	w.mu.Unlock()
+
+	switch {
+	case started:
+		return ErrStarted
+	case shutdown:
+		return ErrShutdown
+	}
+
+	components.mu.Lock()
+	err := w.startAll(ctx, components)
+	components.mu.Unlock()
+
+	if err != nil {
+		w.mu.Lock()
+		w.components = nil
+		w.mu.Unlock()
+
+		return err
+	}
+
+	if err := w.AddCloser(components); err != nil {
+		return JoinErrors(err, w.stopStarted(components))
+	}
+
+	return nil
+}
+
+// startAll runs the start hooks, and stops the started ones if a hook fails;
+// the lock of the components must be held.
+func (w *watcher) startAll(ctx context.Context, components *startedCloser) error {
+	for _, starter := range w.starters {
+		if err := starter.Start(ctx); err != nil {
+			return JoinErrors(fmt.Errorf("start %v: %w", starter.Name, err), w.stopStarted(components))
+		}
+
+		components.add(starter)
+	}
+
+	return nil
+}
+
+// stopStarted calls the closers of the started hooks, within the timeout; the
+// lock of the components must be held.
+func (w *watcher) stopStarted(components *startedCloser) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	return components.stop(ctx)
+}
+
+// watchRestarts restarts the started hooks when the signals occur, until the
+// instance is closed or stopped.
+func (w *watcher) watchRestarts(c chan os.Signal) {
+	defer w.source.Stop(c)
+
+	for {
+		select {
+		case sig := <-c:
+			w.countSignal(sig)
+
+			if err := w.restart(); err != nil {
+				_ = w.closeWithCause(fmt.Errorf("restart: %w", err))
+				return
+			}
+		case <-w.ctx.Done():
+			return
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// restart stops the started hooks and runs the start hooks again; restarts
+// of instances that were not started are ignored.
+func (w *watcher) restart() error {
+	w.mu.Lock()
+	components := w.components
+	w.mu.Unlock()
+
+	if components == nil {
+		return nil
+	}
+
+	// the closers wait for the restart to complete
+	components.mu.Lock()
+	defer components.mu.Unlock()
+
+	if err := w.stopStarted(components); err != nil {
+		return err
+	}
+
+	if w.ctx.Err() != nil {
+		return nil
+	}
+
+	return w.startAll(w.ctx, components)
+}
+
+// startedCloser calls the closers of the started hooks in the reverse order.
+type startedCloser struct {
+	mu    sync.Mutex
+	names []string
+	stops []io.Closer
+}
+
+func (c *startedCloser) add(starter Starter) {
+	c.names = append(c.names, starter.Name)
+
+	if starter.Stop != nil {
+		c.stops = append(c.stops, starter.Stop)
+	}
+}
+
+func (c *startedCloser) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+func (c *startedCloser) CloseContext(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stop(ctx)
+}
+
+// stop calls the closers of the started hooks and forgets them; the lock must
+// be held.
+func (c *startedCloser) stop(ctx context.Context) error {
+	stops := c.stops
+	c.names, c.stops = nil, nil
+
+	var errs []error
+
+	for i := len(stops) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			return JoinErrors(append(errs, ctx.Err())...)
+		}
+
+		errs = append(errs, closeWithContext(ctx, stops[i]))
+	}
+
+	return JoinErrors(errs...)
+}
+
+func (c *startedCloser) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return "starters " + strings.Join(c.names, ", ")
+}
@@ -0,0 +1,111 @@
+//go:build !js
+// +build !js
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestRestartSignals(t *testing.T) {
+
+	var mu sync.Mutex
+	var calls []string
+
+	record := func(name string) {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+	}
+
+	starter := func(name string, err error) yama.Option {
+		return yama.WithStarter(name,
+			func(context.Context) error {
+				record("start " + name)
+				return err
+			},
+			yama.FnAsCloser(func() { record("stop " + name) }))
+	}
+
+	Convey("Ensure restart signals stop and start the components again", t, func() {
+		calls = nil
+		signals := yamatest.NewSignals()
+		started := make(chan struct{}, 2)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithSignalSource(signals),
+			yama.WithRestartSignals(syscall.SIGHUP),
+			starter("db", nil),
+			yama.WithStarter("server", func(context.Context) error {
+				record("start server")
+				started <- struct{}{}
+				return nil
+			}, nil))
+		So(err, ShouldBeNil)
+
+		So(watcher.Start(context.Background()), ShouldBeNil)
+		<-started
+
+		So(signals.Send(syscall.SIGHUP), ShouldBeTrue)
+		<-started
+
+		So(watcher.Close(), ShouldBeNil)
+		So(calls, ShouldResemble, []string{
+			"start db", "start server", "stop db", "start db", "start server", "stop db"})
+	})
+
+	Convey("Ensure failed restarts close the watcher", t, func() {
+		signals := yamatest.NewSignals()
+		restarts := 0
+
+		watcher, err := yama.NewWatcher(
+			yama.WithSignalSource(signals),
+			yama.WithRestartSignals(syscall.SIGHUP),
+			yama.WithStarter("db", func(context.Context) error {
+				if restarts++; restarts > 1 {
+					return io.EOF
+				}
+				return nil
+			}, nil))
+		So(err, ShouldBeNil)
+
+		So(watcher.Start(context.Background()), ShouldBeNil)
+		So(signals.Send(syscall.SIGHUP), ShouldBeTrue)
+
+		err = watcher.Wait()
+		So(errors.Is(err, io.EOF), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "restart: start db: EOF")
+	})
+
+	Convey("Ensure restart signals are not watched", t, func() {
+		_, err := yama.NewWatcher(yama.WatchingSignals(syscall.SIGHUP), yama.WithRestartSignals(syscall.SIGHUP))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "restart signal hangup must not be watched")
+	})
+}
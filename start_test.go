@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestStart(t *testing.T) {
+
+	var mu sync.Mutex
+	var calls []string
+
+	record := func(name string) {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+	}
+
+	starter := func(name string, err error) yama.Option {
+		return yama.WithStarter(name,
+			func(context.Context) error {
+				record("start " + name)
+				return err
+			},
+			yama.FnAsCloser(func() { record("stop " + name) }))
+	}
+
+	Convey("Ensure components are stopped in the reverse order they were started", t, func() {
+		calls = nil
+
+		watcher, err := yama.NewWatcher(starter("db", nil), starter("cache", nil), starter("server", nil))
+		So(err, ShouldBeNil)
+
+		So(watcher.Start(context.Background()), ShouldBeNil)
+		So(watcher.Start(context.Background()), ShouldEqual, yama.ErrStarted)
+		So(watcher.Close(), ShouldBeNil)
+		So(calls, ShouldResemble, []string{
+			"start db", "start cache", "start server", "stop server", "stop cache", "stop db"})
+	})
+
+	Convey("Ensure a failed start stops the components already started", t, func() {
+		calls = nil
+
+		watcher, err := yama.NewWatcher(starter("db", nil), starter("cache", io.EOF), starter("server", nil))
+		So(err, ShouldBeNil)
+
+		err = watcher.Start(context.Background())
+		So(errors.Is(err, io.EOF), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "start cache: EOF")
+		So(calls, ShouldResemble, []string{"start db", "start cache", "stop db"})
+	})
+
+	Convey("Ensure watchers that were shut down cannot be started", t, func() {
+		watcher, err := yama.NewWatcher(starter("db", nil))
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+
+		So(watcher.Start(context.Background()), ShouldEqual, yama.ErrShutdown)
+	})
+
+	Convey("Ensure starters have a start function", t, func() {
+		_, err := yama.NewWatcher(yama.WithStarter("db", nil, nil))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, `starter #0 "db" must have a start function`)
+	})
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"time"
+)
+
+// SignalStat is the number of times a signal was received by a watcher, and
+// when it was last received.
+type SignalStat struct {
+	Signal os.Signal
+	Count  int
+	Last   time.Time
+}
+
+func newSignalStats(signalSets ...[]os.Signal) []SignalStat {
+	var stats []SignalStat
+
+	for _, signals := range signalSets {
+		for _, sig := range signals {
+			stats = append(stats, SignalStat{Signal: sig})
+		}
+	}
+
+	return stats
+}
+
+// countSignal records that the signal was received.
+func (w *watcher) countSignal(sig os.Signal) {
+	now := w.clock.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range w.signalStats {
+		if w.signalStats[i].Signal == sig {
+			w.signalStats[i].Count++
+			w.signalStats[i].Last = now
+
+			return
+		}
+	}
+}
+
+// countSignals counts the signals specified by CountingSignals(), until the
+// instance is stopped.
+func (w *watcher) countSignals(c chan os.Signal) {
+	defer w.source.Stop(c)
+
+	for {
+		select {
+		case sig := <-c:
+			w.countSignal(sig)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// SignalStats returns, for each watched, restart, and counted signal, the
+// number of times it was received, and when it was last received, whether it
+// triggered the shutdown or not: signals that were not confirmed, repeated,
+// or coalesced are counted too.
+func (w *watcher) SignalStats() []SignalStat {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]SignalStat(nil), w.signalStats...)
+}
@@ -0,0 +1,79 @@
+//go:build !js && !plan9
+// +build !js,!plan9
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestSignalStats(t *testing.T) {
+
+	Convey("Ensure received signals are counted, whether they triggered the shutdown or not", t, func() {
+		signals := yamatest.NewSignals()
+		clock := yamatest.NewClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM, syscall.SIGINT),
+			yama.CountingSignals(syscall.SIGHUP),
+			yama.WithSignalSource(signals),
+			yama.WithClock(clock))
+		So(err, ShouldBeNil)
+
+		count := func(sig syscall.Signal) int {
+			for _, stat := range watcher.SignalStats() {
+				if stat.Signal == sig {
+					return stat.Count
+				}
+			}
+			return -1
+		}
+
+		for i := 1; i <= 2; i++ {
+			clock.Advance(time.Minute)
+			So(signals.Send(syscall.SIGHUP), ShouldBeTrue)
+
+			for count(syscall.SIGHUP) < i {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+
+		So(watcher.SignalStats(), ShouldResemble, []yama.SignalStat{
+			{Signal: syscall.SIGTERM, Count: 1, Last: time.Date(2021, time.January, 1, 0, 2, 0, 0, time.UTC)},
+			{Signal: syscall.SIGINT},
+			{Signal: syscall.SIGHUP, Count: 2, Last: time.Date(2021, time.January, 1, 0, 2, 0, 0, time.UTC)},
+		})
+	})
+
+	Convey("Ensure counted signals are not watched", t, func() {
+		_, err := yama.NewWatcher(yama.WatchingSignals(syscall.SIGHUP), yama.CountingSignals(syscall.SIGHUP))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "counted signal hangup must not be watched")
+	})
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// closerStatus is the status of a closer being notified; closers that were
+// not called yet have a zero start time.
+type closerStatus struct {
+	start, end time.Time
+	err        error
+}
+
+// trackRunning records that the closer with the index in the notified closers
+// was called.
+func (w *watcher) trackRunning(i int) {
+	if i < 0 {
+		return
+	}
+
+	w.mu.Lock()
+	w.statuses[i].start = w.clock.Now()
+	w.mu.Unlock()
+}
+
+// trackDone records that the closer with the index returned.
+func (w *watcher) trackDone(i int, err error) {
+	if i < 0 {
+		return
+	}
+
+	w.mu.Lock()
+	w.statuses[i].end = w.clock.Now()
+	w.statuses[i].err = err
+	w.mu.Unlock()
+}
+
+// Status is the live status of a watcher, as rendered by StatusHandler().
+type Status struct {
+	State     string         `json:"state"`
+	Triggered bool           `json:"triggered"`
+	Reason    string         `json:"reason,omitempty"`
+	Signal    string         `json:"signal,omitempty"`
+	Elapsed   string         `json:"elapsed,omitempty"`
+	Remaining string         `json:"remaining,omitempty"`
+	Coalesced int            `json:"coalesced,omitempty"`
+	Closers   []CloserStatus `json:"closers"`
+}
+
+// CloserStatus is the live status of a closer: pending, running, done, or
+// failed, with how long it ran, or has been running.
+type CloserStatus struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Status returns the live status of the instance and of its closers, in
+// registration order.
+func (w *watcher) Status() Status {
+	status := Status{State: w.state(), Triggered: w.ctx.Err() != nil, Coalesced: w.Coalesced()}
+
+	w.mu.Lock()
+	closers := append([]io.Closer(nil), w.closers...)
+	statuses := append([]closerStatus(nil), w.statuses...)
+	w.mu.Unlock()
+
+	now := w.clock.Now()
+
+	if status.Triggered {
+		reason, sig := w.Reason()
+
+		switch reason {
+		case SignalReceived:
+			status.Reason = "signal"
+			status.Signal = signalName(sig)
+		case Rehearsal:
+			status.Reason = "rehearsal"
+		default:
+			status.Reason = "closed"
+		}
+
+		w.mu.Lock()
+		started, ended := w.started, w.ended
+		w.mu.Unlock()
+
+		switch {
+		case !ended.IsZero():
+			status.Elapsed = ended.Sub(started).String()
+		case !started.IsZero():
+			status.Elapsed = now.Sub(started).String()
+			status.Remaining = w.Remaining().String()
+		}
+	}
+
+	status.Closers = make([]CloserStatus, len(closers))
+
+	for i, closer := range closers {
+		cs := CloserStatus{Name: closerName(closer), State: "pending"}
+
+		if i < len(statuses) && !statuses[i].start.IsZero() {
+			st := statuses[i]
+
+			switch {
+			case st.end.IsZero():
+				cs.State = "running"
+				cs.Duration = now.Sub(st.start).String()
+			case st.err != nil:
+				cs.State = "failed"
+				cs.Duration = st.end.Sub(st.start).String()
+				cs.Error = st.err.Error()
+			default:
+				cs.State = "done"
+				cs.Duration = st.end.Sub(st.start).String()
+			}
+		}
+
+		status.Closers[i] = cs
+	}
+
+	return status
+}
+
+// StatusHandler returns a handler that renders the live status of the
+// instance as JSON, e.g. for an admin endpoint, so that the closers that a
+// draining process is stuck on can be found.
+func (w *watcher) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(w.Status())
+	})
+}
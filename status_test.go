@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestStatus(t *testing.T) {
+
+	states := func(status yama.Status) []string {
+		var s []string
+		for _, c := range status.Closers {
+			s = append(s, c.Name+": "+c.State)
+		}
+		return s
+	}
+
+	Convey("Ensure the status tells which closers the shutdown is stuck on", t, func() {
+		running, release := make(chan struct{}), make(chan struct{})
+
+		watcher, err := yama.NewWatcher(
+			yama.WithConcurrency(1),
+			yama.WithClosers(
+				yamatest.CloserSpy("db"),
+				yamatest.CloserSpy("cache").WithError(io.EOF),
+				yama.FnAsCloser(func() {
+					close(running)
+					<-release
+				}),
+				yamatest.CloserSpy("queue")))
+		So(err, ShouldBeNil)
+
+		status := watcher.Status()
+		So(status.State, ShouldEqual, "watching")
+		So(status.Triggered, ShouldBeFalse)
+		So(states(status), ShouldResemble, []string{
+			"db: pending", "cache: pending", "*yama.fnWrapper: pending", "queue: pending"})
+
+		go func() { _ = watcher.Close() }()
+		<-running
+
+		rec := httptest.NewRecorder()
+		watcher.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		So(rec.Header().Get("Content-Type"), ShouldEqual, "application/json")
+
+		status = yama.Status{}
+		So(json.NewDecoder(rec.Body).Decode(&status), ShouldBeNil)
+		So(status.State, ShouldEqual, "shutting down")
+		So(status.Triggered, ShouldBeTrue)
+		So(status.Reason, ShouldEqual, "closed")
+		So(status.Elapsed, ShouldNotBeEmpty)
+		So(states(status), ShouldResemble, []string{
+			"db: done", "cache: failed", "*yama.fnWrapper: running", "queue: pending"})
+		So(status.Closers[1].Error, ShouldEqual, "EOF")
+
+		close(release)
+		So(watcher.Wait(), ShouldBeNil)
+
+		So(states(watcher.Status()), ShouldResemble, []string{
+			"db: done", "cache: failed", "*yama.fnWrapper: done", "queue: done"})
+	})
+	Convey("Ensure closers that cannot be compared, and inline closers, are tracked", t, func() {
+		watcher, err := yama.NewWatcher(
+			yama.WithClosers(
+				uncomparableCloser{},
+				yama.InlineFnAsCloser(func() {}),
+				yamatest.CloserSpy("db").WithError(io.EOF)))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(states(watcher.Status()), ShouldResemble, []string{
+			"yama_test.uncomparableCloser: done", "*yama.inlineFnWrapper: done", "db: failed"})
+	})
+}
+
+// uncomparableCloser is a closer whose type cannot be compared.
+type uncomparableCloser []int
+
+func (uncomparableCloser) Close() error { return nil }
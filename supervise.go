@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync/atomic"
+)
+
+// Supervise starts the command and returns a watcher, created with the
+// options, that supervises the child process: the watched signals are
+// forwarded to the child, the watcher is closed if the child exits by itself,
+// and, when the closers are notified, the watcher waits for the child to exit.
+// A child that was not sent one of the watched signals is asked to terminate
+// first, and a child that has not exited by the end of the timeout is killed.
+func Supervise(cmd *exec.Cmd, options ...Option) (*Watcher, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &child{cmd: cmd, exited: make(chan struct{})}
+
+	options = append(options[:len(options):len(options)], forwardingSignals(c.signal))
+
+	w, err := NewWatcher(options...)
+	if err == nil {
+		err = w.AddCloser(c)
+	}
+
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return nil, err
+	}
+
+	go func() {
+		c.err = cmd.Wait()
+		close(c.exited)
+
+		_ = w.Close()
+	}()
+
+	return w, nil
+}
+
+// child is the closer of a supervised child process.
+type child struct {
+	cmd       *exec.Cmd
+	signalled uint32
+	exited    chan struct{}
+	err       error
+}
+
+// signal forwards the signal to the child process.
+func (c *child) signal(sig os.Signal) {
+	atomic.StoreUint32(&c.signalled, 1)
+	_ = c.cmd.Process.Signal(sig)
+}
+
+func (c *child) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+func (c *child) CloseContext(ctx context.Context) error {
+	select {
+	case <-c.exited:
+		return c.err
+	default:
+	}
+
+	if atomic.LoadUint32(&c.signalled) == 0 {
+		_ = terminate(c.cmd.Process)
+	}
+
+	select {
+	case <-c.exited:
+		return c.err
+	case <-ctx.Done():
+		_ = c.cmd.Process.Kill()
+
+		return ctx.Err()
+	}
+}
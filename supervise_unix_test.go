@@ -0,0 +1,86 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package yama_test
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestSupervise(t *testing.T) {
+
+	Convey("Ensure the child is terminated when the watcher is closed", t, func() {
+		cmd := exec.Command("sleep", "60")
+		watcher, err := yama.Supervise(cmd)
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(cmd.ProcessState, ShouldNotBeNil)
+		So(cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(), ShouldEqual, syscall.SIGTERM)
+	})
+
+	Convey("Ensure the watcher is closed when the child exits", t, func() {
+		closers := yamatest.NewClosers()
+		watcher, err := yama.Supervise(exec.Command("true"), yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		So(watcher.Wait(), ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+
+	Convey("Ensure watched signals are forwarded to the child", t, func() {
+		signals := yamatest.NewSignals()
+		cmd := exec.Command("sleep", "60")
+		watcher, err := yama.Supervise(cmd,
+			yama.WatchingSignals(syscall.SIGHUP),
+			yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGHUP), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+		So(cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(), ShouldEqual, syscall.SIGHUP)
+	})
+
+	Convey("Ensure the child is killed at the end of the timeout", t, func() {
+		cmd := exec.Command("sh", "-c", "trap '' TERM; echo ready; exec sleep 60")
+		stdout, err := cmd.StdoutPipe()
+		So(err, ShouldBeNil)
+
+		watcher, err := yama.Supervise(cmd, yama.WithTimeout(100*time.Millisecond))
+		So(err, ShouldBeNil)
+
+		_, err = stdout.Read(make([]byte, 6)) // wait until the trap is set
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+	})
+
+	Convey("Ensure commands that cannot start are reported", t, func() {
+		_, err := yama.Supervise(exec.Command("/does/not/exist"))
+		So(err, ShouldBeError)
+	})
+}
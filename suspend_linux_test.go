@@ -0,0 +1,96 @@
+//go:build linux
+// +build linux
+
+package yama_test
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama/yamatest"
+)
+
+func TestSuspendHooks(t *testing.T) {
+
+	Convey("Ensure the suspend hooks are called around job control stops", t, func() {
+		const source = `
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGTERM),
+		yama.WithSuspendHooks(func() { fmt.Println("suspending") }, func() { fmt.Println("resumed") }))
+
+	fmt.Println("ready", os.Getpid())
+	_ = watcher.Wait()
+}
+`
+		p := yamatest.StartSubprocess(t, source)
+		So(p.WaitForOutput("ready", 5*time.Second), ShouldBeTrue)
+
+		var pid int
+		_, err := fmt.Sscanf(p.Output(), "ready %d", &pid)
+		So(err, ShouldBeNil)
+
+		So(p.Signal(syscall.SIGTSTP), ShouldBeNil)
+		So(p.WaitForOutput("suspending", 5*time.Second), ShouldBeTrue)
+
+		// the process stops itself once the hook returned
+		deadline := time.Now().Add(5 * time.Second)
+		for !stopped(pid) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(stopped(pid), ShouldBeTrue)
+
+		So(p.Signal(syscall.SIGCONT), ShouldBeNil)
+		So(p.WaitForOutput("resumed", 5*time.Second), ShouldBeTrue)
+
+		So(p.Terminate(), ShouldBeNil)
+		So(p.Wait(), ShouldBeNil)
+		So(strings.Count(p.Output(), "\n"), ShouldEqual, 3)
+	})
+}
+
+// stopped reports whether the process is stopped, according to its status in
+// /proc.
+func stopped(pid int) bool {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+
+	// the state follows the command, which is in parentheses
+	fields := strings.Fields(string(b[strings.LastIndexByte(string(b), ')')+1:]))
+
+	return len(fields) > 0 && fields[0] == "T"
+}
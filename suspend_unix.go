@@ -0,0 +1,60 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"os"
+	"syscall"
+)
+
+const suspendSupported = true
+
+// watchSuspend calls the suspend hooks on SIGTSTP and SIGCONT, until the
+// instance is stopped; the process is stopped with SIGSTOP, which cannot be
+// caught, once onStop returns.
+func (w *watcher) watchSuspend(onStop, onCont func()) {
+	c := make(chan os.Signal, 1)
+	w.source.Notify(c, syscall.SIGTSTP, syscall.SIGCONT)
+
+	go func() {
+		defer w.source.Stop(c)
+
+		for {
+			select {
+			case sig := <-c:
+				if sig != syscall.SIGTSTP {
+					if onCont != nil {
+						onCont()
+					}
+
+					continue
+				}
+
+				if onStop != nil {
+					onStop()
+				}
+
+				_ = syscall.Kill(os.Getpid(), syscall.SIGSTOP)
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
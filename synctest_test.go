@@ -0,0 +1,69 @@
+//go:build go1.25
+// +build go1.25
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"syscall"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestSynctest(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		slow := yama.FnAsCloser(func() { <-release })
+
+		signals := yamatest.NewSignals()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithTimeout(10*time.Second),
+			yama.WithClosers(slow))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		signals.Send(syscall.SIGTERM)
+
+		// closing while the closers are being notified must not prevent the
+		// bubble from advancing its clock
+		closed := make(chan error)
+		go func() { closed <- watcher.Close() }()
+
+		if _, ok := watcher.Wait().(*yama.ErrTimedOut); !ok {
+			t.Errorf("expected the watcher to time out")
+		}
+
+		if _, ok := (<-closed).(*yama.ErrTimedOut); !ok {
+			t.Errorf("expected close to return the time out")
+		}
+
+		if elapsed := time.Since(start); elapsed != 10*time.Second {
+			t.Errorf("expected the timeout to elapse in the bubble, but %v elapsed", elapsed)
+		}
+
+		close(release)
+	})
+}
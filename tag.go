@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// Tagged returns a closer that closes the closer, tagged with the tag, so
+// that CloseTagged(), and the signals specified by ClosingTagsOn(), close it,
+// with the other closers that have the tag, without shutting down.  Tagged
+// closers can be tagged again to have more tags; they are still inline, or
+// honor the deadline of their context, if the closer does.
+func Tagged(tag string, closer io.Closer) io.Closer {
+	t := &taggedCloser{Closer: closer, tags: []string{tag}}
+
+	switch c := closer.(type) {
+	case *taggedCloser:
+		t = &taggedCloser{Closer: c.Closer, tags: append(append([]string(nil), c.tags...), tag)}
+	case inlineTaggedCloser:
+		t = &taggedCloser{Closer: c.Closer, tags: append(append([]string(nil), c.tags...), tag)}
+	}
+
+	if _, ok := t.Closer.(InlineCloser); ok {
+		return inlineTaggedCloser{t}
+	}
+
+	return t
+}
+
+type taggedCloser struct {
+	io.Closer
+	tags []string
+}
+
+func (t *taggedCloser) CloseContext(ctx context.Context) error {
+	return closeWithContext(ctx, t.Closer)
+}
+
+func (t *taggedCloser) Name() string {
+	return closerName(t.Closer)
+}
+
+func (t *taggedCloser) hasTag(tags []string) bool {
+	for _, tag := range t.tags {
+		for _, want := range tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type inlineTaggedCloser struct{ *taggedCloser }
+
+func (inlineTaggedCloser) Inline() {}
+
+func hasTag(closer io.Closer, tags []string) bool {
+	switch c := closer.(type) {
+	case *taggedCloser:
+		return c.hasTag(tags)
+	case inlineTaggedCloser:
+		return c.hasTag(tags)
+	case *weightedCloser:
+		return hasTag(c.Closer, tags)
+	default:
+		return false
+	}
+}
+
+// TagSignal is a signal that closes the closers with any of the tags, as
+// specified by ClosingTagsOn().
+type TagSignal struct {
+	Signal os.Signal
+	Tags   []string
+}
+
+// ClosingTagsOn returns an Option that specifies an OS signal that closes the
+// registered closers with any of the tags, as CloseTagged() does, e.g. SIGUSR1
+// to shed the frontends while SIGTERM closes everything.  The signal must not
+// be watched.
+func ClosingTagsOn(sig os.Signal, tags ...string) Option {
+	return closingTagsOn{TagSignal{Signal: sig, Tags: tags}}
+}
+
+type closingTagsOn struct{ signal TagSignal }
+
+func (c closingTagsOn) Apply(o *Settings) {
+	o.TagSignals = append(o.TagSignals, c.signal)
+}
+
+// tagSignals returns the signals of the tag signals, once each.
+func tagSignals(tagSignals []TagSignal) []os.Signal {
+	var signals []os.Signal
+
+	for _, ts := range tagSignals {
+		if !watches(signals, ts.Signal) {
+			signals = append(signals, ts.Signal)
+		}
+	}
+
+	return signals
+}
+
+// CloseTagged closes the registered closers that have any of the tags now,
+// concurrently and within the timeout, and unregisters them, without shutting
+// down, e.g. to soft drain the frontends of a process; the shutdown waits for
+// them before notifying the emergency closers.  ErrShutdown is returned if the
+// closers are being, or have been, notified, and otherwise an ErrTimedOut with
+// the tagged closers that didn't complete within the timeout, joined with the
+// errors of the closers.
+func (w *watcher) CloseTagged(tags ...string) error {
+	if len(tags) == 0 {
+		return errors.New("tags must not be empty")
+	}
+
+	w.mu.Lock()
+	if w.shutdown {
+		w.mu.Unlock()
+		return ErrShutdown
+	}
+
+	var tagged, others []io.Closer
+
+	for _, closer := range w.closers {
+		if hasTag(closer, tags) {
+			tagged = append(tagged, closer)
+		} else {
+			others = append(others, closer)
+		}
+	}
+
+	w.closers = others
+	w.drains.Add(1)
+	w.mu.Unlock()
+
+	defer w.drains.Done()
+
+	return w.closeTagged(tagged)
+}
+
+// closeTagged calls the closers concurrently and waits for them, at most for
+// the timeout.
+func (w *watcher) closeTagged(closers []io.Closer) error {
+	if len(closers) == 0 {
+		return nil
+	}
+
+	ctx := newDeadlineContext(w.clock.Now().Add(w.timeout))
+	defer ctx.cancel()
+
+	type result struct {
+		i   int
+		err error
+	}
+
+	results := make(chan result, len(closers))
+
+	for i, closer := range closers {
+		go func(i int, closer io.Closer) {
+			results <- result{i: i, err: w.closeNotified(ctx, -1, closer)}
+		}(i, closer)
+	}
+
+	timer := w.clock.NewTimer(w.timeout)
+	defer timer.Stop()
+
+	closed := make([]bool, len(closers))
+	var errs []error
+
+	for remaining := len(closers); remaining > 0; remaining-- {
+		select {
+		case r := <-results:
+			closed[r.i] = true
+
+			if r.err != nil {
+				errs = append(errs, r.err)
+			}
+		case <-timer.C():
+			ctx.expire()
+
+			var uncompleted []io.Closer
+			for i, closer := range closers {
+				if !closed[i] {
+					uncompleted = append(uncompleted, closer)
+				}
+			}
+
+			return JoinErrors(append([]error{&ErrTimedOut{Uncompleted: uncompleted}}, errs...)...)
+		}
+	}
+
+	return JoinErrors(errs...)
+}
+
+// watchTagSignals closes the closers with the tags of the signals that occur,
+// until the closers are notified.
+func (w *watcher) watchTagSignals(c chan os.Signal, signals []TagSignal) {
+	defer w.source.Stop(c)
+
+	for {
+		select {
+		case sig := <-c:
+			w.countSignal(sig)
+
+			for _, s := range signals {
+				if s.Signal == sig {
+					_ = w.CloseTagged(s.Tags...)
+				}
+			}
+		case <-w.ctx.Done():
+			return
+		case <-w.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+//go:build !js
+// +build !js
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestTagSignals(t *testing.T) {
+
+	Convey("Ensure tag signals close the closers with their tags", t, func() {
+		signals := yamatest.NewSignals()
+		frontend, backend := yamatest.CloserSpy("frontend"), yamatest.CloserSpy("backend")
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.ClosingTagsOn(syscall.SIGHUP, "frontend"),
+			yama.WithSignalSource(signals),
+			yama.WithClosers(yama.Tagged("frontend", frontend), backend))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGHUP), ShouldBeTrue)
+
+		for frontend.Calls() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+		So(frontend.Calls(), ShouldEqual, 1)
+		So(backend.Calls(), ShouldEqual, 1)
+	})
+}
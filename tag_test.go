@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestTagged(t *testing.T) {
+
+	Convey("Ensure only the closers with the tags are closed", t, func() {
+		frontend, backend := yamatest.CloserSpy("frontend"), yamatest.CloserSpy("backend")
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(
+			yama.Tagged("frontend", frontend),
+			yama.Tagged("stateful", backend)))
+		So(err, ShouldBeNil)
+
+		So(watcher.CloseTagged("frontend", "edge"), ShouldBeNil)
+		So(frontend.Calls(), ShouldEqual, 1)
+		So(backend.Calls(), ShouldEqual, 0)
+
+		So(watcher.Status().Closers, ShouldResemble, []yama.CloserStatus{{Name: "backend", State: "pending"}})
+
+		So(watcher.CloseTagged("frontend"), ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+		So(frontend.Calls(), ShouldEqual, 1)
+		So(backend.Calls(), ShouldEqual, 1)
+
+		So(watcher.CloseTagged("stateful"), ShouldEqual, yama.ErrShutdown)
+	})
+
+	Convey("Ensure closers can have several tags and stay inline", t, func() {
+		closed := 0
+		closer := yama.Tagged("a", yama.Tagged("b", yama.InlineFnAsCloser(func() { closed++ })))
+
+		_, inline := closer.(yama.InlineCloser)
+		So(inline, ShouldBeTrue)
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(closer))
+		So(err, ShouldBeNil)
+
+		So(watcher.CloseTagged("b"), ShouldBeNil)
+		So(closed, ShouldEqual, 1)
+	})
+
+	Convey("Ensure the errors of tagged closers are reported", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		blocked := yama.Tagged("web", yama.FnAsCloser(func() { <-release }))
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithClosers(blocked, yama.Tagged("web", yamatest.CloserSpy("api").WithError(io.EOF))))
+		So(err, ShouldBeNil)
+
+		err = watcher.CloseTagged("web")
+
+		var timedOut *yama.ErrTimedOut
+		So(errors.As(err, &timedOut), ShouldBeTrue)
+		So(timedOut.Uncompleted, ShouldResemble, []io.Closer{blocked})
+		So(errors.Is(err, io.EOF), ShouldBeTrue)
+	})
+
+	Convey("Ensure tag signals are validated", t, func() {
+		_, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.ClosingTagsOn(syscall.SIGTERM))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "tag signal terminated must not be watched")
+		So(err.Error(), ShouldContainSubstring, "tag signal terminated must have tags")
+	})
+
+	Convey("Ensure the admin API drains the closers with the tags", t, func() {
+		frontend := yamatest.CloserSpy("frontend")
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(yama.Tagged("frontend", frontend)))
+		So(err, ShouldBeNil)
+
+		h := watcher.AdminHandler(yama.AllowingAllAdmin, nil)
+
+		serve := func(target string) int {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+			return rec.Code
+		}
+
+		So(serve("/drain"), ShouldEqual, http.StatusBadRequest)
+		So(serve("/drain?tag=frontend"), ShouldEqual, http.StatusNoContent)
+		So(frontend.Calls(), ShouldEqual, 1)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(serve("/drain?tag=frontend"), ShouldEqual, http.StatusConflict)
+	})
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// TempDir creates a temporary directory, like ioutil.TempDir() in the default
+// directory for temporary files, and registers it with RegisterTempPath().
+func (w *watcher) TempDir(pattern string) (string, error) {
+	dir, err := ioutil.TempDir("", pattern)
+	if err != nil {
+		return "", err
+	}
+
+	if err := w.RegisterTempPath(dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// RegisterTempPath registers a temporary file or directory that is removed,
+// with its contents, once the closers and the emergency closers have been
+// notified, even if the timeout fired; paths are removed in the reverse order
+// of their registration, and the paths that cannot be removed are reported by
+// the error that Wait() returns.  ErrShutdown is returned if the closers are
+// being, or have been, notified.
+func (w *watcher) RegisterTempPath(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.shutdown {
+		return ErrShutdown
+	}
+
+	w.tempPaths = append(w.tempPaths, path)
+
+	return nil
+}
+
+// removeTempPaths removes the registered temporary paths.
+func (w *watcher) removeTempPaths() {
+	w.mu.Lock()
+	paths := w.tempPaths
+	w.mu.Unlock()
+
+	errs := []error{w.err}
+
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := os.RemoveAll(paths[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	w.err = JoinErrors(errs...)
+}
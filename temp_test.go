@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+)
+
+func TestTempPaths(t *testing.T) {
+
+	Convey("Ensure temporary paths are removed once the closers are notified", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		dir, err := watcher.TempDir("yama")
+		So(err, ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "scratch"), []byte("data"), 0o600), ShouldBeNil)
+
+		f, err := ioutil.TempFile("", "yama")
+		So(err, ShouldBeNil)
+		So(f.Close(), ShouldBeNil)
+		So(watcher.RegisterTempPath(f.Name()), ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+
+		_, err = os.Stat(dir)
+		So(os.IsNotExist(err), ShouldBeTrue)
+		_, err = os.Stat(f.Name())
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		So(watcher.RegisterTempPath(dir), ShouldEqual, yama.ErrShutdown)
+		_, err = watcher.TempDir("yama")
+		So(err, ShouldEqual, yama.ErrShutdown)
+	})
+}
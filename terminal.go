@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"io"
+	"os"
+)
+
+// TerminalCloser captures the state of the terminal, e.g. os.Stdin, and
+// returns a closer that restores it, e.g. for programs that put the terminal
+// in raw mode; an error is returned if the file is not a terminal.  The closer
+// should be registered with WithEmergencyClosers() so that the terminal is
+// restored even when the timeout fires.
+func TerminalCloser(f *os.File) (io.Closer, error) {
+	state, err := getTerminalState(f.Fd())
+	if err != nil {
+		return nil, err
+	}
+
+	return &terminalCloser{f: f, state: state}, nil
+}
+
+type terminalCloser struct {
+	f     *os.File
+	state terminalState
+}
+
+func (t *terminalCloser) Close() error {
+	return setTerminalState(t.f.Fd(), t.state)
+}
+
+func (t *terminalCloser) String() string {
+	return "terminal " + t.f.Name()
+}
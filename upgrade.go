@@ -0,0 +1,337 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// upgradeEnv is the environment variable that tells a process that it was
+// started by an upgrade; the readiness pipe is its first inherited file, the
+// handoff socket its second, and the listeners, listed by listenersEnv, are the
+// following ones.
+const (
+	upgradeEnv   = "YAMA_UPGRADE"
+	listenersEnv = "YAMA_UPGRADE_LISTENERS"
+)
+
+// ErrUpgrading is returned when upgrading a process that is already being
+// upgraded.
+var ErrUpgrading = errors.New("upgrade in progress")
+
+// Upgrader upgrades a process without downtime: the new binary is started,
+// and once it reports it is ready, the watcher of the old process is closed so
+// that it drains through its closers.
+//
+// Upgrades rely on inheriting files, which is not supported on Windows.
+type Upgrader struct {
+	w       *Watcher
+	parent  *os.File
+	handoff *os.File
+
+	mu        sync.Mutex
+	upgrading bool
+	inherited map[string]*os.File
+	listeners []listener
+	states    []state
+}
+
+// listener is a listener registered with an Upgrader.
+type listener struct {
+	key string
+	ln  net.Listener
+}
+
+// NewUpgrader creates an Upgrader that upgrades the process when one of the
+// signals occurs, e.g. SIGUSR2, until the watcher's closers are notified.  The
+// signals must not be watched by the watcher.  Upgrades triggered by a signal
+// are bounded by the watcher's timeout; if they fail, the process keeps
+// running.
+func NewUpgrader(w *Watcher, signals ...os.Signal) *Upgrader {
+	u := &Upgrader{w: w}
+
+	if os.Getenv(upgradeEnv) != "" {
+		u.parent = os.NewFile(3, "parent")
+		u.handoff = os.NewFile(4, "handoff")
+		u.inherited = make(map[string]*os.File)
+
+		if keys := os.Getenv(listenersEnv); keys != "" {
+			for i, key := range strings.Split(keys, ",") {
+				u.inherited[key] = os.NewFile(uintptr(5+i), key)
+			}
+		}
+
+		_ = os.Unsetenv(upgradeEnv)
+		_ = os.Unsetenv(listenersEnv)
+	}
+
+	if len(signals) == 0 {
+		return u
+	}
+
+	c := make(chan os.Signal, 1)
+	w.source.Notify(c, signals...)
+
+	go func() {
+		defer w.source.Stop(c)
+
+		for {
+			select {
+			case <-c:
+				ctx, cancel := context.WithTimeout(w.ctx, w.timeout)
+				_ = u.Upgrade(ctx)
+				cancel()
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return u
+}
+
+// HasParent reports whether the process was started by the upgrade of a
+// parent process.
+func (u *Upgrader) HasParent() bool {
+	return u.parent != nil
+}
+
+// Ready tells the parent process, if any, that the process is ready, so that
+// the parent closes its watcher.  The listeners passed by the parent that were
+// not claimed by Listen() are closed.
+func (u *Upgrader) Ready() error {
+	if u.parent == nil {
+		return nil
+	}
+
+	u.mu.Lock()
+	for key, f := range u.inherited {
+		_ = f.Close()
+		delete(u.inherited, key)
+	}
+	u.mu.Unlock()
+
+	defer func() { _ = u.parent.Close() }()
+
+	_, err := u.parent.Write([]byte{1})
+
+	return err
+}
+
+// Listen announces on the local network address, like net.Listen(), and
+// registers the listener so that it is passed to the new process on upgrades.
+// A process started by an upgrade is passed the listener of its parent with
+// the same network and address, so that no connection is refused during the
+// upgrade; the parent's listener can be closed at any time, e.g. by its
+// closers, without affecting the new process.  The listener can be wrapped,
+// e.g. to track connections.
+func (u *Upgrader) Listen(network, address string) (net.Listener, error) {
+	key := network + ":" + address
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var ln net.Listener
+	var err error
+
+	if f, ok := u.inherited[key]; ok {
+		delete(u.inherited, key)
+
+		ln, err = net.FileListener(f)
+		_ = f.Close()
+	} else {
+		ln, err = net.Listen(network, address)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the socket of a unix listener must outlive the listener of the parent
+	if l, ok := ln.(interface{ SetUnlinkOnClose(bool) }); ok {
+		l.SetUnlinkOnClose(false)
+	}
+
+	u.listeners = append(u.listeners, listener{key: key, ln: ln})
+
+	return ln, nil
+}
+
+// files returns the files of the registered listeners, and their keys.
+func (u *Upgrader) files() ([]*os.File, []string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var files []*os.File
+	var keys []string
+
+	for _, l := range u.listeners {
+		f, ok := l.ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+
+		file, err := f.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, nil, err
+		}
+
+		files = append(files, file)
+		keys = append(keys, l.key)
+	}
+
+	return files, keys, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+// Upgrade starts the executable of the process, with the same arguments and
+// environment, and waits for it to be ready, or to exit, or for the context to
+// be done.  Once the new process is ready, the watcher is closed and Upgrade
+// returns without waiting for the closers to be notified.
+//
+// When run by systemd, the service manager is notified that the service is
+// reloading during the upgrade, and then of the process ID of the new process;
+// the unit must allow notifications from all its processes, with
+// NotifyAccess=all, for the new process to notify the service manager itself.
+func (u *Upgrader) Upgrade(ctx context.Context) error {
+	u.mu.Lock()
+	if u.upgrading {
+		u.mu.Unlock()
+		return ErrUpgrading
+	}
+	u.upgrading = true
+	u.mu.Unlock()
+
+	defer func() {
+		u.mu.Lock()
+		u.upgrading = false
+		u.mu.Unlock()
+	}()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	_ = sdNotify("RELOADING=1")
+
+	pid, err := u.upgrade(ctx, exe)
+	if err != nil {
+		_ = sdNotify("READY=1")
+		return err
+	}
+
+	_ = sdNotify(fmt.Sprintf("MAINPID=%d\nREADY=1", pid))
+
+	go func() { _ = u.w.Close() }()
+
+	return nil
+}
+
+// upgrade starts the executable and returns the process ID of the new process
+// once it is ready.
+func (u *Upgrader) upgrade(ctx context.Context, exe string) (int, error) {
+
+	files, keys, err := u.files()
+	if err != nil {
+		return 0, err
+	}
+	defer closeFiles(files)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = r.Close() }()
+
+	local, remote, err := socketpair()
+	if err != nil {
+		_ = w.Close()
+		return 0, err
+	}
+	defer func() { _ = local.Close() }()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), upgradeEnv+"=1", listenersEnv+"="+strings.Join(keys, ","))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append([]*os.File{w, remote}, files...)
+
+	err = cmd.Start()
+	_ = w.Close()
+	_ = remote.Close()
+
+	if err != nil {
+		return 0, err
+	}
+
+	ready := make(chan error, 1)
+
+	go func() {
+		// the read fails if the new process exits before being ready
+		if _, err := r.Read(make([]byte, 1)); err != nil {
+			ready <- errors.New("new process exited before being ready")
+			return
+		}
+
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return 0, err
+	}
+
+	// the new process outlives this one
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+
+	// the states are handed off once the closers have been notified, so they
+	// are final, but before the callers of Wait() let the process exit
+	if conn, err := net.FileConn(local); err == nil {
+		if !u.w.addAfterClosers(func() {
+			_ = u.handOff(conn)
+			_ = conn.Close()
+		}) {
+			_ = conn.Close()
+		}
+	}
+
+	return pid, nil
+}
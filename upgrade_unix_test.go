@@ -0,0 +1,256 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package yama_test
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestUpgrader(t *testing.T) {
+
+	Convey("Ensure the process is upgraded on a signal", t, func() {
+		const source = `
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGTERM),
+		yama.WithClosers(yama.FnAsCloser(func() { fmt.Println("old closed") })))
+	upgrader := yama.NewUpgrader(watcher, syscall.SIGUSR2)
+
+	if upgrader.HasParent() {
+		fmt.Println("new ready")
+		_ = upgrader.Ready()
+		os.Exit(0)
+	}
+
+	fmt.Println("old ready")
+	_ = watcher.Wait()
+}
+`
+		p := yamatest.StartSubprocess(t, source)
+		So(p.WaitForOutput("old ready", 5*time.Second), ShouldBeTrue)
+
+		So(p.Signal(syscall.SIGUSR2), ShouldBeNil)
+		So(p.Wait(), ShouldBeNil)
+		So(p.Output(), ShouldEqual, "old ready\nnew ready\nold closed\n")
+	})
+
+	Convey("Ensure listeners are passed to the new process", t, func() {
+		const source = `
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(yama.WatchingSignals(syscall.SIGTERM))
+	upgrader := yama.NewUpgrader(watcher)
+
+	ln, err := upgrader.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if upgrader.HasParent() {
+		fmt.Println("new", ln.Addr())
+		_ = upgrader.Ready()
+		os.Exit(0)
+	}
+
+	fmt.Println("old", ln.Addr())
+	_ = upgrader.Upgrade(context.Background())
+	_ = watcher.Wait()
+}
+`
+		p := yamatest.StartSubprocess(t, source)
+		So(p.Wait(), ShouldBeNil)
+
+		lines := strings.Split(strings.TrimSpace(p.Output()), "\n")
+		So(lines, ShouldHaveLength, 2)
+		So(lines[0], ShouldStartWith, "old 127.0.0.1:")
+		So(strings.TrimPrefix(lines[1], "new "), ShouldEqual, strings.TrimPrefix(lines[0], "old "))
+	})
+
+	Convey("Ensure states are handed off to the new process", t, func() {
+		const source = `
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGTERM),
+		yama.WithClosers(yama.FnAsCloser(func() { fmt.Println("old closed") })))
+	upgrader := yama.NewUpgrader(watcher)
+
+	if upgrader.HasParent() {
+		_ = upgrader.Ready()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		states, err := upgrader.TakeOver(ctx)
+		fmt.Println("new", string(states["sessions"]), err)
+		os.Exit(0)
+	}
+
+	upgrader.HandOff("sessions", func() ([]byte, error) { return []byte("v1:alice,bob"), nil })
+	_ = upgrader.Upgrade(context.Background())
+	_ = watcher.Wait()
+}
+`
+		p := yamatest.StartSubprocess(t, source)
+		So(p.Wait(), ShouldBeNil)
+		So(p.Output(), ShouldEqual, "old closed\nnew v1:alice,bob <nil>\n")
+	})
+
+	Convey("Ensure processes that were not upgraded have no states to take over", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		states, err := yama.NewUpgrader(watcher).TakeOver(context.Background())
+		So(err, ShouldBeNil)
+		So(states, ShouldBeNil)
+	})
+
+	Convey("Ensure systemd is notified of upgrades", t, func() {
+		const source = `
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(yama.WatchingSignals(syscall.SIGTERM))
+	upgrader := yama.NewUpgrader(watcher)
+
+	if upgrader.HasParent() {
+		_ = upgrader.Ready()
+		os.Exit(0)
+	}
+
+	_ = upgrader.Upgrade(context.Background())
+	_ = watcher.Wait()
+}
+`
+		dir, err := ioutil.TempDir("", "yama")
+		So(err, ShouldBeNil)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		socket := filepath.Join(dir, "notify")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+		So(err, ShouldBeNil)
+		defer func() { _ = conn.Close() }()
+
+		So(os.Setenv("NOTIFY_SOCKET", socket), ShouldBeNil)
+		defer func() { _ = os.Unsetenv("NOTIFY_SOCKET") }()
+
+		p := yamatest.StartSubprocess(t, source)
+		So(p.Wait(), ShouldBeNil)
+
+		buf := make([]byte, 256)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		n, err := conn.Read(buf)
+		So(err, ShouldBeNil)
+		So(string(buf[:n]), ShouldEqual, "RELOADING=1")
+
+		n, err = conn.Read(buf)
+		So(err, ShouldBeNil)
+		So(string(buf[:n]), ShouldStartWith, "MAINPID=")
+		So(string(buf[:n]), ShouldEndWith, "\nREADY=1")
+	})
+
+	Convey("Ensure a failed upgrade keeps the process running", t, func() {
+		const source = `
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(yama.WatchingSignals(syscall.SIGTERM))
+	upgrader := yama.NewUpgrader(watcher)
+
+	if upgrader.HasParent() {
+		os.Exit(1)
+	}
+
+	fmt.Println(upgrader.Upgrade(context.Background()))
+	fmt.Println("old ready")
+	_ = watcher.Wait()
+}
+`
+		p := yamatest.StartSubprocess(t, source)
+		So(p.WaitForOutput("old ready", 5*time.Second), ShouldBeTrue)
+
+		So(p.Terminate(), ShouldBeNil)
+		So(p.Wait(), ShouldBeNil)
+		So(p.Output(), ShouldEqual, "new process exited before being ready\nold ready\n")
+	})
+}
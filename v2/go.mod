@@ -0,0 +1,10 @@
+module l7e.io/yama/v2
+
+go 1.13
+
+require (
+	github.com/smartystreets/goconvey v1.6.4
+	l7e.io/yama v0.0.0
+)
+
+replace l7e.io/yama => ../
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+Package yama is the context-first version of the l7e.io/yama package.
+
+A watcher is created with a parent context, whose cancellation closes it like
+a watched signal does; closers receive a context whose deadline is the end of
+the watcher's timeout, and Wait() returns a structured Result rather than a
+bare error.
+
+	w, err := yama.NewWatcher(ctx, v1.WatchingSignals(syscall.SIGINT, syscall.SIGTERM))
+	if err != nil {
+		return err
+	}
+
+	_ = w.Add(yama.CloserFunc(server.Shutdown))
+
+	if result := w.Wait(); result.Err != nil {
+		log.Print(result.Err)
+	}
+
+The options of version 1 are used as is, and version 1 closers and watchers
+are adapted with FromV1() and V1().
+*/
+package yama // import "l7e.io/yama/v2"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "l7e.io/yama"
+)
+
+// Option is an option of a Watcher; the options of version 1 are used as is.
+type Option = v1.Option
+
+// Closer is closed with a context whose deadline is the end of the watcher's
+// timeout, and which is cancelled when the timeout fires.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// CloserFunc is a function used as a Closer, e.g. the Shutdown() method of an
+// http.Server.
+type CloserFunc func(ctx context.Context) error
+
+// Close calls the function.
+func (f CloserFunc) Close(ctx context.Context) error {
+	return f(ctx)
+}
+
+// FromV1 adapts a version 1 closer, honoring the context if it implements
+// ContextCloser.
+func FromV1(closer io.Closer) Closer {
+	if c, ok := closer.(*toV1); ok {
+		return c.c
+	}
+
+	return fromV1{closer}
+}
+
+type fromV1 struct{ c io.Closer }
+
+func (f fromV1) Close(ctx context.Context) error {
+	if c, ok := f.c.(v1.ContextCloser); ok {
+		return c.CloseContext(ctx)
+	}
+
+	return f.c.Close()
+}
+
+// toV1 registers a Closer with a version 1 watcher.
+type toV1 struct{ c Closer }
+
+func (t *toV1) Close() error {
+	return t.c.Close(context.Background())
+}
+
+func (t *toV1) CloseContext(ctx context.Context) error {
+	return t.c.Close(ctx)
+}
+
+// ErrTimedOut is wrapped by the error of a Result when not all the closers
+// completed within the timeout.
+var ErrTimedOut = errors.New("closers did not complete within the timeout")
+
+// Result is the outcome of the notification of the closers of a Watcher.
+type Result struct {
+	// Err is nil if all the closers completed within the timeout; otherwise,
+	// it wraps ErrTimedOut and the error of the version 1 watcher.
+	Err error
+
+	// Uncompleted are the closers that did not complete within the timeout,
+	// in registration order.
+	Uncompleted []Closer
+}
+
+// Watcher notifies closers when a watched signal occurs, its parent context
+// is cancelled, or it is closed.
+type Watcher struct {
+	w *v1.Watcher
+}
+
+// NewWatcher creates a Watcher that is closed when the parent context is
+// cancelled, in addition to the watched signals.
+func NewWatcher(parent context.Context, options ...Option) (*Watcher, error) {
+	w, err := v1.NewWatcher(options...)
+	if err != nil {
+		return nil, fmt.Errorf("yama: %w", err)
+	}
+
+	if parent.Done() != nil {
+		finished := make(chan struct{})
+
+		go func() {
+			_ = w.Wait()
+			close(finished)
+		}()
+
+		go func() {
+			select {
+			case <-parent.Done():
+				_ = w.Close()
+			case <-finished:
+			}
+		}()
+	}
+
+	return &Watcher{w: w}, nil
+}
+
+// Add registers a closer; errors wrap v1.ErrShutdown if the closers are
+// being, or have been, notified.
+func (w *Watcher) Add(closer Closer) error {
+	if closer == nil {
+		return errors.New("yama: closer must not be null")
+	}
+
+	if err := w.w.AddCloser(&toV1{closer}); err != nil {
+		return fmt.Errorf("yama: %w", err)
+	}
+
+	return nil
+}
+
+// Close the watcher, notifying the closers, and return the result.  Can be
+// called multiple times, and concurrently, but closers are only called once.
+func (w *Watcher) Close() Result {
+	_ = w.w.Close()
+
+	return w.Wait()
+}
+
+// Wait until the closers have been notified and return the result; all the
+// callers receive the same result.
+func (w *Watcher) Wait() Result {
+	err := w.w.Wait()
+	if err == nil {
+		return Result{}
+	}
+
+	result := Result{Err: fmt.Errorf("yama: %w", err)}
+
+	var timedOut *v1.ErrTimedOut
+	if errors.As(err, &timedOut) {
+		result.Err = fmt.Errorf("yama: %w: %v", ErrTimedOut, timedOut)
+
+		for _, closer := range timedOut.Uncompleted {
+			result.Uncompleted = append(result.Uncompleted, FromV1(closer))
+		}
+	}
+
+	return result
+}
+
+// V1 returns the version 1 watcher, e.g. for the adapters of frameworks.
+func (w *Watcher) V1() *v1.Watcher {
+	return w.w
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	v1 "l7e.io/yama"
+	"l7e.io/yama/v2"
+	"l7e.io/yama/yamatest"
+)
+
+func TestWatcher(t *testing.T) {
+
+	Convey("Ensure closers receive the deadline of the timeout", t, func() {
+		w, err := yama.NewWatcher(context.Background(), v1.WithTimeout(time.Minute))
+		So(err, ShouldBeNil)
+
+		var deadline time.Time
+		So(w.Add(yama.CloserFunc(func(ctx context.Context) error {
+			deadline, _ = ctx.Deadline()
+			return nil
+		})), ShouldBeNil)
+
+		result := w.Close()
+		So(result.Err, ShouldBeNil)
+		So(deadline, ShouldHappenAfter, time.Now())
+		So(errors.Is(w.Add(yama.CloserFunc(nil)), v1.ErrShutdown), ShouldBeTrue)
+	})
+
+	Convey("Ensure the watcher is closed when the parent context is cancelled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		w, err := yama.NewWatcher(ctx)
+		So(err, ShouldBeNil)
+
+		cancel()
+		So(w.Wait().Err, ShouldBeNil)
+	})
+
+	Convey("Ensure timeouts are reported in the result", t, func() {
+		slow := yama.CloserFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		spy := yamatest.CloserSpy("v1")
+
+		w, err := yama.NewWatcher(context.Background(),
+			v1.WithTimeout(10*time.Millisecond),
+			v1.WithClosers(spy))
+		So(err, ShouldBeNil)
+		So(w.Add(slow), ShouldBeNil)
+
+		result := w.Close()
+		So(errors.Is(result.Err, yama.ErrTimedOut), ShouldBeTrue)
+		So(result.Uncompleted, ShouldHaveLength, 1)
+		So(spy, yamatest.ShouldHaveBeenClosed)
+	})
+
+	Convey("Ensure version 1 closers are adapted", t, func() {
+		spy := yamatest.CloserSpy("v1")
+		So(yama.FromV1(spy).Close(context.Background()), ShouldBeNil)
+		So(spy, yamatest.ShouldHaveBeenClosed)
+
+		var c io.Closer = spy
+		So(yama.FromV1(c), ShouldNotBeNil)
+	})
+
+	Convey("Ensure invalid options are reported", t, func() {
+		_, err := yama.NewWatcher(context.Background(), v1.WithClock(nil))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "yama: clock must not be null")
+	})
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"io"
+	"runtime/pprof"
+	"time"
+)
+
+// Heartbeat tells the watchdog, if any, that the application is alive.
+func (w *watcher) Heartbeat() {
+	w.mu.Lock()
+	w.heartbeat = w.clock.Now()
+	w.mu.Unlock()
+}
+
+// watchdog closes the instance if the heartbeat is older than the interval and
+// the grace period, once checked every interval, or until the instance is
+// closed or stopped.
+func (w *watcher) watchdog(interval, grace time.Duration, stacks io.Writer) {
+	w.Heartbeat()
+
+	for {
+		select {
+		case now := <-w.clock.After(interval):
+			w.mu.Lock()
+			late := now.Sub(w.heartbeat) > interval+grace
+			w.mu.Unlock()
+
+			if !late {
+				continue
+			}
+
+			if stacks != nil {
+				_ = pprof.Lookup("goroutine").WriteTo(stacks, 2)
+			}
+
+			_ = w.Close()
+
+			return
+		case <-w.ctx.Done():
+			return
+		case <-w.stop:
+			return
+		}
+	}
+}
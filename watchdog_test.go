@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestWatchdog(t *testing.T) {
+
+	Convey("Ensure the watcher is closed when heartbeats stop", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		closers := yamatest.NewClosers()
+		var stacks bytes.Buffer
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithWatchdog(time.Second, time.Second),
+			yama.DumpingStacksOnWatchdog(&stacks),
+			yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		// heartbeats keep the watcher open
+		for i := 0; i < 3; i++ {
+			clock.BlockUntil(1)
+			watcher.Heartbeat()
+			clock.Advance(time.Second)
+		}
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		clock.BlockUntil(1)
+		So(closers.Invoked(), ShouldBeEmpty)
+
+		clock.Advance(time.Second)
+		So(watcher.Wait(), ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "server")
+		So(stacks.String(), ShouldContainSubstring, "goroutine")
+	})
+
+	Convey("Ensure a negative watchdog interval cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithWatchdog(-time.Second, 0))
+		So(err, ShouldBeError)
+	})
+}
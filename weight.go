@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama // import "l7e.io/yama"
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Weighted returns a closer that closes the closer with a share of the
+// timeout proportional to its weight, the other closers weighing one unless
+// they are weighted too, e.g. a weight of 7 gives a Kafka consumer 70% of the
+// timeout when three other closers share the rest.  The deadline of its
+// context is the end of its share, which grows as the other closers complete,
+// so that the time they did not use is redistributed to those still running.
+// Weights only apply if at least one closer is weighted, and they are ignored
+// when a per-closer timeout is set, and by inline closers.
+func Weighted(weight float64, closer io.Closer) io.Closer {
+	if _, ok := closer.(InlineCloser); ok || weight <= 0 {
+		return closer
+	}
+
+	return &weightedCloser{Closer: closer, weight: weight}
+}
+
+type weightedCloser struct {
+	io.Closer
+	weight float64
+}
+
+func (c *weightedCloser) CloseContext(ctx context.Context) error {
+	return closeWithContext(ctx, c.Closer)
+}
+
+func (c *weightedCloser) Name() string {
+	return closerName(c.Closer)
+}
+
+// weightOf returns the weight of the closer, and whether it is weighted.
+func weightOf(closer io.Closer) (float64, bool) {
+	switch c := closer.(type) {
+	case *weightedCloser:
+		return c.weight, true
+	case *taggedCloser:
+		return weightOf(c.Closer)
+	default:
+		return 1, false
+	}
+}
+
+// shares allocates the budget of the closers according to their weights.
+type shares struct {
+	clock   Clock
+	parent  *deadlineContext
+	weights []float64
+	mu      sync.Mutex
+	running map[int]*deadlineContext
+	pending float64
+}
+
+// newShares returns the shares of the closers, or nil if none is weighted.
+func newShares(clock Clock, parent *deadlineContext, closers []io.Closer) *shares {
+	s := &shares{clock: clock, parent: parent, weights: make([]float64, len(closers)), running: make(map[int]*deadlineContext)}
+	weighted := false
+
+	for i, closer := range closers {
+		var ok bool
+		s.weights[i], ok = weightOf(closer)
+		weighted = weighted || ok
+		s.pending += s.weights[i]
+	}
+
+	if !weighted {
+		return nil
+	}
+
+	return s
+}
+
+// share returns the end of the share of the closer with the weight, at the
+// time, when the closers that have not completed weigh pending.
+func (s *shares) share(now time.Time, weight float64) time.Time {
+	deadline, _ := s.parent.Deadline()
+	if !now.Before(deadline) {
+		return deadline
+	}
+
+	return now.Add(time.Duration(float64(deadline.Sub(now)) * weight / s.pending))
+}
+
+// start returns the context of the closer, whose deadline is the end of its
+// share.
+func (s *shares) start(i int) *deadlineContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := newDeadlineContext(s.share(s.clock.Now(), s.weights[i]))
+	s.running[i] = ctx
+
+	return ctx
+}
+
+// done redistributes the share of the closer that completed to the closers
+// still running.
+func (s *shares) done(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, i)
+	s.pending -= s.weights[i]
+
+	now := s.clock.Now()
+	for j, ctx := range s.running {
+		ctx.setDeadline(s.share(now, s.weights[j]))
+	}
+}
+
+// closeWeighted calls the closer with the context of its share, which is
+// expired when the share ends, or when the timeout fires; the status of the
+// closer has the index status.
+func (w *watcher) closeWeighted(shares *shares, i, status int, closer io.Closer) error {
+	ctx := shares.start(i)
+	defer shares.done(i)
+	defer ctx.cancel()
+
+	done := make(chan error, 1)
+
+	go func() { done <- w.closeNotified(ctx, status, closer) }()
+
+	for {
+		deadline, _ := ctx.Deadline()
+		timer := w.clock.NewTimer(deadline.Sub(w.clock.Now()))
+
+		select {
+		case err := <-done:
+			timer.Stop()
+			return err
+		case <-ctx.changed:
+			timer.Stop()
+		case <-shares.parent.Done():
+			timer.Stop()
+			ctx.expire()
+			return <-done
+		case <-timer.C():
+			ctx.expire()
+			return <-done
+		}
+	}
+}
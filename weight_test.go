@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestWeighted(t *testing.T) {
+
+	Convey("Ensure the timeout is shared according to the weights, and redistributed", t, func() {
+		start := time.Now()
+		clock := yamatest.NewClock(start)
+		deadlines := make(chan time.Time, 3)
+		release := make(chan struct{})
+
+		kafka := yama.Weighted(3, yama.ContextFnAsCloser(func(ctx context.Context) error {
+			first, _ := ctx.Deadline()
+			deadlines <- first
+
+			for {
+				if d, _ := ctx.Deadline(); !d.Equal(first) {
+					deadlines <- d
+					return nil
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}))
+
+		cache := yama.ContextFnAsCloser(func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(4*time.Minute),
+			yama.WithClosers(kafka, cache))
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+
+		So(<-deadlines, ShouldEqual, start.Add(3*time.Minute))
+
+		close(release)
+		So(<-deadlines, ShouldEqual, start.Add(4*time.Minute))
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure the context of a weighted closer expires at the end of its share", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		errs := make(chan error, 1)
+		release := make(chan struct{})
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(2*time.Minute),
+			yama.WithClosers(
+				yama.Weighted(1, yama.ContextFnAsCloser(func(ctx context.Context) error {
+					<-ctx.Done()
+					errs <- ctx.Err()
+					return nil
+				})),
+				yama.FnAsCloser(func() { <-release })))
+		So(err, ShouldBeNil)
+
+		go func() { _ = watcher.Close() }()
+
+		clock.BlockUntil(3)
+		clock.Advance(time.Minute)
+		So(<-errs == context.DeadlineExceeded, ShouldBeTrue)
+
+		close(release)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure weighted closers keep their tags and names", t, func() {
+		spy := yamatest.CloserSpy("kafka")
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(yama.Weighted(7, yama.Tagged("stateful", spy))))
+		So(err, ShouldBeNil)
+		So(watcher.Status().Closers[0].Name, ShouldEqual, "kafka")
+
+		So(watcher.CloseTagged("stateful"), ShouldBeNil)
+		So(spy.Calls(), ShouldEqual, 1)
+	})
+}
@@ -42,24 +42,130 @@ The application can programmatically trigger Closer notifications by calling
 
 If this is done, subsequent signals will not trigger Closer notifications.
 
-There are a few helper methods, FnAsCloser() and ErrValFnAsCloser(), that can
-be used to wrap simple functions and functions that can return an error,
-respectively, into instances that implement io.Closer.
+Once the Closer notifications have completed, the watcher stops watching the
+signals, which then have their default effect.  Watchers that are no longer
+needed can stop watching the signals, without notifying their closers, by
+calling
+
+    watcher.Stop()
+
+Applications can also let Run() create the watcher and call their main
+function with a context that is cancelled when one of the signals occur; the
+closers are notified once the function returns or one of the signals occur.
+
+    err := yama.Run(context.Background(), options, func(ctx context.Context) error {
+        return serve(ctx)
+    })
+
+Applications that have a top-level context can let NotifyContext() create
+the watcher, whose closers are notified when that context is cancelled, with
+a context that is cancelled when one of the signals occur, and a function that
+stops watching them.
+
+    ctx, stop, err := yama.NotifyContext(ctx, options...)
+
+Watchers can also follow a context that they don't own, whose cancellation
+notifies the closers as one of the signals would.
+
+    yama.WithTriggerContext(ctx)
+
+Code that is driven by contexts can use the watcher's context, which is
+cancelled as soon as one of the signals occur or the watcher is closed.
+
+    ctx := watcher.Context()
+
+Loops can also select on the channel returned by Done(), which is closed at
+the same time, rather than blocking in Wait().
+
+    select {
+    case job := <-jobs:
+        process(job)
+    case <-watcher.Done():
+        return
+    }
+
+Closers can also be registered after the watcher has been created
+
+    err := watcher.AddCloser(db)
+
+and unregistered once the application closed them itself
+
+    err := watcher.RemoveCloser(conn)
+
+Libraries deep in the dependency tree can register their closers with the
+process-wide default watcher, returned by Default(), once main() has opted in
+with SetDefault(), or with Watch(), which creates it; until then, no signal is
+captured and ErrNoDefault is returned.
+
+    _, err := yama.Watch(yama.WatchingEvents(yama.Interrupt, yama.Terminate))
+    ...
+    err = yama.Register(pool)
+    ...
+    err = yama.Wait()
+
+Closers that implement ContextCloser have their CloseContext() method called,
+instead of Close(), with a context whose deadline is the end of the timeout.
+
+There are a few helper methods, FnAsCloser(), ErrValFnAsCloser(), and
+ContextFnAsCloser(), that can be used to wrap simple functions, functions that
+can return an error, and functions that honor a context deadline, respectively,
+into instances that implement io.Closer.
+
+Closers that complete promptly without blocking can be wrapped with
+InlineFnAsCloser(), or implement InlineCloser, so that they are called on the
+notifying goroutine rather than in a goroutine of their own.
+
+Wrapper binaries can let Supervise() start a child process and create a
+watcher that forwards the signals to the child, and waits for it to exit when
+the closers are notified.
+
+    watcher, err := yama.Supervise(exec.Command("server"),
+        yama.WatchingSignals(syscall.SIGINT, syscall.SIGTERM))
+
+Processes can be upgraded without downtime by an Upgrader, which starts the new
+binary when one of its signals occurs and closes the watcher once the new
+process reports it is ready.
+
+    upgrader := yama.NewUpgrader(watcher, syscall.SIGUSR2)
+    if upgrader.HasParent() {
+        err = upgrader.Ready()
+    }
+
+Listeners created by the upgrader's Listen() are passed to the new process, and
+states registered with HandOff() are streamed to it, once the closers of the old
+process have been notified, for it to TakeOver().
 */
 package yama // import "l7e.io/yama"
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/signal"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // DefaultTimeout is the default closer timeout of watcher instances.
 const DefaultTimeout = 10 * time.Second
 
+// ErrShutdown is returned when adding a closer to a watcher whose closers are
+// being, or have been, notified.
+var ErrShutdown = errors.New("watcher has been shutdown")
+
+// ErrDuplicateCloser is returned when registering a closer that is already
+// registered, unless duplicates are allowed.
+var ErrDuplicateCloser = errors.New("closer already registered")
+
+// ErrUnknownCloser is returned when unregistering a closer that is not
+// registered.
+var ErrUnknownCloser = errors.New("closer not registered")
+
 // ErrTimedOut is an error that contains the set of closers that didn't complete
 // before the configured timeout.
 type ErrTimedOut struct {
@@ -70,139 +176,1286 @@ func (e *ErrTimedOut) Error() string {
 	return "closers timed out"
 }
 
+// ErrSlowClosers is an error that contains the closers that didn't complete
+// within the per-closer timeout, in registration order; those that didn't
+// complete within the watcher's timeout either are also reported by an
+// ErrTimedOut error.
+type ErrSlowClosers struct {
+	Slow []io.Closer
+}
+
+func (e *ErrSlowClosers) Error() string {
+	return fmt.Sprintf("%d closers exceeded their timeout", len(e.Slow))
+}
+
+// Shutdowner is the interface of watchers; components that register closers
+// with, or wait for, a watcher can depend on it so that tests can substitute a
+// fake watcher.
+type Shutdowner interface {
+	// AddCloser registers an additional closer to be called when the
+	// closers are notified.
+	AddCloser(closer io.Closer) error
+
+	// RemoveCloser unregisters a closer, e.g. one that the component closed
+	// itself.
+	RemoveCloser(closer io.Closer) error
+
+	// Close notifies the registered closers.
+	Close() error
+
+	// Wait blocks until the registered closers have been notified.
+	Wait() error
+
+	// Context returns a context that is cancelled once the shutdown is
+	// triggered, before the closers are notified.
+	Context() context.Context
+
+	// Done returns a channel that is closed once the shutdown is triggered,
+	// the Done() channel of Context().
+	Done() <-chan struct{}
+}
+
+var _ Shutdowner = (*Watcher)(nil)
+
 // Watcher notifies configured closers when a configured signal occurred or
 // when the instance is closed.  Closers are only called once.
 //
+// A Watcher that becomes unreachable without having been closed stops
+// watching the configured signals, as if Stop() had been called, unless it
+// exits the process after shutdown; applications that rely on signals to
+// notify the closers must keep a reference to the instance, e.g. by waiting
+// for it.
+//
 // See the package documentation for details.
 type Watcher struct {
-	wg      sync.WaitGroup
-	signals chan os.Signal
-	done    chan struct{}
-	timeout time.Duration
-	closers []io.Closer
-	once    sync.Once
-	err     error
+	*watcher
 }
 
-// holder is a wrapper to the struct we are going to close with metadata
-// to help with debugging close.
-type holder struct {
-	key    int
-	closer io.Closer
+// watcher holds the state of a Watcher; the goroutine watching for signals
+// only references the state, so that the Watcher can become unreachable.
+type watcher struct {
+	signals           chan os.Signal
+	watched           []os.Signal
+	combining         chan os.Signal
+	registered        []chan os.Signal
+	source            SignalSource
+	done              chan struct{}
+	closing           sync.Once
+	stop              chan struct{}
+	stopping          sync.Once
+	timeout           time.Duration
+	concurrency       int
+	drainDelay        time.Duration
+	heartbeat         time.Time
+	marker            string
+	pidFile           string
+	dryRun            io.Writer
+	duplicates        bool
+	synchronous       bool
+	echo              io.Writer
+	hint              string
+	hintOut           io.Writer
+	progress          io.Writer
+	progressInterval  time.Duration
+	budgetAlert       func(alert BudgetAlert)
+	budgetFractions   []float64
+	confirmation      confirmation
+	closerTimeout     time.Duration
+	abandon           bool
+	emergency         []io.Closer
+	emergencyTimeout  time.Duration
+	flushers          []io.Closer
+	flushTimeout      time.Duration
+	last              io.Closer
+	lastTimeout       time.Duration
+	notifiers         []Notifier
+	notifyTimeout     time.Duration
+	notifyQueues      []chan Notification
+	notifyFinished    bool
+	notifications     sync.WaitGroup
+	drains            sync.WaitGroup
+	clock             Clock
+	exit              func(code int)
+	exitAfterShutdown bool
+	reraise           bool
+	exitCode          func(reason Reason, sig os.Signal, err error) int
+	received          os.Signal
+	rehearsal         bool
+	cause             error
+	starters          []Starter
+	components        *startedCloser
+	events            *events
+	statuses          []closerStatus
+	draining          *deadlineContext
+	budget            time.Time
+	budgetCap         time.Time
+	budgetSpent       bool
+	coalesceWindow    time.Duration
+	lastSignal        os.Signal
+	lastSignalAt      time.Time
+	coalesced         int
+	signalStats       []SignalStat
+	forwards          []func(sig os.Signal)
+	escalation        Escalation
+	policies          map[os.Signal]SignalPolicy
+	combiningWindow   time.Duration
+	combinations      []SignalCombination
+	policy            SignalPolicy
+	timeoutPolicy     TimeoutPolicy
+	ctx               context.Context
+	cancel            context.CancelFunc
+	mu                sync.Mutex
+	closers           []io.Closer
+	afterClosers      []func()
+	doneChans         []chan<- error
+	tempPaths         []string
+	started, ended    time.Time
+	notified          int
+	closersDone       bool
+	shutdown          bool
+	once              sync.Once
+	finished          chan struct{}
+	err               error
 }
 
-// NewWatcher creates Watcher with various options.
-func NewWatcher(options ...Option) (yama *Watcher, err error) {
-	w := &Watcher{
-		signals: make(chan os.Signal, 1),
-		done:    make(chan struct{}, 1),
-	}
+// NewWatcher creates Watcher with various options; all the problems found in
+// the settings of the options are reported together.
+func NewWatcher(options ...Option) (*Watcher, error) {
+	s := &Settings{TimeOut: DefaultTimeout, Source: osSignals{}, Clock: realClock{}, ExitFunc: os.Exit, ExitCode: exitCode}
 
-	s := &Settings{TimeOut: DefaultTimeout}
+	for i, option := range options {
+		if option == nil {
+			s.errs = append(s.errs, fmt.Errorf("option #%d must not be null", i))
+			continue
+		}
 
-	for _, option := range options {
 		option.Apply(s)
 	}
 
-	for i, closer := range s.Closers {
-		if closer == nil {
-			return nil, fmt.Errorf("closer #%d must not be null", i)
-		}
+	return newWatcher(s)
+}
+
+// NewWatcherFromSettings creates a Watcher with a copy of the settings, e.g.
+// a base configuration cloned, and specialized by applying options to it; the
+// settings that are not set, such as the timeout, the signal source, the
+// clock, and the exit functions, have their defaults.
+func NewWatcherFromSettings(settings *Settings) (*Watcher, error) {
+	if settings == nil {
+		return nil, errors.New("settings must not be null")
+	}
+
+	s := settings.Clone()
+
+	if s.TimeOut == 0 {
+		s.TimeOut = DefaultTimeout
+	}
+
+	if s.Source == nil {
+		s.Source = osSignals{}
+	}
+
+	if s.Clock == nil {
+		s.Clock = realClock{}
+	}
+
+	if s.ExitFunc == nil {
+		s.ExitFunc = os.Exit
+	}
+
+	if s.ExitCode == nil {
+		s.ExitCode = exitCode
+	}
+
+	return newWatcher(s)
+}
+
+func newWatcher(s *Settings) (yama *Watcher, err error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		signals:  make(chan os.Signal, 1),
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		finished: make(chan struct{}),
 	}
 
 	w.timeout = s.TimeOut
-	w.closers = s.Closers
+	w.concurrency = s.Concurrency
+	w.drainDelay = s.DrainDelay
+	w.marker = s.ShutdownMarker
+	w.pidFile = s.PIDFile
+	w.dryRun = s.DryRun
+	w.duplicates = s.AllowDuplicates
+	w.synchronous = s.Synchronous
+	w.echo = s.SignalEcho
+	w.hint = s.InterruptHint
+	w.hintOut = s.InterruptHintOut
+	w.progress = s.Progress
+	w.progressInterval = s.ProgressInterval
+	w.budgetAlert = s.BudgetAlert
+	w.budgetFractions = sortedFractions(s.BudgetFractions)
+	w.confirmation = confirmation{
+		prompt:  s.ConfirmationPrompt,
+		timeout: s.ConfirmationTimeout,
+		in:      s.ConfirmationIn,
+		out:     s.ConfirmationOut,
+		answers: make(chan string),
+	}
+	w.closerTimeout = s.CloserTimeOut
+	w.abandon = s.AbandonSlowClosers
+	w.emergency = append([]io.Closer(nil), s.EmergencyClosers...)
+	w.emergencyTimeout = s.EmergencyTimeOut
+	w.starters = append([]Starter(nil), s.Starters...)
+	w.flushers = append([]io.Closer(nil), s.Flushers...)
+	w.flushTimeout = s.FlushTimeOut
+	w.last = s.LastCloser
+	w.lastTimeout = s.LastTimeOut
+	w.notifiers = append([]Notifier(nil), s.Notifiers...)
+	w.notifyTimeout = s.NotifyTimeOut
+	w.clock = s.Clock
+	w.events = newEvents(s.StatusEvents, s.Clock)
+	w.exit = s.ExitFunc
+	w.exitAfterShutdown = s.ExitAfterShutdown
+	w.reraise = s.ReraiseSignal
+	w.exitCode = s.ExitCode
+	w.forwards = s.forwards
+	w.escalation = s.Escalation
+	w.policy = SignalPolicy{TimeOut: s.TimeOut, DrainDelay: s.DrainDelay, Escalation: s.Escalation}
+	w.policies = make(map[os.Signal]SignalPolicy, len(s.SignalPolicies))
+	for sig, policy := range s.SignalPolicies {
+		w.policies[sig] = policy
+	}
+	w.coalesceWindow = s.CoalescingWindow
+	w.signalStats = newSignalStats(s.Signals, s.RestartSignals, s.CountedSignals, tagSignals(s.TagSignals), combinationSignals(s.SignalCombinations, s.Signals))
+	w.timeoutPolicy = s.TimeoutPolicy
+	w.watched = append([]os.Signal(nil), s.Signals...)
+	w.closers = append([]io.Closer(nil), s.Closers...)
+	w.ctx, w.cancel = context.WithCancel(context.Background())
 
-	signal.Notify(w.signals, s.Signals...)
+	if w.pidFile != "" {
+		if err := writePIDFile(w.pidFile); err != nil {
+			return nil, err
+		}
+	}
 
-	// The wait group will be marked done when a signal is observed or the
-	// watcher receives done.
-	w.wg.Add(1)
+	// the steps that can fail release what the previous ones started
+	fail := func(err error) (*Watcher, error) {
+		close(w.stop)
+		w.removePIDFile()
 
-	go func() {
-		defer func() {
-			w.notify()
-			w.wg.Done()
-		}()
+		return nil, err
+	}
 
-		for {
-			select {
-			case <-w.signals:
-				return
-			case <-w.done:
-				return
-			}
+	if s.CgroupMemory.Interval > 0 {
+		if err := w.watchCgroupMemory(s.CgroupMemory); err != nil {
+			return fail(err)
+		}
+	}
+
+	if s.SessionEndReason != "" {
+		if err := w.watchSessionEnd(s.SessionEndReason); err != nil {
+			return fail(err)
 		}
+	}
+
+	w.source = s.Source
+
+	if s.OnSuspend != nil || s.OnResume != nil {
+		w.watchSuspend(s.OnSuspend, s.OnResume)
+	}
+
+	if s.SleepInterval > 0 {
+		go w.watchSleep(s.Clock.(SleepClock), s.SleepInterval, s.OnSystemSuspend, s.OnSystemResume, s.ExcludeSleep)
+	}
+
+	if len(s.CountedSignals) > 0 {
+		go w.countSignals(w.register(s.CountedSignals...))
+	}
+
+	if len(s.RestartSignals) > 0 {
+		go w.watchRestarts(w.register(s.RestartSignals...))
+	}
+
+	if len(s.TagSignals) > 0 {
+		go w.watchTagSignals(w.register(tagSignals(s.TagSignals)...), append([]TagSignal(nil), s.TagSignals...))
+	}
+
+	for _, ctx := range s.TriggerContexts {
+		go w.watchTriggerContext(ctx)
+	}
+
+	if s.WatchdogInterval > 0 {
+		go w.watchdog(s.WatchdogInterval, s.WatchdogGrace, s.WatchdogStacks)
+	}
+
+	if s.ChaosProbability > 0 {
+		go w.rehearse(s.ChaosProbability, s.ChaosWindow)
+	}
+
+	// watchers that only are closed programmatically don't need to register
+	// for signals, nor a goroutine to watch them
+	if len(w.watched) == 0 {
+		return &Watcher{w}, nil
+	}
+
+	w.source.Notify(w.signals, w.watched...)
+
+	if len(s.SignalCombinations) > 0 {
+		w.combiningWindow = s.CombiningWindow
+		w.combinations = append([]SignalCombination(nil), s.SignalCombinations...)
+
+		if signals := combinationSignals(s.SignalCombinations, s.Signals); len(signals) > 0 {
+			w.combining = make(chan os.Signal, len(signals))
+			w.source.Notify(w.combining, signals...)
+		}
+	}
+
+	go func() {
+		if !w.awaitTrigger() {
+			return
+		}
+
+		go w.repeatedSignals()
+
+		w.notify()
 	}()
 
-	return w, nil
+	yama = &Watcher{w}
+
+	if !w.exitAfterShutdown {
+		runtime.SetFinalizer(yama, func(yama *Watcher) { yama.Stop() })
+	}
+
+	return yama, nil
+}
+
+// register returns a channel to which the source relays the signals, until
+// the instance is stopped.
+func (w *watcher) register(sig ...os.Signal) chan os.Signal {
+	c := make(chan os.Signal, 1)
+	w.source.Notify(c, sig...)
+	w.registered = append(w.registered, c)
+
+	return c
+}
+
+// AddCloser registers an additional closer to be called when a configured
+// signal occurs or the instance is closed.  ErrShutdown is returned if the
+// closers are being, or have been, notified, and ErrDuplicateCloser if the
+// closer is already registered and duplicates are not allowed.
+func (w *watcher) AddCloser(closer io.Closer) error {
+	if closer == nil {
+		return errors.New("closer must not be null")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.shutdown {
+		return ErrShutdown
+	}
+
+	if !w.duplicates && indexOfCloser(w.closers, closer) >= 0 {
+		return ErrDuplicateCloser
+	}
+
+	w.closers = append(w.closers, closer)
+
+	return nil
+}
+
+// RemoveCloser unregisters a closer, e.g. a connection that the application
+// closed itself, so that it is not called when the closers are notified; a
+// closer registered more than once is unregistered once.  ErrShutdown is
+// returned if the closers are being, or have been, notified, and
+// ErrUnknownCloser if the closer is not registered.
+func (w *watcher) RemoveCloser(closer io.Closer) error {
+	if closer == nil {
+		return errors.New("closer must not be null")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.shutdown {
+		return ErrShutdown
+	}
+
+	i := indexOfCloser(w.closers, closer)
+	if i < 0 {
+		return ErrUnknownCloser
+	}
+
+	w.closers = append(w.closers[:i:i], w.closers[i+1:]...)
+
+	return nil
 }
 
-// Wait until the configured signal occurs or the instance is closed.
-func (w *Watcher) Wait() error {
-	w.wg.Wait()
+// indexOfCloser returns the index of the closer in closers, or -1; closers of
+// types that cannot be compared are never found.
+func indexOfCloser(closers []io.Closer, closer io.Closer) int {
+	if !reflect.TypeOf(closer).Comparable() {
+		return -1
+	}
+
+	for i, c := range closers {
+		if reflect.TypeOf(c) == reflect.TypeOf(closer) && c == closer {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Simulate delivers the signal to the instance as if it had been delivered by
+// the OS, without sending it to the process.  Like signals delivered by the
+// OS, the signal is ignored if it is not one of the configured signals, or if
+// it cannot be delivered without blocking.
+func (w *watcher) Simulate(sig os.Signal) {
+	if !watches(w.watched, sig) {
+		return
+	}
+
+	select {
+	case w.signals <- sig:
+	default:
+	}
+}
+
+// Wait until the configured signal occurs or the instance is closed, and the
+// closers have been notified.  Any number of goroutines can wait concurrently;
+// they are all unblocked once the closers have been notified and all receive
+// the same error.
+func (w *watcher) Wait() error {
+	<-w.finished
 
 	return w.err
 }
 
+// Context returns a context that is cancelled as soon as a configured signal
+// occurs or the instance is closed, before the closers are notified, so that
+// code driven by contexts, e.g. HTTP clients and worker loops, can stop with
+// the shutdown; it is not cancelled when the instance is stopped.
+func (w *watcher) Context() context.Context {
+	return w.ctx
+}
+
+// Done returns a channel that is closed as soon as a configured signal occurs
+// or the instance is closed, before the closers are notified, for callers to
+// select on alongside other channels rather than blocking in Wait(); it is
+// the Done() channel of Context().
+func (w *watcher) Done() <-chan struct{} {
+	return w.ctx.Done()
+}
+
+// NotifyDone causes the error that Wait() returns to be sent to the channel
+// once the closers have been notified, or right away if they already have.
+// Like signal.Notify(), the error is not sent if the channel is not ready to
+// receive it, so the channel should be buffered.
+func (w *watcher) NotifyDone(c chan<- error) {
+	if c == nil {
+		panic("yama: NotifyDone using nil channel")
+	}
+
+	w.mu.Lock()
+	select {
+	case <-w.finished:
+		w.mu.Unlock()
+		sendDone(c, w.err)
+
+		return
+	default:
+	}
+
+	w.doneChans = append(w.doneChans, c)
+	w.mu.Unlock()
+}
+
+func sendDone(c chan<- error, err error) {
+	select {
+	case c <- err:
+	default:
+	}
+}
+
 // Close the instance, notifying any registered closers. Can be called
-// multiple times, but closers will only be called once.
-func (w *Watcher) Close() error {
-	w.done <- struct{}{}
+// multiple times, and concurrently, but closers will only be called once.
+func (w *watcher) Close() error {
+	w.closing.Do(func() { close(w.done) })
 	w.notify()
 
-	return w.err
+	return w.Wait()
 }
 
-// Notify closers, ensuring they are only called once.
-func (w *Watcher) notify() {
-	w.once.Do(w.notifyClosers)
+// closeWithCause closes the instance, like Close(), with the cause of the
+// shutdown, which is reported by the error that Wait() returns unless the
+// closers are already being, or have been, notified.
+func (w *watcher) closeWithCause(cause error) error {
+	w.mu.Lock()
+	if !w.shutdown && w.cause == nil {
+		w.cause = cause
+	}
+	w.mu.Unlock()
+
+	return w.Close()
+}
+
+// Stop watching the configured signals, releasing the instance's signal
+// registration and goroutine, without notifying the closers; the instance can
+// still be closed.  Stop is called once the closers have been notified, and can
+// be called multiple times.
+func (w *watcher) Stop() {
+	w.stopping.Do(func() {
+		close(w.stop)
+
+		for _, c := range w.registered {
+			w.source.Stop(c)
+		}
+
+		if len(w.watched) == 0 {
+			return
+		}
+
+		w.source.Stop(w.signals)
+
+		if w.combining != nil {
+			w.source.Stop(w.combining)
+		}
+
+		// drain any signal that was delivered before the registration was
+		// released
+		select {
+		case <-w.signals:
+		default:
+		}
+	})
 }
 
-// notifyClosers calls all closers once and wait for them to finish with a
-// channel.  If not all closers return within the timeout, returns an error
-// with the tardy closers.
-func (w *Watcher) notifyClosers() {
-	count := len(w.closers)
+// awaitTrigger waits for a watched signal, confirmed if needed, or for the
+// instance to be closed; it reports false if the instance is stopped first.
+func (w *watcher) awaitTrigger() bool {
+	// the signals of the combinations that aren't watched, and when they
+	// last occurred
+	var early map[os.Signal]time.Time
+
+	for {
+		select {
+		case sig := <-w.signals:
+			w.countSignal(sig)
+
+			if !w.confirmInterrupt(sig) {
+				continue
+			}
+
+			policy, ok := w.policies[sig]
+
+			if w.combiningWindow > 0 {
+				collected, collecting := w.collectSignals(sig, early)
+				if !collecting {
+					return false
+				}
+
+				if combined, found := w.combinedPolicy(collected); found {
+					policy, ok = combined, true
+				}
+			}
+
+			w.mu.Lock()
+			w.received = sig
+			w.lastSignal, w.lastSignalAt = sig, w.clock.Now()
+			if ok {
+				w.policy = policy
+			}
+			w.mu.Unlock()
+
+			w.echoSignal(sig)
+			w.hintInterrupt(sig)
+			w.forward(sig)
+
+			return true
+		case sig := <-w.combining:
+			w.countSignal(sig)
+
+			if early == nil {
+				early = make(map[os.Signal]time.Time)
+			}
+
+			early[sig] = w.clock.Now()
+		case <-w.done:
+			return true
+		case <-w.stop:
+			return false
+		}
+	}
+}
+
+// coalesce reports whether the signal repeats the previous one within the
+// coalescing window, and counts it if so.
+func (w *watcher) coalesce(sig os.Signal) bool {
+	now := w.clock.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	repeated := w.coalesceWindow > 0 && sig == w.lastSignal && now.Sub(w.lastSignalAt) < w.coalesceWindow
+	if repeated {
+		w.coalesced++
+	}
+
+	w.lastSignal, w.lastSignalAt = sig, now
+
+	return repeated
+}
+
+// Coalesced returns the number of signals that were coalesced with the
+// previous one, as specified by CoalescingSignals().
+func (w *watcher) Coalesced() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.coalesced
+}
+
+// repeatedSignals counts and forwards the signals delivered while the closers
+// are notified, and escalates according to the policy, until the instance is
+// stopped.
+func (w *watcher) repeatedSignals() {
+	for {
+		select {
+		case sig := <-w.signals:
+			w.countSignal(sig)
+
+			if w.coalesce(sig) {
+				continue
+			}
+
+			w.forward(sig)
+
+			if w.shutdownPolicy().Escalation == ExitingOnRepeat {
+				w.exit(1)
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Remaining returns what is left of the budget of the closers, which are all
+// bounded by the same deadline, so that the drain delay and the closers that
+// already completed have been subtracted: the timeout before the shutdown is
+// triggered, a shrinking duration, which ExtendDeadline() and Abort() move,
+// while the closers are notified, and zero once they have been, or the
+// deadline has passed.  The emergency closers, the flushers, and the last
+// closer have their own timeouts, and are not accounted for.
+func (w *watcher) Remaining() time.Duration {
+	now := w.clock.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case w.budgetSpent:
+		return 0
+	case w.budget.IsZero():
+		return w.policy.TimeOut
+	case !now.Before(w.budget):
+		return 0
+	default:
+		return w.budget.Sub(now)
+	}
+}
+
+// shutdownPolicy returns the policy of the shutdown: that of the signal that
+// triggered it, if it has one, or the settings of the instance.
+func (w *watcher) shutdownPolicy() SignalPolicy {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.policy
+}
+
+// echoSignal writes a note that the signal was received, if configured.
+func (w *watcher) echoSignal(sig os.Signal) {
+	if w.echo == nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w.echo, "%v received %v, beginning graceful shutdown (budget %v)\n",
+		w.clock.Now().Format(time.RFC3339), signalName(sig), w.shutdownPolicy().TimeOut)
+}
+
+// hintInterrupt writes the interrupt hint, if configured, when the signal is
+// an interrupt, and another one exits the process.
+func (w *watcher) hintInterrupt(sig os.Signal) {
+	if w.hintOut == nil || sig != os.Interrupt || w.shutdownPolicy().Escalation != ExitingOnRepeat {
+		return
+	}
+
+	_, _ = fmt.Fprintln(w.hintOut, w.hint)
+}
+
+// forward the signal to the configured processes.
+func (w *watcher) forward(sig os.Signal) {
+	for _, forward := range w.forwards {
+		forward(sig)
+	}
+}
+
+// Notify closers, ensuring they are only called once, and then unblock the
+// callers of Wait().  Only the first caller notifies the closers; the others
+// return immediately, rather than blocking on the once, so that goroutines
+// waiting for the notification are always blocked on the finished channel,
+// which testing/synctest considers durably blocked.
+func (w *watcher) notify() {
+	first := false
+	w.once.Do(func() { first = true })
+
+	if !first {
+		return
+	}
+
+	w.mu.Lock()
+	w.started = w.clock.Now()
+	w.mu.Unlock()
+
+	if w.events != nil || len(w.notifiers) > 0 {
+		_, sig := w.Reason()
+		w.events.emit("triggered", sig, nil, nil)
+		w.sendNotification("triggered", sig, nil)
+	}
+
+	w.cancel()
+	w.markShutdown()
+
+	immediate := w.dryRun == nil && w.shutdownPolicy().Immediate
+
+	if w.dryRun != nil {
+		w.writePlan()
+	} else {
+		if immediate {
+			w.skipClosers()
+		} else {
+			w.notifyClosers()
+		}
+
+		w.drains.Wait()
+		w.notifyEmergencyClosers()
+		w.removeTempPaths()
+		w.notifyFlushers()
+	}
+
+	w.runAfterClosers()
+
+	if w.dryRun == nil && w.last != nil {
+		closeInOrder(w.clock, w.lastTimeout, []io.Closer{w.last})
+	}
+
+	w.unmarkShutdown()
+	w.removePIDFile()
+	w.Stop()
+	w.mu.Lock()
+	w.ended = w.clock.Now()
+	w.mu.Unlock()
+
+	w.mu.Lock()
+	if w.cause != nil {
+		w.err = JoinErrors(w.cause, w.err)
+	}
+	w.mu.Unlock()
+
+	w.events.emit("completed", nil, nil, w.err)
+	w.sendNotification("completed", nil, w.err)
+	w.finishNotifications()
+
+	// the process is expected to be terminated by the signal before the
+	// callers of Wait() are unblocked
+	w.mu.Lock()
+	received := w.received
+	w.mu.Unlock()
+
+	if w.reraise && received != nil {
+		reraise(received)
+	}
+
+	close(w.finished)
+
+	w.mu.Lock()
+	chans := w.doneChans
+	w.doneChans = nil
+	w.mu.Unlock()
+
+	for _, c := range chans {
+		sendDone(c, w.err)
+	}
+
+	if w.exitAfterShutdown || immediate {
+		reason, sig := w.Reason()
+		w.exit(w.exitCode(reason, sig, w.err))
+	}
+}
+
+// skipClosers marks the closers as notified without calling them, for
+// immediate shutdowns.
+func (w *watcher) skipClosers() {
+	w.mu.Lock()
+	w.shutdown = true
+	w.budgetSpent = true
+	w.mu.Unlock()
+}
+
+// addAfterClosers registers a function to be called once the closers have
+// been notified, before the callers of Wait() are unblocked; it reports false
+// if the closers have already been notified.
+func (w *watcher) addAfterClosers(f func()) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closersDone {
+		return false
+	}
+
+	w.afterClosers = append(w.afterClosers, f)
+
+	return true
+}
+
+func (w *watcher) runAfterClosers() {
+	w.mu.Lock()
+	w.closersDone = true
+	after := w.afterClosers
+	w.mu.Unlock()
+
+	for _, f := range after {
+		f()
+	}
+}
+
+// notifyClosers calls all closers once, after the drain delay and at most the
+// configured concurrency at a time, and wait for them to finish.  If not all
+// closers return within the timeout, measured from the start of the
+// notification, returns an error with the tardy closers, in the order in which
+// they were registered.
+func (w *watcher) notifyClosers() {
+	policy := w.shutdownPolicy()
+	start := w.clock.Now()
+	deadline := start.Add(policy.TimeOut)
+
+	w.mu.Lock()
+	if !w.budgetCap.IsZero() && w.budgetCap.Before(deadline) {
+		deadline = w.budgetCap
+	}
+	w.budget = deadline
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.budgetSpent = true
+		w.mu.Unlock()
+	}()
+
+	if w.budgetAlert != nil && len(w.budgetFractions) > 0 {
+		stop, stopped := make(chan struct{}), make(chan struct{})
+
+		go func() {
+			w.alertBudget(start, stop)
+			close(stopped)
+		}()
+
+		defer func() {
+			close(stop)
+			<-stopped
+		}()
+	}
+
+	if policy.DrainDelay > 0 {
+		<-w.clock.After(policy.DrainDelay)
+	}
+
+	// the budget may have been moved during the drain delay
+	w.mu.Lock()
+	deadline = w.budget
+	w.shutdown = true
+	closers := w.closers
+	w.statuses = make([]closerStatus, len(closers))
+	w.notified = len(closers)
+	w.mu.Unlock()
+
+	closers, indexes := w.closeInline(closers)
+
+	count := len(closers)
 	if count == 0 {
 		return
 	}
 
-	pending := make(map[int]holder)
-	completed := make(chan holder, count)
+	// closers share what is left of the timeout once the drain delay and the
+	// inline closers are done
+	ctx := newDeadlineContext(deadline)
 
-	for i, closer := range w.closers {
-		h := holder{key: i, closer: closer}
+	w.mu.Lock()
+	w.draining = ctx
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.draining = nil
+		w.mu.Unlock()
+	}()
+
+	// the contexts of closers that are left running are not cancelled
+	leave := false
+	defer func() {
+		if !leave {
+			ctx.cancel()
+		}
+	}()
+
+	if w.synchronous {
+		w.closeSynchronously(ctx, closers, indexes)
+		return
+	}
+
+	workers := count
+	if w.concurrency > 0 && w.concurrency < count {
+		workers = w.concurrency
+	}
+
+	// completion is tracked by index, rather than with a map and a channel of
+	// closers, to keep allocations down at shutdown
+	closed := make([]uint32, count)
+	slow := make([]uint32, count)
+	remaining := int32(count)
+	next := int32(workers)
+	all := make(chan struct{})
+
+	complete := func(i int) {
+		atomic.StoreUint32(&closed[i], 1)
+
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			close(all)
+		}
+	}
+
+	// the first closers are each handed to a worker, which then takes the
+	// remaining closers, if any, until the deadline has passed
+	shares := newShares(w.clock, ctx, closers)
+
+	work := func(i int) {
+		for {
+			status := statusIndex(indexes, i)
+
+			if w.closerTimeout > 0 {
+				w.closeWithLimit(ctx, status, closers[i], &slow[i], func() { complete(i) })
+			} else if shares != nil {
+				_ = w.closeWeighted(shares, i, status, closers[i])
+				complete(i)
+			} else {
+				_ = w.closeNotified(ctx, status, closers[i])
+				complete(i)
+			}
+
+			i = int(atomic.AddInt32(&next, 1)) - 1
+			if i >= count || ctx.Err() != nil {
+				return
+			}
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		go work(i)
+	}
+
+	if w.progress != nil {
+		stop, stopped := make(chan struct{}), make(chan struct{})
 
 		go func() {
-			_ = h.closer.Close()
-			completed <- h
+			w.showProgress(deadline, closers, closed, stop)
+			close(stopped)
 		}()
 
-		pending[i] = h
+		defer func() {
+			close(stop)
+			<-stopped
+		}()
 	}
 
-	// wait on channels for notifications
+	// a single timer bounds the whole notification, so that the timeout does
+	// not restart each time a closer completes; it is only reset when the
+	// deadline is moved
+	timer := w.clock.NewTimer(deadline.Sub(w.clock.Now()))
+	defer func() { timer.Stop() }()
+
+waiting:
 	for {
 		select {
-		case <-time.After(w.timeout):
+		case <-timer.C():
+			leave = w.timedOut(ctx)
+
 			var uncompleted []io.Closer
-			for _, h := range pending {
-				uncompleted = append(uncompleted, h.closer)
+			for i, closer := range closers {
+				if atomic.LoadUint32(&closed[i]) == 0 {
+					uncompleted = append(uncompleted, closer)
+				}
 			}
 
 			w.err = &ErrTimedOut{Uncompleted: uncompleted}
 
+			break waiting
+		case <-ctx.changed:
+			timer.Stop()
+
+			moved, _ := ctx.Deadline()
+			timer = w.clock.NewTimer(moved.Sub(w.clock.Now()))
+		case <-all:
+			break waiting
+		}
+	}
+
+	var slowest []io.Closer
+	for i, closer := range closers {
+		if atomic.LoadUint32(&slow[i]) == 1 {
+			slowest = append(slowest, closer)
+		}
+	}
+
+	if len(slowest) > 0 {
+		w.err = JoinErrors(w.err, &ErrSlowClosers{Slow: slowest})
+	}
+}
+
+// notifyEmergencyClosers calls the emergency closers concurrently and waits
+// for them, at most for their timeout.
+func (w *watcher) notifyEmergencyClosers() {
+	if len(w.emergency) == 0 {
+		return
+	}
+
+	ctx := newDeadlineContext(w.clock.Now().Add(w.emergencyTimeout))
+	defer ctx.cancel()
+
+	remaining := int32(len(w.emergency))
+	all := make(chan struct{})
+
+	for _, closer := range w.emergency {
+		go func(closer io.Closer) {
+			_ = closeWithContext(ctx, closer)
+
+			if atomic.AddInt32(&remaining, -1) == 0 {
+				close(all)
+			}
+		}(closer)
+	}
+
+	timer := w.clock.NewTimer(w.emergencyTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		ctx.expire()
+	case <-all:
+	}
+}
+
+// notifyFlushers calls the flushers one at a time and waits for them, at most
+// for their timeout; the metrics flushers are given the report of the shutdown.
+func (w *watcher) notifyFlushers() {
+	if len(w.flushers) > 0 {
+		closeInOrder(w.clock, w.flushTimeout, w.withReport(w.flushers))
+	}
+}
+
+// closeInOrder calls the closers one at a time and waits for them, at most for
+// the timeout.
+func closeInOrder(clock Clock, timeout time.Duration, closers []io.Closer) {
+	ctx := newDeadlineContext(clock.Now().Add(timeout))
+	defer ctx.cancel()
+
+	all := make(chan struct{})
+
+	go func() {
+		for _, closer := range closers {
+			if ctx.Err() != nil {
+				break
+			}
+
+			_ = closeWithContext(ctx, closer)
+		}
+
+		close(all)
+	}()
+
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		ctx.expire()
+	case <-all:
+	}
+}
+
+// closeWithLimit calls the closer, whose status has the index, with a context
+// whose deadline is the end of the per-closer timeout, or of the watcher's
+// timeout if it comes first, and calls complete once the closer has returned.
+// A closer that exceeds the per-closer timeout is flagged as slow and, if slow
+// closers are abandoned, complete is called without waiting for it to return.
+func (w *watcher) closeWithLimit(ctx *deadlineContext, status int, closer io.Closer, slow *uint32, complete func()) {
+	deadline := w.clock.Now().Add(w.closerTimeout)
+	if d, _ := ctx.Deadline(); d.Before(deadline) {
+		deadline = d
+	}
+
+	limit := newDeadlineContext(deadline)
+	done := make(chan struct{})
+
+	go func() {
+		_ = w.closeNotified(limit, status, closer)
+		close(done)
+	}()
+
+	timer := w.clock.NewTimer(w.closerTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		limit.expire()
+		<-done
+	case <-timer.C():
+		atomic.StoreUint32(slow, 1)
+		limit.expire()
+
+		if w.abandon {
+			complete()
 			return
-		case closer := <-completed:
-			delete(pending, closer.key)
-			count--
+		}
 
-			if count == 0 || len(pending) == 0 {
-				return
+		<-done
+	}
+
+	limit.cancel()
+	complete()
+}
+
+// timedOut applies the timeout policy to the closers that have not completed,
+// and reports whether they are left running with their context.
+func (w *watcher) timedOut(ctx *deadlineContext) bool {
+	w.events.emit("timedout", nil, nil, nil)
+	w.sendNotification("timedout", nil, nil)
+
+	switch w.timeoutPolicy {
+	case LeavingRunningOnTimeout:
+		return true
+	case DumpingStacksOnTimeout:
+		_ = pprof.Lookup("goroutine").WriteTo(os.Stderr, 2)
+		w.finishNotifications()
+		w.exit(1)
+	case ExitingOnTimeout:
+		w.finishNotifications()
+		w.exit(1)
+	}
+
+	ctx.expire()
+
+	return false
+}
+
+// closeSynchronously calls the closers one at a time, in registration order,
+// on the calling goroutine.  The deadline is checked after each closer: once
+// it has passed, the remaining closers are not called, and they are reported
+// with the closer that overran it.  The indexes are those of the statuses of
+// the closers, as returned by closeInline().
+func (w *watcher) closeSynchronously(ctx *deadlineContext, closers []io.Closer, indexes []int) {
+	shares := newShares(w.clock, ctx, closers)
+
+	for i, closer := range closers {
+		if shares != nil {
+			_ = w.closeWeighted(shares, i, statusIndex(indexes, i), closer)
+		} else {
+			_ = w.closeNotified(ctx, statusIndex(indexes, i), closer)
+		}
+
+		if d, _ := ctx.Deadline(); !w.clock.Now().Before(d) {
+			_ = w.timedOut(ctx)
+			w.err = &ErrTimedOut{Uncompleted: closers[i:]}
+
+			return
+		}
+	}
+}
+
+// InlineCloser is implemented by closers that complete promptly without
+// blocking, e.g. closers that only set a flag or close a channel.  The watcher
+// calls such closers, one after the other, on the notifying goroutine before
+// the other closers, rather than in a goroutine of their own; they are not
+// bounded by the timeout.
+type InlineCloser interface {
+	io.Closer
+
+	// Inline is a marker method; it is never called.
+	Inline()
+}
+
+// closeInline calls the inline closers and returns the other closers, with
+// the indexes of their statuses, which are nil if there were no inline
+// closers, so that the indexes are those of the closers.
+func (w *watcher) closeInline(closers []io.Closer) ([]io.Closer, []int) {
+	var others []io.Closer
+	var indexes []int
+
+	for i, closer := range closers {
+		if _, ok := closer.(InlineCloser); !ok {
+			if others != nil {
+				others = append(others, closer)
+				indexes = append(indexes, i)
+			}
+
+			continue
+		}
+
+		if others == nil {
+			others = append(make([]io.Closer, 0, len(closers)-1), closers[:i]...)
+			indexes = make([]int, i, len(closers)-1)
+
+			for j := range indexes {
+				indexes[j] = j
 			}
 		}
+
+		_ = w.notifyCloser(i, closer, closer.Close)
+	}
+
+	if others == nil {
+		return closers, nil
+	}
+
+	return others, indexes
+}
+
+// statusIndex returns the index of the status of the closer with the index, as
+// mapped by the indexes returned by closeInline().
+func statusIndex(indexes []int, i int) int {
+	if indexes == nil {
+		return i
 	}
+
+	return indexes[i]
+}
+
+// ContextCloser is implemented by closers that honor a deadline.  When a
+// registered closer implements this interface, CloseContext() is called instead
+// of Close() with a context whose deadline is the end of the watcher's timeout.
+type ContextCloser interface {
+	CloseContext(ctx context.Context) error
+}
+
+func closeWithContext(ctx context.Context, closer io.Closer) error {
+	if c, ok := closer.(ContextCloser); ok {
+		return c.CloseContext(ctx)
+	}
+
+	return closer.Close()
 }
 
 // FnAsCloser wraps a function in a Closer instance, called when the instance's
@@ -220,6 +1473,19 @@ func (w *fnWrapper) Close() error {
 	return nil
 }
 
+// InlineFnAsCloser wraps a function that completes promptly without blocking in
+// an InlineCloser instance, called when the instance's Close() method is called;
+// the method always returns nil.
+func InlineFnAsCloser(f func()) io.Closer {
+	return &inlineFnWrapper{fnWrapper{f: f}}
+}
+
+type inlineFnWrapper struct {
+	fnWrapper
+}
+
+func (w *inlineFnWrapper) Inline() {}
+
 // ErrValFnAsCloser wraps a function which can return an error in a Closer
 // instance, called when the instance's Close() method is called; the method's
 // value is the value returned by the function.
@@ -234,3 +1500,24 @@ type errValFnWrapper struct {
 func (w *errValFnWrapper) Close() error {
 	return w.f()
 }
+
+// ContextFnAsCloser wraps a function which honors a context deadline in a
+// Closer instance.  When notified by a watcher, the function is called with a
+// context whose deadline is the end of the watcher's timeout; when the
+// instance's Close() method is called directly, the function is called with
+// context.Background().
+func ContextFnAsCloser(f func(ctx context.Context) error) io.Closer {
+	return &contextFnWrapper{f: f}
+}
+
+type contextFnWrapper struct {
+	f func(ctx context.Context) error
+}
+
+func (w *contextFnWrapper) Close() error {
+	return w.f(context.Background())
+}
+
+func (w *contextFnWrapper) CloseContext(ctx context.Context) error {
+	return w.f(ctx)
+}
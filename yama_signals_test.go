@@ -0,0 +1,90 @@
+//go:build !js && !plan9
+// +build !js,!plan9
+
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yama_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestImmediateSignals(t *testing.T) {
+
+	Convey("Ensure immediate signals only call the emergency closers and the flushers, and exit", t, func() {
+		signals := yamatest.NewSignals()
+		closer, emergency, flusher := yamatest.CloserSpy("server"), yamatest.CloserSpy("wal"), yamatest.CloserSpy("log")
+		codes := make(chan int, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGINT, syscall.SIGQUIT),
+			yama.WithImmediateSignals(syscall.SIGQUIT),
+			yama.WithSignalSource(signals),
+			yama.WithDrainDelay(time.Hour),
+			yama.WithTimeout(2*time.Hour),
+			yama.WithEmergencyClosers(time.Second, emergency),
+			yama.WithFlushers(time.Second, flusher),
+			yama.WithExitFunc(func(code int) { codes <- code }),
+			yama.WithClosers(closer))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGQUIT), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+		So(<-codes, ShouldEqual, yama.ExitCodeOK)
+
+		So(closer.Calls(), ShouldEqual, 0)
+		So(emergency.Calls(), ShouldEqual, 1)
+		So(flusher.Calls(), ShouldEqual, 1)
+		So(watcher.AddCloser(closer), ShouldEqual, yama.ErrShutdown)
+	})
+
+	Convey("Ensure policies are validated", t, func() {
+		_, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalPolicy(syscall.SIGHUP, yama.SignalPolicy{TimeOut: time.Second}),
+			yama.WithSignalPolicy(syscall.SIGTERM, yama.SignalPolicy{DrainDelay: time.Second}))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "signal hangup of a policy must be watched")
+		So(err.Error(), ShouldContainSubstring, "timeout 0s of signal terminated must be positive")
+		So(err.Error(), ShouldContainSubstring, "drain delay 1s of signal terminated must be between zero and its timeout 0s")
+	})
+}
+
+func TestStopSignals(t *testing.T) {
+
+	Convey("Ensure Stop releases the registrations of the counted, restart, and tag signals", t, func() {
+		signals := yamatest.NewSignals()
+		watcher, err := yama.NewWatcher(
+			yama.CountingSignals(syscall.SIGHUP),
+			yama.WithRestartSignals(syscall.SIGTERM),
+			yama.ClosingTagsOn(syscall.SIGINT, "frontend"),
+			yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		watcher.Stop()
+		So(signals.Send(syscall.SIGHUP), ShouldBeFalse)
+		So(signals.Send(syscall.SIGTERM), ShouldBeFalse)
+		So(signals.Send(syscall.SIGINT), ShouldBeFalse)
+	})
+}
@@ -17,11 +17,22 @@
 package yama_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 
 	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
 )
 
 func TestHelpers(t *testing.T) {
@@ -37,6 +48,18 @@ func TestHelpers(t *testing.T) {
 		So(called, ShouldBeTrue)
 	})
 
+	Convey("Ensure wrapped inline functions are called", t, func() {
+		called := false
+		c := yama.InlineFnAsCloser(func() {
+			called = true
+		})
+
+		_ = c.Close()
+
+		So(called, ShouldBeTrue)
+		So(c, ShouldImplement, (*yama.InlineCloser)(nil))
+	})
+
 	Convey("Ensure wrapped functions that can return errors are called", t, func() {
 		called := false
 		c := yama.ErrValFnAsCloser(func() error {
@@ -48,6 +71,48 @@ func TestHelpers(t *testing.T) {
 
 		So(called, ShouldBeTrue)
 	})
+
+	Convey("Ensure wrapped functions that honor a deadline are called", t, func() {
+		var received context.Context
+		c := yama.ContextFnAsCloser(func(ctx context.Context) error {
+			received = ctx
+			return nil
+		})
+
+		_ = c.Close()
+		So(received, ShouldNotBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		_ = c.(yama.ContextCloser).CloseContext(ctx)
+		So(received, ShouldEqual, ctx)
+	})
+}
+
+func TestJoinErrors(t *testing.T) {
+
+	Convey("Ensure nil errors are dropped", t, func() {
+		So(yama.JoinErrors(), ShouldBeNil)
+		So(yama.JoinErrors(nil, nil), ShouldBeNil)
+
+		err := errors.New("only")
+		So(yama.JoinErrors(nil, err, nil), ShouldEqual, err)
+	})
+
+	Convey("Ensure several errors are aggregated", t, func() {
+		first := errors.New("first")
+		timedOut := &yama.ErrTimedOut{}
+
+		err := yama.JoinErrors(first, timedOut)
+		So(err, ShouldHaveSameTypeAs, &yama.ErrMultiple{})
+		So(err.Error(), ShouldEqual, "first; closers timed out")
+		So(errors.Is(err, first), ShouldBeTrue)
+
+		var target *yama.ErrTimedOut
+		So(errors.As(err, &target), ShouldBeTrue)
+		So(target, ShouldEqual, timedOut)
+	})
 }
 
 func TestNewWatcher(t *testing.T) {
@@ -59,4 +124,1190 @@ func TestNewWatcher(t *testing.T) {
 		So(err.Error(), ShouldEqual, "closer #1 must not be null")
 	})
 
+	Convey("Ensure that a nil signal source cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithSignalSource(nil))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "signal source must not be null")
+	})
+
+	Convey("Ensure that a nil clock cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithClock(nil))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "clock must not be null")
+	})
+
+	Convey("Ensure that a nil exit function cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithExitFunc(nil))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "exit function must not be null")
+	})
+
+	Convey("Ensure that a drain delay beyond the timeout cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithTimeout(time.Second), yama.WithDrainDelay(time.Second))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "drain delay 1s must be between zero and the timeout 1s")
+	})
+
+	Convey("Ensure that a negative concurrency cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithConcurrency(-1))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "concurrency -1 must not be negative")
+	})
+
+	Convey("Ensure that a non-positive timeout cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithTimeout(0))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "timeout 0s must be positive")
+	})
+
+	Convey("Ensure that signals cannot be watched more than once", t, func() {
+		_, err := yama.NewWatcher(yama.WatchingSignals(os.Interrupt, os.Interrupt))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "signal interrupt must not be watched more than once")
+	})
+
+	Convey("Ensure that nil options cannot be passed in", t, func() {
+		_, err := yama.NewWatcher(yama.WithTimeout(time.Second), nil)
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "option #1 must not be null")
+	})
+
+	Convey("Ensure that closers cannot be registered more than once", t, func() {
+		c := yama.FnAsCloser(func() {})
+
+		_, err := yama.NewWatcher(yama.WithClosers(c, yama.FnAsCloser(func() {}), c))
+		So(err, ShouldBeError)
+		So(errors.Is(err, yama.ErrDuplicateCloser), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "closer #2 is closer #0: closer already registered")
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(c))
+		So(err, ShouldBeNil)
+		So(watcher.AddCloser(c), ShouldEqual, yama.ErrDuplicateCloser)
+		So(watcher.Close(), ShouldBeNil)
+	})
+
+	Convey("Ensure that duplicate closers can be allowed", t, func() {
+		var calls int32
+		c := yama.FnAsCloser(func() { atomic.AddInt32(&calls, 1) })
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(c, c), yama.AllowingDuplicateClosers())
+		So(err, ShouldBeNil)
+		So(watcher.AddCloser(c), ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+		So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+	})
+
+	Convey("Ensure that all the problems of the settings are reported", t, func() {
+		_, err := yama.NewWatcher(yama.WithClock(nil), yama.WithConcurrency(-1))
+		So(err, ShouldHaveSameTypeAs, &yama.ErrMultiple{})
+		So(err.Error(), ShouldEqual, "clock must not be null; concurrency -1 must not be negative")
+	})
+
+	Convey("Ensure that the errors of the options are reported with the other problems", t, func() {
+		_, err := yama.NewWatcher(nil, yama.WithProfile("nope"), yama.WithProfile("nada"), yama.WithConcurrency(-1))
+		So(err, ShouldHaveSameTypeAs, &yama.ErrMultiple{})
+		So(err.Error(), ShouldEqual, `option #0 must not be null; unknown profile "nope"; unknown profile "nada"; `+
+			"concurrency -1 must not be negative")
+	})
+
+	Convey("Ensure that watchers without signals do not register for them", t, func() {
+		signals := yamatest.NewSignals()
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(
+			yama.WithSignalSource(signals),
+			yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(os.Interrupt), ShouldBeFalse)
+		watcher.Simulate(os.Interrupt)
+		time.Sleep(10 * time.Millisecond)
+		So(closers.Invoked(), ShouldBeEmpty)
+
+		So(watcher.Close(), ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+
+	Convey("Ensure that added closers are notified", t, func() {
+		called := false
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		err = watcher.AddCloser(yama.FnAsCloser(func() { called = true }))
+		So(err, ShouldBeNil)
+
+		So(watcher.AddCloser(nil), ShouldBeError)
+
+		_ = watcher.Close()
+		So(called, ShouldBeTrue)
+
+		err = watcher.AddCloser(yama.FnAsCloser(func() {}))
+		So(err, ShouldEqual, yama.ErrShutdown)
+	})
+
+	Convey("Ensure that removed closers are not notified", t, func() {
+		closers := yamatest.NewClosers()
+		db, conn := closers.Closer("db"), closers.Closer("conn")
+		watcher, err := yama.NewWatcher(yama.WithClosers(db))
+		So(err, ShouldBeNil)
+
+		So(watcher.AddCloser(conn), ShouldBeNil)
+		So(watcher.RemoveCloser(conn), ShouldBeNil)
+		So(watcher.RemoveCloser(conn), ShouldEqual, yama.ErrUnknownCloser)
+		So(watcher.RemoveCloser(nil), ShouldBeError)
+
+		_ = watcher.Close()
+		yamatest.AssertInvoked(t, closers, "db")
+
+		So(watcher.RemoveCloser(db), ShouldEqual, yama.ErrShutdown)
+	})
+
+	Convey("Ensure that context closers receive the timeout deadline", t, func() {
+		var deadline time.Time
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(time.Minute),
+			yama.WithClosers(yama.ContextFnAsCloser(func(ctx context.Context) error {
+				deadline, _ = ctx.Deadline()
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		start := time.Now()
+		_ = watcher.Close()
+		So(deadline, ShouldHappenWithin, time.Second, start.Add(time.Minute))
+	})
+
+	Convey("Ensure that watchers can be created from cloned settings", t, func() {
+		base := &yama.Settings{}
+		yama.WatchingSignals(syscall.SIGTERM).Apply(base)
+		yama.WithClosers(yamatest.CloserSpy("db")).Apply(base)
+
+		s := base.Clone()
+		yama.WithTimeout(time.Minute).Apply(s)
+		yama.WithClosers(yamatest.CloserSpy("db"), yamatest.CloserSpy("cache")).Apply(s)
+		s.Signals[0] = syscall.SIGINT
+
+		So(base.Signals, ShouldResemble, []os.Signal{syscall.SIGTERM})
+		So(base.Closers, ShouldHaveLength, 1)
+		So(base.TimeOut, ShouldEqual, 0)
+
+		watcher, err := yama.NewWatcherFromSettings(s)
+		So(err, ShouldBeNil)
+		So(watcher.Status().Closers, ShouldHaveLength, 2)
+		So(watcher.Remaining(), ShouldEqual, time.Minute)
+		watcher.Stop()
+
+		watcher, err = yama.NewWatcherFromSettings(base)
+		So(err, ShouldBeNil)
+		So(watcher.Remaining(), ShouldEqual, yama.DefaultTimeout)
+		watcher.Stop()
+
+		_, err = yama.NewWatcherFromSettings(nil)
+		So(err, ShouldBeError, "settings must not be null")
+	})
+}
+
+func TestClock(t *testing.T) {
+
+	Convey("Ensure the timeout follows the watcher's clock", t, func() {
+		clock := yamatest.NewClock(time.Now())
+
+		deadlines := make(chan time.Time, 1)
+		ctxErr := make(chan error, 1)
+		release := make(chan struct{})
+		defer close(release)
+
+		slow := yama.ContextFnAsCloser(func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			deadlines <- deadline
+			<-ctx.Done()
+			ctxErr <- ctx.Err()
+			<-release
+			return nil
+		})
+
+		watcher, werr := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Hour),
+			yama.WithClosers(slow))
+		So(werr, ShouldBeNil)
+
+		start := clock.Now()
+		observed := make(chan time.Time, 1)
+		go func() {
+			clock.BlockUntil(1)
+			observed <- <-deadlines
+			clock.Advance(time.Hour)
+		}()
+
+		werr = watcher.Close()
+		So(werr, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(<-observed, ShouldEqual, start.Add(time.Hour))
+		So(errors.Is(<-ctxErr, context.DeadlineExceeded), ShouldBeTrue)
+	})
+}
+
+func TestTimeout(t *testing.T) {
+
+	Convey("Ensure the timeout does not restart when closers complete", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		release := make(chan struct{})
+		completed := make(chan struct{})
+		fast := yama.FnAsCloser(func() {
+			<-release
+			close(completed)
+		})
+		slow := yamatest.CloserSpy("slow").WithClock(clock).WithDelay(time.Hour)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.WithClosers(fast, slow))
+		So(err, ShouldBeNil)
+
+		go func() {
+			clock.BlockUntil(2)
+			clock.Advance(30 * time.Second)
+			close(release)
+			<-completed
+			time.Sleep(10 * time.Millisecond) // let the watcher observe the completion
+			clock.Advance(30 * time.Second)
+		}()
+
+		err = watcher.Close()
+		So(err, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(err.(*yama.ErrTimedOut).Uncompleted, ShouldResemble, []io.Closer{slow})
+	})
+}
+
+func TestInline(t *testing.T) {
+
+	Convey("Ensure inline closers are called before the other closers", t, func() {
+		closers := yamatest.NewClosers()
+		var order []string
+
+		watcher, err := yama.NewWatcher(yama.WithClosers(
+			closers.Closer("server"),
+			yama.InlineFnAsCloser(func() { order = append(order, "flag") }),
+			yama.InlineFnAsCloser(func() { order = append(order, "channel") })))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(order, ShouldResemble, []string{"flag", "channel"})
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+
+	Convey("Ensure watchers with only inline closers do not start a timer", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		called := false
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithClosers(yama.InlineFnAsCloser(func() { called = true })))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(called, ShouldBeTrue)
+		So(clock.Timers(), ShouldEqual, 0)
+	})
+}
+
+func TestDrainDelay(t *testing.T) {
+
+	Convey("Ensure closers are called with what is left of the timeout after the drain delay", t, func() {
+		start := time.Now()
+		clock := yamatest.NewClock(start)
+		spy := yamatest.CloserSpy("server").WithClock(clock)
+		deadlines := make(chan time.Time, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.WithDrainDelay(10*time.Second),
+			yama.WithClosers(spy, yama.ContextFnAsCloser(func(ctx context.Context) error {
+				deadline, _ := ctx.Deadline()
+				deadlines <- deadline
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		go func() {
+			clock.BlockUntil(1)
+			clock.Advance(10 * time.Second)
+		}()
+
+		So(watcher.Close(), ShouldBeNil)
+		So(<-deadlines, ShouldEqual, start.Add(time.Minute))
+		So(spy.Invocations()[0].Time, ShouldEqual, start.Add(10*time.Second))
+	})
+}
+
+func TestRemaining(t *testing.T) {
+
+	Convey("Ensure the remaining budget shrinks as the shutdown proceeds", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		remaining := make(chan time.Duration, 2)
+
+		var watcher *yama.Watcher
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.WithDrainDelay(10*time.Second),
+			yama.WithClosers(yama.FnAsCloser(func() {
+				remaining <- watcher.Remaining()
+				clock.Advance(20 * time.Second)
+				remaining <- watcher.Remaining()
+			})))
+		So(err, ShouldBeNil)
+		So(watcher.Remaining(), ShouldEqual, time.Minute)
+
+		go func() {
+			clock.BlockUntil(1)
+			clock.Advance(10 * time.Second)
+		}()
+
+		So(watcher.Close(), ShouldBeNil)
+		So(<-remaining, ShouldEqual, 50*time.Second)
+		So(<-remaining, ShouldEqual, 30*time.Second)
+		So(watcher.Remaining(), ShouldEqual, 0)
+	})
+}
+
+func TestSignalPolicies(t *testing.T) {
+
+	Convey("Ensure the policy of the signal that triggered the shutdown applies", t, func() {
+		start := time.Now()
+		clock := yamatest.NewClock(start)
+		signals := yamatest.NewSignals()
+		spy := yamatest.CloserSpy("server").WithClock(clock)
+		deadlines := make(chan time.Time, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGINT, syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.WithSignalPolicy(syscall.SIGINT, yama.SignalPolicy{TimeOut: 2 * time.Second}),
+			yama.WithSignalPolicy(syscall.SIGTERM, yama.SignalPolicy{TimeOut: 30 * time.Second, DrainDelay: 10 * time.Second}),
+			yama.WithClosers(spy, yama.ContextFnAsCloser(func(ctx context.Context) error {
+				deadline, _ := ctx.Deadline()
+				deadlines <- deadline
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		go func() {
+			clock.BlockUntil(1)
+			clock.Advance(10 * time.Second)
+		}()
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+		So(<-deadlines, ShouldEqual, start.Add(30*time.Second))
+		So(spy.Invocations()[0].Time, ShouldEqual, start.Add(10*time.Second))
+	})
+
+	Convey("Ensure signals without a policy follow the settings of the watcher", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		signals := yamatest.NewSignals()
+		deadlines := make(chan time.Time, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGINT, syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.WithSignalPolicy(syscall.SIGINT, yama.SignalPolicy{TimeOut: 2 * time.Second}),
+			yama.WithClosers(yama.ContextFnAsCloser(func(ctx context.Context) error {
+				deadline, _ := ctx.Deadline()
+				deadlines <- deadline
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		start := clock.Now()
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+		So(<-deadlines, ShouldEqual, start.Add(time.Minute))
+	})
+
+}
+
+func TestConcurrency(t *testing.T) {
+
+	Convey("Ensure no more closers than the concurrency are called at once", t, func() {
+		var mu sync.Mutex
+		running, most := 0, 0
+
+		var closers []io.Closer
+		for i := 0; i < 10; i++ {
+			closers = append(closers, yama.FnAsCloser(func() {
+				mu.Lock()
+				running++
+				if running > most {
+					most = running
+				}
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}))
+		}
+
+		watcher, err := yama.NewWatcher(
+			yama.WithConcurrency(3),
+			yama.WithClosers(closers...))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(most, ShouldBeBetweenOrEqual, 1, 3)
+	})
+
+	Convey("Ensure closers not called by the timeout are reported", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		slow := yamatest.CloserSpy("slow").WithClock(clock).WithDelay(time.Hour)
+		queued := yamatest.CloserSpy("queued")
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.WithConcurrency(1),
+			yama.WithClosers(slow, queued))
+		So(err, ShouldBeNil)
+
+		go func() {
+			clock.BlockUntil(2)
+			clock.Advance(time.Minute)
+		}()
+
+		err = watcher.Close()
+		So(err, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(err.(*yama.ErrTimedOut).Uncompleted, ShouldResemble, []io.Closer{slow, queued})
+		So(queued, yamatest.ShouldNotHaveBeenClosed)
+	})
+}
+
+func TestCloserTimeout(t *testing.T) {
+
+	Convey("Ensure closers exceeding the closer timeout are reported", t, func() {
+		slow := yama.ContextFnAsCloser(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		fast := yamatest.CloserSpy("fast")
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(time.Minute),
+			yama.WithCloserTimeout(10*time.Millisecond),
+			yama.WithClosers(slow, fast))
+		So(err, ShouldBeNil)
+
+		err = watcher.Close()
+		So(err, ShouldHaveSameTypeAs, &yama.ErrSlowClosers{})
+		So(err.(*yama.ErrSlowClosers).Slow, ShouldResemble, []io.Closer{slow})
+		So(fast, yamatest.ShouldHaveBeenClosed, 1)
+	})
+
+	Convey("Ensure slow closers can be abandoned", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		stuck := yama.FnAsCloser(func() { <-release })
+		queued := yamatest.CloserSpy("queued")
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(time.Minute),
+			yama.WithCloserTimeout(10*time.Millisecond),
+			yama.AbandoningSlowClosers(),
+			yama.WithConcurrency(1),
+			yama.WithClosers(stuck, queued))
+		So(err, ShouldBeNil)
+
+		err = watcher.Close()
+		So(err, ShouldHaveSameTypeAs, &yama.ErrSlowClosers{})
+		So(err.(*yama.ErrSlowClosers).Slow, ShouldResemble, []io.Closer{stuck})
+		So(queued, yamatest.ShouldHaveBeenClosed, 1)
+	})
+
+	Convey("Ensure slow closers that time out are reported by both errors", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		stuck := yama.FnAsCloser(func() { <-release })
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(20*time.Millisecond),
+			yama.WithCloserTimeout(10*time.Millisecond),
+			yama.WithClosers(stuck))
+		So(err, ShouldBeNil)
+
+		err = watcher.Close()
+
+		var timedOut *yama.ErrTimedOut
+		So(errors.As(err, &timedOut), ShouldBeTrue)
+		So(timedOut.Uncompleted, ShouldResemble, []io.Closer{stuck})
+
+		var slow *yama.ErrSlowClosers
+		So(errors.As(err, &slow), ShouldBeTrue)
+		So(slow.Slow, ShouldResemble, []io.Closer{stuck})
+	})
+
+	Convey("Ensure the closer timeout cannot exceed the timeout", t, func() {
+		_, err := yama.NewWatcher(yama.WithTimeout(time.Second), yama.WithCloserTimeout(time.Minute))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "closer timeout 1m0s must be between zero and the timeout 1s")
+	})
+}
+
+func TestTimeoutPolicy(t *testing.T) {
+
+	Convey("Ensure closers can be left running with their context", t, func() {
+		release := make(chan struct{})
+		cancelled := make(chan bool, 1)
+
+		stuck := yama.ContextFnAsCloser(func(ctx context.Context) error {
+			<-release
+			cancelled <- ctx.Err() != nil
+			return nil
+		})
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithTimeoutPolicy(yama.LeavingRunningOnTimeout),
+			yama.WithClosers(stuck))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+
+		close(release)
+		So(<-cancelled, ShouldBeFalse)
+	})
+
+	Convey("Ensure the process can exit on timeout", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		code := -1
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithTimeoutPolicy(yama.ExitingOnTimeout),
+			yama.WithExitFunc(func(c int) { code = c }),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(code, ShouldEqual, 1)
+	})
+}
+
+func TestEmergencyClosers(t *testing.T) {
+
+	Convey("Ensure emergency closers are called after the timeout", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		stuck := yama.FnAsCloser(func() { <-release })
+		crashLog := yamatest.CloserSpy("crash log")
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithClosers(stuck),
+			yama.WithEmergencyClosers(time.Second, crashLog))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(crashLog, yamatest.ShouldHaveBeenClosed, 1)
+	})
+
+	Convey("Ensure emergency closers are bounded by their own timeout", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		stuck := yama.FnAsCloser(func() { <-release })
+		emergency := yamatest.CloserSpy("emergency")
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClosers(yamatest.CloserSpy("db")),
+			yama.WithEmergencyClosers(10*time.Millisecond, stuck, emergency))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(emergency, yamatest.ShouldHaveBeenClosed, 1)
+	})
+
+	Convey("Ensure emergency closers have a timeout", t, func() {
+		_, err := yama.NewWatcher(yama.WithEmergencyClosers(0, yamatest.CloserSpy("log")))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "emergency timeout 0s must be positive")
+	})
+}
+
+func TestFlushers(t *testing.T) {
+
+	Convey("Ensure flushers are called in order after all the other closers", t, func() {
+		var mu sync.Mutex
+		var calls []string
+
+		record := func(name string) io.Closer {
+			return yama.FnAsCloser(func() {
+				mu.Lock()
+				calls = append(calls, name)
+				mu.Unlock()
+			})
+		}
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClosers(record("db")),
+			yama.WithEmergencyClosers(time.Second, record("crash log")),
+			yama.WithFlushers(time.Second, record("exporter"), record("logger")))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(calls, ShouldResemble, []string{"db", "crash log", "exporter", "logger"})
+	})
+
+	Convey("Ensure flushers are bounded by their own timeout", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		stuck := yama.FnAsCloser(func() { <-release })
+		logger := yamatest.CloserSpy("logger")
+
+		watcher, err := yama.NewWatcher(yama.WithFlushers(10*time.Millisecond, stuck, logger))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(logger, yamatest.ShouldHaveBeenClosed, 0)
+	})
+
+	Convey("Ensure flushers have a timeout", t, func() {
+		_, err := yama.NewWatcher(yama.WithFlushers(0, yamatest.CloserSpy("logger")))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "flush timeout 0s must be positive")
+	})
+}
+
+func TestLastCloser(t *testing.T) {
+
+	Convey("Ensure the last closer is called after the flushers", t, func() {
+		var mu sync.Mutex
+		var calls []string
+
+		record := func(name string) io.Closer {
+			return yama.FnAsCloser(func() {
+				mu.Lock()
+				calls = append(calls, name)
+				mu.Unlock()
+			})
+		}
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClosers(record("db")),
+			yama.WithFlushers(time.Second, record("logger")),
+			yama.WithLastCloser(time.Second, record("debug server")))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(calls, ShouldResemble, []string{"db", "logger", "debug server"})
+	})
+
+	Convey("Ensure the last closer is left running once the other closers time out", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		debug := make(chan error, 1)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithTimeout(10*time.Millisecond),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })),
+			yama.WithLastCloser(time.Second, yama.ContextFnAsCloser(func(ctx context.Context) error {
+				debug <- ctx.Err()
+				return nil
+			})))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(<-debug, ShouldBeNil)
+	})
+
+	Convey("Ensure the last closer has a timeout", t, func() {
+		_, err := yama.NewWatcher(yama.WithLastCloser(0, yamatest.CloserSpy("debug server")))
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldEqual, "last closer timeout 0s must be positive")
+	})
+}
+
+func TestClosingSynchronously(t *testing.T) {
+
+	Convey("Ensure closers are called in order on the notifying goroutine", t, func() {
+		var order []string
+		closer := func(name string) io.Closer {
+			return yama.FnAsCloser(func() { order = append(order, name) })
+		}
+
+		watcher, err := yama.NewWatcher(
+			yama.ClosingSynchronously(),
+			yama.WithClosers(closer("server"), closer("db"), closer("cache")))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(order, ShouldResemble, []string{"server", "db", "cache"})
+	})
+
+	Convey("Ensure closers are not called once the timeout has passed", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		fast := yamatest.CloserSpy("fast")
+		slow := yama.FnAsCloser(func() { clock.Advance(time.Minute) })
+		queued := yamatest.CloserSpy("queued")
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.ClosingSynchronously(),
+			yama.WithClosers(fast, slow, queued))
+		So(err, ShouldBeNil)
+
+		err = watcher.Close()
+		So(err, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(err.(*yama.ErrTimedOut).Uncompleted, ShouldResemble, []io.Closer{slow, queued})
+		So(fast, yamatest.ShouldHaveBeenClosed, 1)
+		So(queued, yamatest.ShouldNotHaveBeenClosed)
+	})
+}
+
+func TestSimulate(t *testing.T) {
+
+	Convey("Ensure simulated signals trigger the watcher", t, func() {
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(syscall.SIGTERM)
+
+		err = watcher.Wait()
+		So(err, ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+
+	Convey("Ensure simulated signals that are not watched are ignored", t, func() {
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(os.Interrupt)
+		time.Sleep(10 * time.Millisecond)
+		So(closers.Invoked(), ShouldBeEmpty)
+
+		_ = watcher.Close()
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+}
+
+func TestSignalEcho(t *testing.T) {
+
+	Convey("Ensure a note is written when a signal triggers the watcher", t, func() {
+		out := &bytes.Buffer{}
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithClock(yamatest.NewClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))),
+			yama.WithTimeout(30*time.Second),
+			yama.WithSignalEcho(out))
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(syscall.SIGTERM)
+
+		So(watcher.Wait(), ShouldBeNil)
+		So(out.String(), ShouldEqual,
+			"2021-01-01T00:00:00Z received SIGTERM, beginning graceful shutdown (budget 30s)\n")
+	})
+
+	Convey("Ensure no note is written when the watcher is closed", t, func() {
+		out := &bytes.Buffer{}
+		watcher, err := yama.NewWatcher(yama.WithSignalEcho(out))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(out.String(), ShouldBeEmpty)
+	})
+}
+
+func TestCoalescingSignals(t *testing.T) {
+
+	Convey("Ensure bursts of repeated signals are coalesced, and counted", t, func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		signals := yamatest.NewSignals()
+		clock := yamatest.NewClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		codes := make(chan int, 1)
+		running := make(chan struct{})
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithClock(clock),
+			yama.CoalescingSignals(100*time.Millisecond),
+			yama.WithEscalation(yama.ExitingOnRepeat),
+			yama.WithExitFunc(func(code int) { codes <- code }),
+			yama.WithClosers(yama.FnAsCloser(func() {
+				close(running)
+				<-release
+			})))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		<-running
+
+		for i := 1; i <= 2; i++ {
+			clock.Advance(50 * time.Millisecond)
+			So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+
+			for watcher.Coalesced() < i {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		So(watcher.Status().Coalesced, ShouldEqual, 2)
+
+		clock.Advance(100 * time.Millisecond)
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(<-codes, ShouldEqual, 1)
+		So(watcher.Coalesced(), ShouldEqual, 2)
+	})
+}
+
+func TestInterruptHint(t *testing.T) {
+
+	Convey("Ensure the hint is written on interrupts", t, func() {
+		out := &bytes.Buffer{}
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithEscalation(yama.ExitingOnRepeat),
+			yama.WithInterruptHint(out, ""))
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(os.Interrupt)
+
+		So(watcher.Wait(), ShouldBeNil)
+		So(out.String(), ShouldEqual, yama.DefaultInterruptHint+"\n")
+	})
+
+	Convey("Ensure the hint is not written when another interrupt does not exit", t, func() {
+		out := &bytes.Buffer{}
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithInterruptHint(out, ""))
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(os.Interrupt)
+
+		So(watcher.Wait(), ShouldBeNil)
+		So(out.String(), ShouldBeEmpty)
+	})
+
+	Convey("Ensure the hint is not written on other signals", t, func() {
+		out := &bytes.Buffer{}
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(yamatest.NewSignals()),
+			yama.WithEscalation(yama.ExitingOnRepeat),
+			yama.WithInterruptHint(out, "press Ctrl-C again to quit"))
+		So(err, ShouldBeNil)
+
+		watcher.Simulate(syscall.SIGTERM)
+
+		So(watcher.Wait(), ShouldBeNil)
+		So(out.String(), ShouldBeEmpty)
+	})
+}
+
+func TestWait(t *testing.T) {
+
+	Convey("Ensure concurrent waiters all receive the same result", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		release := make(chan struct{})
+		defer close(release)
+
+		slow := yama.FnAsCloser(func() { <-release })
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Second),
+			yama.WithClosers(slow))
+		So(err, ShouldBeNil)
+
+		const waiters = 10
+		results := make(chan error, waiters)
+
+		var started sync.WaitGroup
+		started.Add(waiters)
+
+		for i := 0; i < waiters; i++ {
+			go func() {
+				started.Done()
+				results <- watcher.Wait()
+			}()
+		}
+
+		started.Wait()
+		go func() { _ = watcher.Close() }()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+
+		first := <-results
+		So(first, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+
+		for i := 1; i < waiters; i++ {
+			So(<-results, ShouldEqual, first)
+		}
+	})
+}
+
+func TestContext(t *testing.T) {
+
+	Convey("Ensure the context is cancelled when a signal occurs, before the closers complete", t, func() {
+		release := make(chan struct{})
+		signals := yamatest.NewSignals()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })))
+		So(err, ShouldBeNil)
+
+		ctx := watcher.Context()
+		So(ctx.Err(), ShouldBeNil)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		<-ctx.Done()
+		So(ctx.Err(), ShouldEqual, context.Canceled)
+
+		close(release)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure the context is cancelled when the watcher is closed, but not when it is stopped", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		watcher.Stop()
+		So(watcher.Context().Err(), ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(watcher.Context().Err(), ShouldEqual, context.Canceled)
+	})
+}
+
+func TestDone(t *testing.T) {
+
+	Convey("Ensure the channel is closed once the shutdown is triggered", t, func() {
+		release := make(chan struct{})
+		watcher, err := yama.NewWatcher(yama.WithClosers(yama.FnAsCloser(func() { <-release })))
+		So(err, ShouldBeNil)
+
+		select {
+		case <-watcher.Done():
+			t.Error("done before the shutdown was triggered")
+		default:
+		}
+
+		go func() { _ = watcher.Close() }()
+
+		<-watcher.Done()
+		close(release)
+		So(watcher.Wait(), ShouldBeNil)
+	})
+}
+
+func TestNotifyDone(t *testing.T) {
+
+	Convey("Ensure the result is sent to all the channels", t, func() {
+		slow := yama.FnAsCloser(func() { time.Sleep(time.Second) })
+		watcher, err := yama.NewWatcher(yama.WithTimeout(10*time.Millisecond), yama.WithClosers(slow))
+		So(err, ShouldBeNil)
+
+		first, second := make(chan error, 1), make(chan error, 1)
+		watcher.NotifyDone(first)
+		watcher.NotifyDone(second)
+
+		So(watcher.Close(), ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(<-first, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(<-second, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+	})
+
+	Convey("Ensure the result is sent right away once the closers are notified", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+
+		done := make(chan error, 1)
+		watcher.NotifyDone(done)
+		So(<-done, ShouldBeNil)
+	})
+
+	Convey("Ensure channels that are not ready are skipped", t, func() {
+		watcher, err := yama.NewWatcher()
+		So(err, ShouldBeNil)
+
+		watcher.NotifyDone(make(chan error))
+		So(watcher.Close(), ShouldBeNil)
+		So(func() { watcher.NotifyDone(nil) }, ShouldPanic)
+	})
+}
+
+func TestClose(t *testing.T) {
+
+	Convey("Ensure repeated closes do not block after a signal", t, func() {
+		signals := yamatest.NewSignals()
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(signals),
+			yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(os.Interrupt), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(watcher.Close(), ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+
+	Convey("Ensure concurrent closes notify the closers once", t, func() {
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		const callers = 10
+		results := make(chan error, callers)
+
+		for i := 0; i < callers; i++ {
+			go func() { results <- watcher.Close() }()
+		}
+
+		for i := 0; i < callers; i++ {
+			So(<-results, ShouldBeNil)
+		}
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+}
+
+// stoppingSignals reports when the watcher stops watching the signals.
+type stoppingSignals struct {
+	*yamatest.Signals
+	stopped chan struct{}
+}
+
+func (s *stoppingSignals) Stop(c chan<- os.Signal) {
+	s.Signals.Stop(c)
+	close(s.stopped)
+}
+
+func TestStop(t *testing.T) {
+
+	Convey("Ensure the signal registration is released after shutdown", t, func() {
+		signals := yamatest.NewSignals()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+		So(signals.Send(os.Interrupt), ShouldBeFalse)
+	})
+
+	Convey("Ensure abandoned watchers stop watching signals", t, func() {
+		signals := &stoppingSignals{Signals: yamatest.NewSignals(), stopped: make(chan struct{})}
+		_, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(signals))
+		So(err, ShouldBeNil)
+
+		released := false
+		for i := 0; i < 100 && !released; i++ {
+			runtime.GC()
+
+			select {
+			case <-signals.stopped:
+				released = true
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		So(released, ShouldBeTrue)
+	})
+
+	Convey("Ensure stopped watchers ignore signals but can be closed", t, func() {
+		signals := yamatest.NewSignals()
+		closers := yamatest.NewClosers()
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(os.Interrupt),
+			yama.WithSignalSource(signals),
+			yama.WithClosers(closers.Closer("server")))
+		So(err, ShouldBeNil)
+
+		watcher.Stop()
+		watcher.Stop()
+		So(signals.Send(os.Interrupt), ShouldBeFalse)
+		So(closers.Invoked(), ShouldBeEmpty)
+
+		So(watcher.Close(), ShouldBeNil)
+		yamatest.AssertInvoked(t, closers, "server")
+	})
+}
+
+func TestExitingAfterShutdown(t *testing.T) {
+
+	Convey("Ensure the process exits cleanly after shutdown", t, func() {
+		codes := make(chan int, 1)
+		watcher, err := yama.NewWatcher(
+			yama.ExitingAfterShutdown(),
+			yama.WithExitFunc(func(code int) { codes <- code }))
+		So(err, ShouldBeNil)
+
+		_ = watcher.Close()
+		So(<-codes, ShouldEqual, 0)
+	})
+
+	Convey("Ensure the process exits with an error when closers time out", t, func() {
+		clock := yamatest.NewClock(time.Now())
+		release := make(chan struct{})
+		defer close(release)
+
+		codes := make(chan int, 1)
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithClosers(yama.FnAsCloser(func() { <-release })),
+			yama.ExitingAfterShutdown(),
+			yama.WithExitFunc(func(code int) { codes <- code }))
+		So(err, ShouldBeNil)
+
+		go func() {
+			clock.BlockUntil(1)
+			clock.Advance(yama.DefaultTimeout)
+		}()
+
+		_ = watcher.Close()
+		So(<-codes, ShouldEqual, 1)
+	})
+
+	Convey("Ensure the process does not exit by default", t, func() {
+		exited := false
+		watcher, err := yama.NewWatcher(yama.WithExitFunc(func(int) { exited = true }))
+		So(err, ShouldBeNil)
+
+		_ = watcher.Close()
+		So(exited, ShouldBeFalse)
+	})
 }
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"os/signal"
 	"sync"
 	"syscall"
 	"testing"
@@ -30,6 +32,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
 )
 
 type Unhashable map[string]interface{}
@@ -77,6 +80,7 @@ func (c *BadCloser) Close() error {
 func TestYama(t *testing.T) {
 
 	Convey("Validate watcher handles unhashable types", t, func() {
+		uCalled = 0
 		u := make(Unhashable)
 		watcher, err := yama.NewWatcher(
 			yama.WatchingSignals(syscall.SIGTERM),
@@ -185,7 +189,14 @@ func TestYama(t *testing.T) {
 		So(err, ShouldBeNil)
 		So(closeMe.Closed, ShouldEqual, 1)
 
+		// the watcher released its registration once the closers were
+		// notified, so keep the process from being hung up
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
 		_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		<-hup
 		err = watcher.Wait()
 		So(err, ShouldBeNil)
 		So(closeMe.Closed, ShouldEqual, 1)
@@ -249,4 +260,138 @@ func TestYama(t *testing.T) {
 		So(neverClose.Closed, ShouldEqual, 1)
 		So(closeMe.Closed, ShouldEqual, 1)
 	})
+
+	Convey("Validate every watcher observes a shared signal", t, func() {
+		first, second := &CloseMe{}, &CloseMe{}
+		watcher1, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGHUP),
+			yama.WithClosers(first))
+		So(err, ShouldBeNil)
+
+		watcher2, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGHUP, syscall.SIGTERM),
+			yama.WithClosers(second))
+		So(err, ShouldBeNil)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		}()
+
+		So(watcher1.Wait(), ShouldBeNil)
+		So(watcher2.Wait(), ShouldBeNil)
+		So(first.Closed, ShouldEqual, 1)
+		So(second.Closed, ShouldEqual, 1)
+	})
+
+	Convey("Validate stopped watchers do not affect other watchers", t, func() {
+		stopped, watching := &CloseMe{}, &CloseMe{}
+		watcher1, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGHUP),
+			yama.WithClosers(stopped))
+		So(err, ShouldBeNil)
+
+		watcher2, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGHUP),
+			yama.WithClosers(watching))
+		So(err, ShouldBeNil)
+
+		watcher1.Stop()
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		}()
+
+		So(watcher2.Wait(), ShouldBeNil)
+		So(watching.Closed, ShouldEqual, 1)
+		So(stopped.Closed, ShouldEqual, 0)
+	})
+}
+
+func TestDispatcher(t *testing.T) {
+
+	Convey("Ensure the dispatcher buffers one of each distinct signal", t, func() {
+		watcher, err := yama.NewWatcher(yama.WatchingSignals(syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2))
+		So(err, ShouldBeNil)
+		defer watcher.Stop()
+
+		So(yama.DispatchCapacity(), ShouldBeGreaterThanOrEqualTo, 3)
+	})
+}
+
+func TestForwardingSignals(t *testing.T) {
+
+	Convey("Ensure signals are forwarded to processes", t, func() {
+		signals := yamatest.NewSignals()
+		cmd := exec.Command("sleep", "60")
+		So(cmd.Start(), ShouldBeNil)
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.ForwardingSignals(cmd.Process.Pid))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+
+		_ = cmd.Wait()
+		So(cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(), ShouldEqual, syscall.SIGTERM)
+	})
+
+	Convey("Ensure signals are forwarded to process groups", t, func() {
+		signals := yamatest.NewSignals()
+		cmd := exec.Command("sleep", "60")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		So(cmd.Start(), ShouldBeNil)
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.ForwardingSignalsToGroup(cmd.Process.Pid))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(watcher.Wait(), ShouldBeNil)
+
+		_ = cmd.Wait()
+		So(cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(), ShouldEqual, syscall.SIGTERM)
+	})
+}
+
+func TestReraiseSignal(t *testing.T) {
+
+	Convey("Ensure the process is terminated by the signal after the closers", t, func() {
+		const source = `
+package main
+
+import (
+	"fmt"
+	"syscall"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGTERM),
+		yama.WithReraiseSignal(),
+		yama.WithClosers(yama.FnAsCloser(func() { fmt.Println("closed") })))
+
+	fmt.Println("ready")
+	_ = watcher.Wait()
+	fmt.Println("returned")
+}
+`
+		p := yamatest.StartSubprocess(t, source)
+		So(p.WaitForOutput("ready", 5*time.Second), ShouldBeTrue)
+
+		So(p.Signal(syscall.SIGTERM), ShouldBeNil)
+
+		err := p.Wait()
+		So(err, ShouldHaveSameTypeAs, &exec.ExitError{})
+		So(err.(*exec.ExitError).Sys().(syscall.WaitStatus).Signal(), ShouldEqual, syscall.SIGTERM)
+		So(p.Output(), ShouldEqual, "ready\nclosed\n")
+	})
 }
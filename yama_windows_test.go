@@ -17,91 +17,51 @@
 package yama_test
 
 import (
-	"bytes"
-	"io/ioutil"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"syscall"
 	"testing"
 	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
 )
 
 func TestDeath(t *testing.T) {
 
 	Convey("Validate death happens cleanly on windows with ctrl-c event", t, func() {
-		// create source file
 		const source = `
 package main
 import (
+	"fmt"
 	"syscall"
 	"l7e.io/yama"
 )
 func main() {
 	watcher, _ := yama.NewWatcher(yama.WatchingSignals(syscall.SIGINT))
+	fmt.Println("ready")
 	watcher.Wait()
 }
 `
-		tmp, err := ioutil.TempDir("", "TestCtrlBreak")
-		if err != nil {
-			t.Fatal("TempDir failed: ", err)
-		}
-		defer os.RemoveAll(tmp)
-
-		// write ctrlbreak.go
-		name := filepath.Join(tmp, "ctlbreak")
-		src := name + ".go"
-		f, err := os.Create(src)
-		if err != nil {
-			t.Fatalf("Failed to create %v: %v", src, err)
-		}
-		defer f.Close()
-		_, _ = f.Write([]byte(source))
+		p := yamatest.StartSubprocess(t, source)
+		So(p.WaitForOutput("ready", 5*time.Second), ShouldBeTrue)
 
-		// compile it
-		exe := name + ".exe"
-		defer os.Remove(exe)
-		o, err := exec.Command("go", "build", "-o", exe, src).CombinedOutput()
-		if err != nil {
-			t.Fatalf("Failed to compile: %v\n%v", err, string(o))
-		}
+		So(p.Terminate(), ShouldBeNil)
 
-		// run it
-		cmd := exec.Command(exe)
-		var b bytes.Buffer
-		cmd.Stdout = &b
-		cmd.Stderr = &b
-		cmd.SysProcAttr = &syscall.SysProcAttr{
-			CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP,
-		}
-		err = cmd.Start()
-		if err != nil {
-			t.Fatalf("Start failed: %v", err)
-		}
-		go func() {
-			time.Sleep(1 * time.Second)
-			sendCtrlBreak(t, cmd.Process.Pid)
-		}()
-		err = cmd.Wait()
+		err := p.Wait()
 		if err != nil {
-			t.Fatalf("Program exited with error: %v\n%v", err, b.String())
+			t.Fatalf("Program exited with error: %v\n%v", err, p.Output())
 		}
 	})
 }
 
-func sendCtrlBreak(t *testing.T, pid int) {
-	d, e := syscall.LoadDLL("kernel32.dll")
-	if e != nil {
-		t.Fatalf("LoadDLL: %v\n", e)
-	}
-	p, e := d.FindProc("GenerateConsoleCtrlEvent")
-	if e != nil {
-		t.Fatalf("FindProc: %v\n", e)
-	}
-	r, _, e := p.Call(syscall.CTRL_BREAK_EVENT, uintptr(pid))
-	if r == 0 {
-		t.Fatalf("GenerateConsoleCtrlEvent: %v\n", e)
-	}
+func TestHandlingSessionEnd(t *testing.T) {
+
+	Convey("Ensure the session window is created, and destroyed once the watcher is closed", t, func() {
+		watcher, err := yama.NewWatcher(
+			yama.HandlingSessionEnd("Saving documents"),
+			yama.WithClosers(yamatest.CloserSpy("documents")))
+		So(err, ShouldBeNil)
+
+		So(watcher.Close(), ShouldBeNil)
+	})
 }
@@ -0,0 +1,11 @@
+module l7e.io/yama/yamaecho
+
+go 1.13
+
+require (
+	github.com/labstack/echo/v4 v4.6.3
+	github.com/smartystreets/goconvey v1.6.4
+	l7e.io/yama v0.0.0
+)
+
+replace l7e.io/yama => ../
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+Package yamaecho provides a helper to gracefully serve an Echo instance.
+
+	watcher, err := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGINT, syscall.SIGTERM),
+		yama.WithTimeout(2*time.Second))
+
+	err = yamaecho.Run(e, ":8080", watcher)
+
+Run blocks until the watcher has notified its closers; the Echo instance is
+shut down within the watcher's timeout.
+*/
+package yamaecho // import "l7e.io/yama/yamaecho"
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"l7e.io/yama"
+	"l7e.io/yama/internal/graceful"
+)
+
+// Run starts the Echo instance on addr and serves traffic until the watcher
+// notifies its closers.  The instance is shut down with a deadline that is the
+// end of the watcher's timeout.  If the instance cannot serve traffic, the
+// watcher is closed.
+//
+// The returned error aggregates the errors from serving traffic, shutting down
+// the instance, and waiting for the watcher.
+func Run(e *echo.Echo, addr string, w yama.Shutdowner) error {
+	return graceful.Run(w, func() error { return e.Start(addr) }, e.Shutdown)
+}
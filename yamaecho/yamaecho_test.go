@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamaecho_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamaecho"
+)
+
+func TestRun(t *testing.T) {
+
+	Convey("Ensure the instance serves until the watcher is closed", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		e := echo.New()
+		e.HideBanner = true
+		e.HidePort = true
+		e.GET("/", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = watcher.Close()
+		}()
+
+		err = yamaecho.Run(e, "127.0.0.1:0", watcher)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Ensure the watcher is closed when the instance cannot serve", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		e := echo.New()
+		e.HideBanner = true
+		e.HidePort = true
+
+		err = yamaecho.Run(e, "127.0.0.1:-1", watcher)
+		So(err, ShouldNotBeNil)
+		So(watcher.AddCloser(yama.FnAsCloser(func() {})), ShouldEqual, yama.ErrShutdown)
+	})
+}
@@ -0,0 +1,11 @@
+module l7e.io/yama/yamafasthttp
+
+go 1.16
+
+require (
+	github.com/smartystreets/goconvey v1.6.4
+	github.com/valyala/fasthttp v1.44.0
+	l7e.io/yama v0.0.0
+)
+
+replace l7e.io/yama => ../
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+Package yamafasthttp provides an adapter to gracefully shutdown a fasthttp
+server.
+
+	watcher, err := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGINT, syscall.SIGTERM),
+		yama.WithTimeout(2*time.Second))
+
+	err = yamafasthttp.Run(server, ":8080", watcher)
+
+Unlike net/http, fasthttp does not provide a context per connection that is
+cancelled when the server shuts down.  Instead, the channel returned by the
+Done() method of every fasthttp.RequestCtx is closed when the shutdown starts;
+long running handlers should select on it so that they complete within the
+watcher's timeout.
+*/
+package yamafasthttp // import "l7e.io/yama/yamafasthttp"
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/valyala/fasthttp"
+
+	"l7e.io/yama"
+	"l7e.io/yama/internal/graceful"
+)
+
+// Closer returns a closer that shuts the server down, waiting for open
+// connections to become idle until the end of the watcher's timeout.  Since
+// fasthttp cannot interrupt active connections, the returned error reports
+// that connections were still open when the deadline expired.
+func Closer(server *fasthttp.Server) io.Closer {
+	return yama.ContextFnAsCloser(func(ctx context.Context) error {
+		return shutdown(ctx, server)
+	})
+}
+
+// Run serves traffic on addr until the watcher notifies its closers.  The
+// server is shut down with a deadline that is the end of the watcher's
+// timeout.  If the server cannot serve traffic, the watcher is closed.
+//
+// Run sets CloseOnShutdown so that keep-alive clients are told to close their
+// connections, and reconnect elsewhere, as soon as the shutdown starts.
+//
+// The returned error aggregates the errors from serving traffic, shutting down
+// the server, and waiting for the watcher.
+func Run(server *fasthttp.Server, addr string, w yama.Shutdowner) error {
+	server.CloseOnShutdown = true
+
+	return graceful.Run(w,
+		func() error { return server.ListenAndServe(addr) },
+		func(ctx context.Context) error { return shutdown(ctx, server) })
+}
+
+func shutdown(ctx context.Context, server *fasthttp.Server) error {
+	err := server.ShutdownWithContext(ctx)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("connections still open: %w", err)
+	}
+
+	return err
+}
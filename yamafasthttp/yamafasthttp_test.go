@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamafasthttp_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/valyala/fasthttp"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamafasthttp"
+)
+
+func TestRun(t *testing.T) {
+
+	Convey("Ensure the server serves until the watcher is closed", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		server := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {}}
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = watcher.Close()
+		}()
+
+		err = yamafasthttp.Run(server, "127.0.0.1:0", watcher)
+		So(err, ShouldBeNil)
+		So(server.CloseOnShutdown, ShouldBeTrue)
+	})
+}
+
+func TestCloser(t *testing.T) {
+
+	Convey("Ensure open connections are reported when the deadline expires", t, func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		server := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+			close(started)
+			<-release
+		}}
+		defer close(release)
+
+		go func() { _ = server.Serve(ln) }()
+		go func() { _, _, _ = fasthttp.Get(nil, "http://"+ln.Addr().String()) }()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err = yamafasthttp.Closer(server).(yama.ContextCloser).CloseContext(ctx)
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		So(err.Error(), ShouldStartWith, "connections still open")
+	})
+}
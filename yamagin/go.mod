@@ -0,0 +1,11 @@
+module l7e.io/yama/yamagin
+
+go 1.13
+
+require (
+	github.com/gin-gonic/gin v1.7.7
+	github.com/smartystreets/goconvey v1.6.4
+	l7e.io/yama v0.0.0
+)
+
+replace l7e.io/yama => ../
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+Package yamagin provides a helper to gracefully serve a Gin engine.
+
+	watcher, err := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGINT, syscall.SIGTERM),
+		yama.WithTimeout(2*time.Second))
+
+	err = yamagin.Run(engine, ":8080", watcher)
+
+Run blocks until the watcher has notified its closers; the engine's server is
+shut down within the watcher's timeout.
+*/
+package yamagin // import "l7e.io/yama/yamagin"
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"l7e.io/yama"
+	"l7e.io/yama/internal/graceful"
+)
+
+// Run serves the engine on addr until the watcher notifies its closers. The
+// server is shut down with a deadline that is the end of the watcher's
+// timeout.  If the server cannot serve traffic, the watcher is closed.
+//
+// The returned error aggregates the errors from serving traffic, shutting down
+// the server, and waiting for the watcher.
+func Run(engine *gin.Engine, addr string, w yama.Shutdowner) error {
+	server := &http.Server{Addr: addr, Handler: engine}
+
+	return graceful.Run(w, server.ListenAndServe, server.Shutdown)
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamagin_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamagin"
+)
+
+func TestRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	Convey("Ensure the engine serves until the watcher is closed", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		engine := gin.New()
+		engine.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = watcher.Close()
+		}()
+
+		err = yamagin.Run(engine, "127.0.0.1:0", watcher)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Ensure the watcher is closed when the engine cannot serve", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		err = yamagin.Run(gin.New(), "127.0.0.1:-1", watcher)
+		So(err, ShouldNotBeNil)
+		So(watcher.AddCloser(yama.FnAsCloser(func() {})), ShouldEqual, yama.ErrShutdown)
+	})
+}
@@ -0,0 +1,11 @@
+module l7e.io/yama/yamakit
+
+go 1.13
+
+require (
+	github.com/go-kit/kit v0.10.0
+	github.com/smartystreets/goconvey v1.6.4
+	l7e.io/yama v0.0.0
+)
+
+replace l7e.io/yama => ../
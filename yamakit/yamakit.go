@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+Package yamakit integrates go-kit services with a yama watcher.
+
+A Lifecycle is injected into the transports and service middlewares of a
+go-kit service so that they can register their stop functions with the
+watcher; the run loop of main collapses to yama.Run().
+
+	options := []yama.Option{
+		yama.WatchingSignals(syscall.SIGINT, syscall.SIGTERM),
+		yama.WithTimeout(2*time.Second)}
+
+	err := yama.Run(context.Background(), options, func(ctx context.Context) error {
+		lifecycle := yamakit.New(yama.FromContext(ctx))
+
+		drain, err := lifecycle.Middleware()
+		if err != nil {
+			return err
+		}
+
+		handler := httptransport.NewServer(drain(makeEndpoint(svc)), decode, encode)
+		if err := lifecycle.ServeHTTP(listener, handler); err != nil {
+			return err
+		}
+
+		return lifecycle.Wait()
+	})
+
+Here, the listener is served until a signal occurs; the endpoint then rejects
+new requests with ErrStopping and the watcher waits for in-flight requests and
+the HTTP server to complete, within the watcher's timeout.
+*/
+package yamakit // import "l7e.io/yama/yamakit"
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"l7e.io/yama"
+)
+
+// ErrStopping is returned by endpoints wrapped by the middleware of a
+// Lifecycle once the watcher has started notifying its closers.
+var ErrStopping = errors.New("service is stopping")
+
+// Lifecycle registers the stop functions of go-kit transports and service
+// middlewares with a watcher.
+type Lifecycle struct {
+	w     yama.Shutdowner
+	wg    sync.WaitGroup
+	close sync.Once
+	mu    sync.Mutex
+	errs  []error
+}
+
+// New creates a Lifecycle that registers stop functions with the watcher.
+func New(w yama.Shutdowner) *Lifecycle {
+	return &Lifecycle{w: w}
+}
+
+// OnStop registers a stop function with the watcher.  The function is called
+// with a context whose deadline is the end of the watcher's timeout.
+func (l *Lifecycle) OnStop(stop func(ctx context.Context) error) error {
+	return l.w.AddCloser(yama.ContextFnAsCloser(func(ctx context.Context) error {
+		err := stop(ctx)
+		l.record(err)
+
+		return err
+	}))
+}
+
+// Go calls execute in its own goroutine and registers interrupt as a stop
+// function; interrupt is expected to make execute return.  If execute returns
+// before the watcher notifies its closers, the watcher is closed since the
+// service can no longer operate.
+func (l *Lifecycle) Go(execute func() error, interrupt func(ctx context.Context) error) error {
+	if err := l.OnStop(interrupt); err != nil {
+		return err
+	}
+
+	l.wg.Add(1)
+
+	go func() {
+		defer l.wg.Done()
+
+		l.record(execute())
+		l.close.Do(func() { _ = l.w.Close() })
+	}()
+
+	return nil
+}
+
+// ServeHTTP serves the handler, e.g. a go-kit HTTP transport server, on the
+// listener until the watcher notifies its closers.
+func (l *Lifecycle) ServeHTTP(ln net.Listener, handler http.Handler) error {
+	server := &http.Server{Handler: handler}
+
+	return l.Go(func() error {
+		if err := server.Serve(ln); err != http.ErrServerClosed {
+			return err
+		}
+
+		return nil
+	}, server.Shutdown)
+}
+
+// Middleware returns an endpoint middleware that rejects requests with
+// ErrStopping once the watcher has started notifying its closers.  Its stop
+// function waits for in-flight requests to complete.
+func (l *Lifecycle) Middleware() (endpoint.Middleware, error) {
+	d := &drain{}
+
+	if err := l.OnStop(d.stop); err != nil {
+		return nil, err
+	}
+
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if !d.enter() {
+				return nil, ErrStopping
+			}
+			defer d.inflight.Done()
+
+			return next(ctx, request)
+		}
+	}, nil
+}
+
+// Wait waits for the watcher to notify its closers and for the functions
+// started by Go() to return.  The returned error aggregates the errors of the
+// watcher, the stop functions, and the functions started by Go().
+func (l *Lifecycle) Wait() error {
+	err := l.w.Wait()
+	l.wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return yama.JoinErrors(append(l.errs, err)...)
+}
+
+func (l *Lifecycle) record(err error) {
+	if err == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.errs = append(l.errs, err)
+}
+
+// drain tracks in-flight requests, refusing new ones once stopped.
+type drain struct {
+	mu       sync.Mutex
+	stopping bool
+	inflight sync.WaitGroup
+}
+
+func (d *drain) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopping {
+		return false
+	}
+
+	d.inflight.Add(1)
+
+	return true
+}
+
+func (d *drain) stop(ctx context.Context) error {
+	d.mu.Lock()
+	d.stopping = true
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		d.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamakit_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamakit"
+	"l7e.io/yama/yamatest"
+)
+
+func TestLifecycle(t *testing.T) {
+
+	Convey("Ensure stop functions are called when the watcher is closed", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		lifecycle := yamakit.New(watcher)
+
+		stopErr := errors.New("stop failed")
+		err = lifecycle.OnStop(func(ctx context.Context) error { return stopErr })
+		So(err, ShouldBeNil)
+
+		_ = watcher.Close()
+
+		So(lifecycle.Wait(), ShouldEqual, stopErr)
+	})
+
+	Convey("Ensure stop functions are registered with the injected watcher", t, func() {
+		recorder := yamatest.NewRecorder()
+		lifecycle := yamakit.New(recorder)
+
+		stopped := false
+		err := lifecycle.OnStop(func(ctx context.Context) error {
+			stopped = true
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(recorder.Closers(), ShouldHaveLength, 1)
+
+		_ = recorder.Trigger()
+		So(stopped, ShouldBeTrue)
+		So(lifecycle.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure the watcher is closed when a served handler fails", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		lifecycle := yamakit.New(watcher)
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		_ = ln.Close()
+
+		err = lifecycle.ServeHTTP(ln, http.NotFoundHandler())
+		So(err, ShouldBeNil)
+
+		So(lifecycle.Wait(), ShouldNotBeNil)
+	})
+
+	Convey("Ensure several servers can be stopped", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		lifecycle := yamakit.New(watcher)
+
+		for i := 0; i < 2; i++ {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			So(lifecycle.ServeHTTP(ln, http.NotFoundHandler()), ShouldBeNil)
+		}
+
+		_ = watcher.Close()
+
+		So(lifecycle.Wait(), ShouldBeNil)
+	})
+
+	Convey("Ensure the middleware drains in-flight requests", t, func() {
+		watcher, err := yama.NewWatcher(yama.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+
+		lifecycle := yamakit.New(watcher)
+		drain, err := lifecycle.Middleware()
+		So(err, ShouldBeNil)
+
+		started := make(chan struct{})
+		completed := false
+		e := drain(func(ctx context.Context, request interface{}) (interface{}, error) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			completed = true
+			return request, nil
+		})
+
+		go func() { _, _ = e(context.Background(), "request") }()
+		<-started
+
+		_ = watcher.Close()
+		So(completed, ShouldBeTrue)
+
+		_, err = e(context.Background(), "request")
+		So(err, ShouldEqual, yamakit.ErrStopping)
+
+		So(lifecycle.Wait(), ShouldBeNil)
+	})
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest // import "l7e.io/yama/yamatest"
+
+import (
+	"sync"
+	"time"
+
+	"l7e.io/yama"
+)
+
+// Clock is a yama.Clock whose time only changes when it is advanced.
+type Clock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	slept  time.Duration
+	timers []*timer
+}
+
+var _ yama.SleepClock = (*Clock)(nil)
+
+// NewClock creates a clock whose current time is now.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After waits for the clock to be advanced by the duration and then sends the
+// time of the clock on the returned channel.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer creates a timer that fires when the clock is advanced by the
+// duration.
+func (c *Clock) NewTimer(d time.Duration) yama.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &timer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+
+	return t
+}
+
+// Advance moves the time of the clock forward by the duration, firing the
+// timers whose deadline has been reached.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.advance(d)
+}
+
+// Sleep moves the time of the clock forward by the duration, like Advance,
+// as if the system had slept for that long.
+func (c *Clock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.slept += d
+	c.advance(d)
+}
+
+// Slept returns the time the system has slept, as simulated by Sleep().
+func (c *Clock) Slept() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.slept
+}
+
+func (c *Clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+
+	pending := c.timers[:0]
+
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+
+		t.c <- c.now
+	}
+
+	c.timers = pending
+	c.cond.Broadcast()
+}
+
+// BlockUntil blocks until at least n timers are waiting for the clock to be
+// advanced; tests use it to ensure that a watcher has started its timeout
+// before advancing the clock.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Timers returns the number of timers waiting for the clock to be advanced.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			t.clock.cond.Broadcast()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// osClock is the clock of the OS.
+type osClock struct{}
+
+func (osClock) Now() time.Time {
+	return time.Now()
+}
+
+func (osClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (osClock) NewTimer(d time.Duration) yama.Timer {
+	return osTimer{time.NewTimer(d)}
+}
+
+type osTimer struct{ t *time.Timer }
+
+func (o osTimer) C() <-chan time.Time {
+	return o.t.C
+}
+
+func (o osTimer) Stop() bool {
+	return o.t.Stop()
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama/yamatest"
+)
+
+func TestClock(t *testing.T) {
+	epoch := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Ensure timers fire when the clock is advanced", t, func() {
+		clock := yamatest.NewClock(epoch)
+
+		after := clock.After(time.Second)
+		timer := clock.NewTimer(2 * time.Second)
+		So(clock.Timers(), ShouldEqual, 2)
+
+		clock.Advance(time.Second)
+		So(<-after, ShouldEqual, epoch.Add(time.Second))
+		So(clock.Timers(), ShouldEqual, 1)
+
+		select {
+		case <-timer.C():
+			t.Error("timer fired early")
+		default:
+		}
+
+		clock.Advance(time.Second)
+		So(<-timer.C(), ShouldEqual, epoch.Add(2*time.Second))
+		So(clock.Now(), ShouldEqual, epoch.Add(2*time.Second))
+	})
+
+	Convey("Ensure sleeping advances the clock and is reported", t, func() {
+		clock := yamatest.NewClock(epoch)
+
+		after := clock.After(time.Second)
+		clock.Sleep(time.Hour)
+		So(<-after, ShouldEqual, epoch.Add(time.Hour))
+		So(clock.Slept(), ShouldEqual, time.Hour)
+
+		clock.Advance(time.Second)
+		So(clock.Slept(), ShouldEqual, time.Hour)
+	})
+
+	Convey("Ensure stopped timers do not fire", t, func() {
+		clock := yamatest.NewClock(epoch)
+
+		timer := clock.NewTimer(time.Second)
+		So(timer.Stop(), ShouldBeTrue)
+		So(timer.Stop(), ShouldBeFalse)
+		So(clock.Timers(), ShouldEqual, 0)
+	})
+
+	Convey("Ensure BlockUntil waits for timers", t, func() {
+		clock := yamatest.NewClock(epoch)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			clock.After(time.Second)
+		}()
+
+		clock.BlockUntil(1)
+		So(clock.Timers(), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest // import "l7e.io/yama/yamatest"
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// Closers creates named closers and records their invocations.
+type Closers struct {
+	mu      sync.Mutex
+	invoked []string
+}
+
+// NewClosers creates an empty set of closers.
+func NewClosers() *Closers {
+	return &Closers{}
+}
+
+// Closer returns a closer that records its invocations under name.
+func (c *Closers) Closer(name string) io.Closer {
+	return &namedCloser{closers: c, name: name}
+}
+
+// Invoked returns the names of the invoked closers, in the order in which
+// they were invoked; a closer invoked several times appears several times.
+func (c *Closers) Invoked() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.invoked...)
+}
+
+// AssertInvoked fails the test unless exactly the named closers were each
+// invoked once, in any order.
+func AssertInvoked(t testing.TB, closers *Closers, names ...string) {
+	t.Helper()
+
+	invoked := closers.Invoked()
+	sort.Strings(invoked)
+
+	expected := append([]string(nil), names...)
+	sort.Strings(expected)
+
+	if len(invoked) != len(expected) {
+		t.Errorf("expected closers %v to be invoked, but %v were", expected, invoked)
+		return
+	}
+
+	for i := range invoked {
+		if invoked[i] != expected[i] {
+			t.Errorf("expected closers %v to be invoked, but %v were", expected, invoked)
+			return
+		}
+	}
+}
+
+type namedCloser struct {
+	closers *Closers
+	name    string
+}
+
+func (n *namedCloser) Close() error {
+	n.closers.mu.Lock()
+	defer n.closers.mu.Unlock()
+
+	n.closers.invoked = append(n.closers.invoked, n.name)
+
+	return nil
+}
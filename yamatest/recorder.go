@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest // import "l7e.io/yama/yamatest"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+
+	"l7e.io/yama"
+)
+
+// Call is an interaction with a Recorder.
+type Call struct {
+	// Method is the name of the method that was called.
+	Method string
+
+	// Closer is the closer passed to AddCloser() or RemoveCloser().
+	Closer io.Closer
+
+	// Signal is the signal passed to Simulate().
+	Signal os.Signal
+}
+
+// Recorder is a fake watcher that records the interactions of the components
+// under test, and that tests trigger with Trigger().  It implements
+// yama.Shutdowner.
+type Recorder struct {
+	mu        sync.Mutex
+	calls     []Call
+	closers   []io.Closer
+	triggered bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+}
+
+var _ yama.Shutdowner = (*Recorder)(nil)
+
+// NewRecorder creates a recorder that has not been triggered.
+func NewRecorder() *Recorder {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Recorder{ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// AddCloser records the call and registers the closer, unless the recorder
+// has been triggered, in which case yama.ErrShutdown is returned.
+func (r *Recorder) AddCloser(closer io.Closer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, Call{Method: "AddCloser", Closer: closer})
+
+	if closer == nil {
+		return errors.New("closer must not be null")
+	}
+
+	if r.triggered {
+		return yama.ErrShutdown
+	}
+
+	r.closers = append(r.closers, closer)
+
+	return nil
+}
+
+// RemoveCloser records the call and unregisters the closer, unless the
+// recorder has been triggered, in which case yama.ErrShutdown is returned;
+// yama.ErrUnknownCloser is returned if the closer is not registered, or if it
+// cannot be compared.
+func (r *Recorder) RemoveCloser(closer io.Closer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, Call{Method: "RemoveCloser", Closer: closer})
+
+	if closer == nil {
+		return errors.New("closer must not be null")
+	}
+
+	if r.triggered {
+		return yama.ErrShutdown
+	}
+
+	// closers of types that cannot be compared are never found, as by the
+	// watcher
+	if !reflect.TypeOf(closer).Comparable() {
+		return yama.ErrUnknownCloser
+	}
+
+	for i, c := range r.closers {
+		if reflect.TypeOf(c) == reflect.TypeOf(closer) && c == closer {
+			r.closers = append(r.closers[:i:i], r.closers[i+1:]...)
+			return nil
+		}
+	}
+
+	return yama.ErrUnknownCloser
+}
+
+// Close records the call and triggers the recorder.
+func (r *Recorder) Close() error {
+	r.record(Call{Method: "Close"})
+
+	return r.Trigger()
+}
+
+// Simulate records the call and triggers the recorder.
+func (r *Recorder) Simulate(sig os.Signal) {
+	r.record(Call{Method: "Simulate", Signal: sig})
+
+	_ = r.Trigger()
+}
+
+// Wait records the call and blocks until the recorder is triggered.
+func (r *Recorder) Wait() error {
+	r.record(Call{Method: "Wait"})
+
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Context returns a context that is cancelled once the recorder is triggered,
+// before the closers are called; the call is not recorded.
+func (r *Recorder) Context() context.Context {
+	return r.ctx
+}
+
+// Done returns a channel that is closed once the recorder is triggered, the
+// Done() channel of Context(); the call is not recorded.
+func (r *Recorder) Done() <-chan struct{} {
+	return r.ctx.Done()
+}
+
+// Trigger cancels the context of the recorder, calls the registered closers,
+// in the order in which they were registered, and unblocks the callers of
+// Wait().  The closers are only called the first time the recorder is
+// triggered.
+func (r *Recorder) Trigger() error {
+	r.mu.Lock()
+	if r.triggered {
+		r.mu.Unlock()
+		<-r.done
+
+		return r.result()
+	}
+
+	r.triggered = true
+	closers := r.closers
+	r.mu.Unlock()
+
+	r.cancel()
+
+	for _, closer := range closers {
+		_ = closer.Close()
+	}
+
+	close(r.done)
+
+	return r.result()
+}
+
+// SetResult sets the error returned by Close(), Wait(), and Trigger().
+func (r *Recorder) SetResult(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+}
+
+// Calls returns the recorded interactions, in the order in which they
+// occurred.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// Closers returns the registered closers.
+func (r *Recorder) Closers() []io.Closer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]io.Closer(nil), r.closers...)
+}
+
+// Triggered reports whether the recorder has been triggered.
+func (r *Recorder) Triggered() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.triggered
+}
+
+func (r *Recorder) record(call Call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func (r *Recorder) result() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
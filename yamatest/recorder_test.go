@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest_test
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestRecorder(t *testing.T) {
+
+	Convey("Ensure interactions are recorded", t, func() {
+		recorder := yamatest.NewRecorder()
+		closers := yamatest.NewClosers()
+		db := closers.Closer("db")
+
+		So(recorder.AddCloser(db), ShouldBeNil)
+		So(recorder.RemoveCloser(db), ShouldBeNil)
+		So(recorder.RemoveCloser(db), ShouldEqual, yama.ErrUnknownCloser)
+		So(recorder.AddCloser(db), ShouldBeNil)
+		recorder.Simulate(syscall.SIGTERM)
+		So(recorder.Wait(), ShouldBeNil)
+		So(recorder.Close(), ShouldBeNil)
+
+		So(recorder.Calls(), ShouldResemble, []yamatest.Call{
+			{Method: "AddCloser", Closer: db},
+			{Method: "RemoveCloser", Closer: db},
+			{Method: "RemoveCloser", Closer: db},
+			{Method: "AddCloser", Closer: db},
+			{Method: "Simulate", Signal: syscall.SIGTERM},
+			{Method: "Wait"},
+			{Method: "Close"},
+		})
+		So(recorder.Closers(), ShouldResemble, []io.Closer{db})
+	})
+
+	Convey("Ensure triggering calls closers once", t, func() {
+		recorder := yamatest.NewRecorder()
+		closers := yamatest.NewClosers()
+
+		So(recorder.AddCloser(closers.Closer("server")), ShouldBeNil)
+		So(recorder.Triggered(), ShouldBeFalse)
+
+		waited := make(chan error)
+		go func() { waited <- recorder.Wait() }()
+
+		failed := errors.New("failed")
+		recorder.SetResult(failed)
+
+		So(recorder.Trigger(), ShouldEqual, failed)
+		So(<-waited, ShouldEqual, failed)
+		So(recorder.Trigger(), ShouldEqual, failed)
+		So(recorder.Triggered(), ShouldBeTrue)
+
+		yamatest.AssertInvoked(t, closers, "server")
+
+		So(recorder.AddCloser(closers.Closer("late")), ShouldEqual, yama.ErrShutdown)
+	})
+
+	Convey("Ensure the context is cancelled before the closers are called", t, func() {
+		recorder := yamatest.NewRecorder()
+		So(recorder.Context().Err(), ShouldBeNil)
+
+		cancelled := false
+		So(recorder.AddCloser(yama.FnAsCloser(func() { cancelled = recorder.Context().Err() != nil })), ShouldBeNil)
+
+		So(recorder.Close(), ShouldBeNil)
+		So(cancelled, ShouldBeTrue)
+
+		_, open := <-recorder.Done()
+		So(open, ShouldBeFalse)
+	})
+
+	Convey("Ensure closers that cannot be compared are not found", t, func() {
+		recorder := yamatest.NewRecorder()
+		closer := sliceCloser{1, 2}
+
+		So(recorder.AddCloser(closer), ShouldBeNil)
+		So(recorder.RemoveCloser(closer), ShouldEqual, yama.ErrUnknownCloser)
+	})
+}
+
+// sliceCloser is a closer whose type cannot be compared.
+type sliceCloser []int
+
+func (sliceCloser) Close() error { return nil }
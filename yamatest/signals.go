@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest // import "l7e.io/yama/yamatest"
+
+import (
+	"os"
+	"sync"
+)
+
+// Signals is a yama.SignalSource that delivers signals in-process.
+type Signals struct {
+	mu       sync.Mutex
+	channels map[chan<- os.Signal][]os.Signal
+}
+
+// NewSignals creates a source of in-process signals.
+func NewSignals() *Signals {
+	return &Signals{channels: make(map[chan<- os.Signal][]os.Signal)}
+}
+
+// Notify causes Send() to relay the signals to c.  If no signals are provided,
+// all signals are relayed to c.
+func (s *Signals) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.channels[c] = append(s.channels[c], sig...)
+}
+
+// Stop causes Send() to stop relaying signals to c.
+func (s *Signals) Stop(c chan<- os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.channels, c)
+}
+
+// Send relays the signal to the channels that were registered for it.  Like
+// the signal package, Send does not block when a channel is not ready to
+// receive the signal; it reports whether the signal was relayed to at least
+// one channel.
+func (s *Signals) Send(sig os.Signal) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	relayed := false
+
+	for c, signals := range s.channels {
+		if !watches(signals, sig) {
+			continue
+		}
+
+		select {
+		case c <- sig:
+			relayed = true
+		default:
+		}
+	}
+
+	return relayed
+}
+
+func watches(signals []os.Signal, sig os.Signal) bool {
+	if len(signals) == 0 {
+		return true
+	}
+
+	for _, s := range signals {
+		if s == sig {
+			return true
+		}
+	}
+
+	return false
+}
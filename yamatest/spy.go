@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest // import "l7e.io/yama/yamatest"
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"l7e.io/yama"
+)
+
+// SpyCall is a call to a Spy.
+type SpyCall struct {
+	// Time is the time of the spy's clock when it was called.
+	Time time.Time
+
+	// Context is the context the spy received; it is nil when the spy was
+	// called with Close().
+	Context context.Context
+}
+
+// Spy is a closer that records its calls and that can be configured to take
+// time, or to fail.
+type Spy struct {
+	name  string
+	mu    sync.Mutex
+	clock yama.Clock
+	delay time.Duration
+	err   error
+	calls []SpyCall
+}
+
+// CloserSpy creates a spy that returns immediately, without error, and that
+// timestamps its calls with the clock of the OS.
+func CloserSpy(name string) *Spy {
+	return &Spy{name: name, clock: osClock{}}
+}
+
+// WithClock specifies the clock used to timestamp calls and to time delays.
+func (s *Spy) WithClock(clock yama.Clock) *Spy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = clock
+
+	return s
+}
+
+// WithDelay specifies how long the spy takes to close.  When called with
+// CloseContext(), the spy returns the context's error if it is done before the
+// delay elapses.
+func (s *Spy) WithDelay(delay time.Duration) *Spy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delay = delay
+
+	return s
+}
+
+// WithError specifies the error returned by the spy.
+func (s *Spy) WithError(err error) *Spy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+
+	return s
+}
+
+// Name returns the name of the spy.
+func (s *Spy) Name() string {
+	return s.name
+}
+
+// Close records the call and returns once the delay has elapsed.
+func (s *Spy) Close() error {
+	clock, delay, err := s.record(SpyCall{})
+	if delay > 0 {
+		<-clock.After(delay)
+	}
+
+	return err
+}
+
+// CloseContext records the call and returns once the delay has elapsed or the
+// context is done.
+func (s *Spy) CloseContext(ctx context.Context) error {
+	clock, delay, err := s.record(SpyCall{Context: ctx})
+	if delay > 0 {
+		select {
+		case <-clock.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
+
+func (s *Spy) record(call SpyCall) (yama.Clock, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	call.Time = s.clock.Now()
+	s.calls = append(s.calls, call)
+
+	return s.clock, s.delay, s.err
+}
+
+// Calls returns the number of times the spy was called.
+func (s *Spy) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.calls)
+}
+
+// Invocations returns the calls to the spy, in the order in which they
+// occurred.
+func (s *Spy) Invocations() []SpyCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]SpyCall(nil), s.calls...)
+}
+
+func (s *Spy) String() string {
+	return s.name
+}
+
+// ShouldHaveBeenClosed is a GoConvey assertion that the spy was called; when
+// a count is expected, the spy must have been called exactly that many times.
+//
+//	So(spy, yamatest.ShouldHaveBeenClosed)
+//	So(spy, yamatest.ShouldHaveBeenClosed, 1)
+func ShouldHaveBeenClosed(actual interface{}, expected ...interface{}) string {
+	spy, ok := actual.(*Spy)
+	if !ok {
+		return fmt.Sprintf("expected a *yamatest.Spy, but got %T", actual)
+	}
+
+	calls := spy.Calls()
+
+	switch len(expected) {
+	case 0:
+		if calls == 0 {
+			return fmt.Sprintf("expected %q to have been closed, but it was not", spy.name)
+		}
+	case 1:
+		times, ok := expected[0].(int)
+		if !ok {
+			return fmt.Sprintf("expected the number of times to be an int, but got %T", expected[0])
+		}
+
+		if calls != times {
+			return fmt.Sprintf("expected %q to have been closed %d time(s), but it was closed %d time(s)", spy.name, times, calls)
+		}
+	default:
+		return "expected at most the number of times the spy was closed"
+	}
+
+	return ""
+}
+
+// ShouldNotHaveBeenClosed is a GoConvey assertion that the spy was not called.
+//
+//	So(spy, yamatest.ShouldNotHaveBeenClosed)
+func ShouldNotHaveBeenClosed(actual interface{}, expected ...interface{}) string {
+	if len(expected) != 0 {
+		return "expected no arguments"
+	}
+
+	return ShouldHaveBeenClosed(actual, 0)
+}
+
+// TestingT is the subset of testing.TB used by the assertions; it is also
+// satisfied by the TestingT of testify.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertClosed is a testify style assertion that the spy was called exactly
+// the number of times.
+//
+//	yamatest.AssertClosed(t, spy, 1)
+func AssertClosed(t TestingT, spy *Spy, times int) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	if msg := ShouldHaveBeenClosed(spy, times); msg != "" {
+		t.Errorf("%s", msg)
+		return false
+	}
+
+	return true
+}
+
+// AssertNotClosed is a testify style assertion that the spy was not called.
+//
+//	yamatest.AssertNotClosed(t, spy)
+func AssertNotClosed(t TestingT, spy *Spy) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	return AssertClosed(t, spy, 0)
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestSpy(t *testing.T) {
+	epoch := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Ensure calls are recorded", t, func() {
+		clock := yamatest.NewClock(epoch)
+		failed := errors.New("failed")
+		spy := yamatest.CloserSpy("db").WithClock(clock).WithError(failed)
+
+		So(spy, yamatest.ShouldNotHaveBeenClosed)
+		So(spy.Close(), ShouldEqual, failed)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		So(spy.CloseContext(ctx), ShouldEqual, failed)
+
+		So(spy, yamatest.ShouldHaveBeenClosed)
+		So(spy, yamatest.ShouldHaveBeenClosed, 2)
+		So(spy.Invocations(), ShouldResemble, []yamatest.SpyCall{
+			{Time: epoch},
+			{Time: epoch, Context: ctx},
+		})
+		So(spy.Name(), ShouldEqual, "db")
+	})
+
+	Convey("Ensure the watcher times out slow spies", t, func() {
+		clock := yamatest.NewClock(epoch)
+		slow := yamatest.CloserSpy("slow").WithClock(clock).WithDelay(time.Hour)
+		fast := yamatest.CloserSpy("fast").WithClock(clock)
+
+		watcher, err := yama.NewWatcher(
+			yama.WithClock(clock),
+			yama.WithTimeout(time.Minute),
+			yama.WithClosers(slow, fast))
+		So(err, ShouldBeNil)
+
+		go func() {
+			clock.BlockUntil(2)
+			for fast.Calls() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(10 * time.Millisecond) // let the watcher observe the completion
+			clock.Advance(time.Minute)
+		}()
+
+		err = watcher.Close()
+		So(err, ShouldHaveSameTypeAs, &yama.ErrTimedOut{})
+		So(err.(*yama.ErrTimedOut).Uncompleted, ShouldResemble, []io.Closer{slow})
+
+		So(slow, yamatest.ShouldHaveBeenClosed, 1)
+		So(fast, yamatest.ShouldHaveBeenClosed, 1)
+	})
+
+	Convey("Ensure the assertions report failures", t, func() {
+		spy := yamatest.CloserSpy("db")
+
+		So(yamatest.ShouldHaveBeenClosed(spy), ShouldNotBeEmpty)
+		So(yamatest.ShouldHaveBeenClosed(spy, "once"), ShouldNotBeEmpty)
+		So(yamatest.ShouldHaveBeenClosed("db"), ShouldNotBeEmpty)
+
+		tb := &fakeTB{TB: t}
+		So(yamatest.AssertClosed(tb, spy, 1), ShouldBeFalse)
+		So(tb.failed, ShouldBeTrue)
+
+		tb = &fakeTB{TB: t}
+		So(yamatest.AssertNotClosed(tb, spy), ShouldBeTrue)
+		So(tb.failed, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest // import "l7e.io/yama/yamatest"
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Subprocess is a program, built from source by the test, that is terminated
+// the way the platform asks programs to terminate gracefully: SIGTERM on Unix,
+// and CTRL_BREAK on Windows, which Go delivers as os.Interrupt.
+type Subprocess struct {
+	cmd    *exec.Cmd
+	output output
+}
+
+// StartSubprocess builds a main package from its source and starts it.  The
+// source is built within the module of the test, so it can import the module
+// and its dependencies.  The test fails if the program cannot be built or
+// started.
+//
+// Since the program can only be terminated gracefully once its watcher is
+// watching signals, it should print a line once the watcher is created, and
+// the test should wait for that line with WaitForOutput().
+func StartSubprocess(t testing.TB, source string) *Subprocess {
+	t.Helper()
+
+	// directories starting with an underscore are ignored by "go test ./..."
+	tmp, err := ioutil.TempDir(".", "_yamatest")
+	if err != nil {
+		t.Fatalf("failed to create a temporary directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(tmp) })
+
+	src := filepath.Join(tmp, "main.go")
+	if err := ioutil.WriteFile(src, []byte(source), 0o600); err != nil {
+		t.Fatalf("failed to write %v: %v", src, err)
+	}
+
+	exe, err := filepath.Abs(filepath.Join(tmp, "main.exe"))
+	if err != nil {
+		t.Fatalf("failed to resolve the executable: %v", err)
+	}
+
+	if o, err := exec.Command("go", "build", "-o", exe, src).CombinedOutput(); err != nil {
+		t.Fatalf("failed to compile: %v\n%v", err, string(o))
+	}
+
+	s := &Subprocess{cmd: exec.Command(exe)}
+	s.cmd.Stdout = &s.output
+	s.cmd.Stderr = &s.output
+	s.cmd.SysProcAttr = sysProcAttr()
+
+	if err := s.cmd.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if s.cmd.ProcessState == nil {
+			_ = s.cmd.Process.Kill()
+			_ = s.cmd.Wait()
+		}
+	})
+
+	return s
+}
+
+// WaitForOutput waits for the program to output the text, reporting whether
+// it did within the timeout.
+func (s *Subprocess) WaitForOutput(text string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for !strings.Contains(s.Output(), text) {
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return true
+}
+
+// Terminate asks the program to terminate gracefully.
+func (s *Subprocess) Terminate() error {
+	return terminate(s.cmd.Process)
+}
+
+// Signal sends the signal to the program.
+func (s *Subprocess) Signal(sig os.Signal) error {
+	return s.cmd.Process.Signal(sig)
+}
+
+// Wait waits for the program to exit, returning an *exec.ExitError if it did
+// not exit cleanly.
+func (s *Subprocess) Wait() error {
+	return s.cmd.Wait()
+}
+
+// Output returns what the program has written to stdout and stderr so far.
+func (s *Subprocess) Output() string {
+	return s.output.String()
+}
+
+// output is a buffer that can be written by the program while the test reads
+// it.
+type output struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.Write(p)
+}
+
+func (o *output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.String()
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama/yamatest"
+)
+
+func TestSubprocess(t *testing.T) {
+
+	Convey("Ensure the program is terminated gracefully", t, func() {
+		const source = `
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"l7e.io/yama"
+)
+
+func main() {
+	watcher, _ := yama.NewWatcher(
+		yama.WatchingSignals(os.Interrupt, syscall.SIGTERM),
+		yama.WithClosers(yama.FnAsCloser(func() { fmt.Println("closed") })))
+	fmt.Println("ready")
+	_ = watcher.Wait()
+}
+`
+		p := yamatest.StartSubprocess(t, source)
+		So(p.WaitForOutput("ready", 5*time.Second), ShouldBeTrue)
+
+		So(p.Terminate(), ShouldBeNil)
+		So(p.Wait(), ShouldBeNil)
+		So(p.Output(), ShouldEqual, "ready\nclosed\n")
+	})
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest // import "l7e.io/yama/yamatest"
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"l7e.io/yama"
+)
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	// Started is the kind of the event recorded when a closer is called.
+	Started EventKind = iota
+
+	// Finished is the kind of the event recorded when a closer returns.
+	Finished
+)
+
+func (k EventKind) String() string {
+	if k == Started {
+		return "started"
+	}
+
+	return "finished"
+}
+
+// Event is the start or finish of a closer recorded by a Timeline.
+type Event struct {
+	Name string
+	Kind EventKind
+
+	// Seq is the virtual timestamp of the event: its position in the
+	// timeline.
+	Seq int
+
+	// Time is the time of the timeline's clock when the event occurred.
+	Time time.Time
+}
+
+// Timeline records the order in which closers start and finish.
+type Timeline struct {
+	mu     sync.Mutex
+	clock  yama.Clock
+	events []Event
+}
+
+// NewTimeline creates a timeline that timestamps events with the clock, e.g.
+// the Clock passed to the watcher under test.
+func NewTimeline(clock yama.Clock) *Timeline {
+	return &Timeline{clock: clock}
+}
+
+// Wrap returns a closer that records, under name, when the closer is called
+// and when it returns.  Closers that implement yama.ContextCloser keep
+// receiving the watcher's context.
+func (t *Timeline) Wrap(name string, closer io.Closer) io.Closer {
+	return &timedCloser{timeline: t, name: name, closer: closer}
+}
+
+// Closer returns a closer, that does nothing, whose calls are recorded under
+// name.
+func (t *Timeline) Closer(name string) io.Closer {
+	return t.Wrap(name, yama.FnAsCloser(func() {}))
+}
+
+// Events returns the recorded events in the order in which they occurred.
+func (t *Timeline) Events() []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Event(nil), t.events...)
+}
+
+// AssertClosedBefore fails the test unless the closer named before finished
+// before the closer named after started.
+func (t *Timeline) AssertClosedBefore(tb testing.TB, before, after string) {
+	tb.Helper()
+
+	finished, ok := t.find(before, Finished)
+	if !ok {
+		tb.Errorf("expected %q to have finished before %q started, but it did not finish", before, after)
+		return
+	}
+
+	started, ok := t.find(after, Started)
+	if !ok {
+		tb.Errorf("expected %q to have finished before %q started, but %q did not start", before, after, after)
+		return
+	}
+
+	if finished.Seq > started.Seq {
+		tb.Errorf("expected %q to have finished before %q started, but it finished at #%d after it started at #%d",
+			before, after, finished.Seq, started.Seq)
+	}
+}
+
+func (t *Timeline) find(name string, kind EventKind) (Event, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, e := range t.events {
+		if e.Name == name && e.Kind == kind {
+			return e, true
+		}
+	}
+
+	return Event{}, false
+}
+
+func (t *Timeline) record(name string, kind EventKind) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.events = append(t.events, Event{Name: name, Kind: kind, Seq: len(t.events), Time: t.clock.Now()})
+}
+
+type timedCloser struct {
+	timeline *Timeline
+	name     string
+	closer   io.Closer
+}
+
+func (c *timedCloser) Close() error {
+	c.timeline.record(c.name, Started)
+	defer c.timeline.record(c.name, Finished)
+
+	return c.closer.Close()
+}
+
+func (c *timedCloser) CloseContext(ctx context.Context) error {
+	cc, ok := c.closer.(yama.ContextCloser)
+	if !ok {
+		return c.Close()
+	}
+
+	c.timeline.record(c.name, Started)
+	defer c.timeline.record(c.name, Finished)
+
+	return cc.CloseContext(ctx)
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestTimeline(t *testing.T) {
+	epoch := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Ensure starts and finishes are recorded in order", t, func() {
+		clock := yamatest.NewClock(epoch)
+		timeline := yamatest.NewTimeline(clock)
+
+		a := timeline.Closer("a")
+		b := timeline.Wrap("b", yama.FnAsCloser(func() { clock.Advance(time.Second) }))
+
+		_ = a.Close()
+		_ = b.Close()
+
+		So(timeline.Events(), ShouldResemble, []yamatest.Event{
+			{Name: "a", Kind: yamatest.Started, Seq: 0, Time: epoch},
+			{Name: "a", Kind: yamatest.Finished, Seq: 1, Time: epoch},
+			{Name: "b", Kind: yamatest.Started, Seq: 2, Time: epoch},
+			{Name: "b", Kind: yamatest.Finished, Seq: 3, Time: epoch.Add(time.Second)},
+		})
+
+		timeline.AssertClosedBefore(t, "a", "b")
+	})
+
+	Convey("Ensure wrapped context closers receive the context", t, func() {
+		timeline := yamatest.NewTimeline(yamatest.NewClock(epoch))
+
+		var received context.Context
+		c := timeline.Wrap("c", yama.ContextFnAsCloser(func(ctx context.Context) error {
+			received = ctx
+			return nil
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		_ = c.(yama.ContextCloser).CloseContext(ctx)
+		So(received, ShouldEqual, ctx)
+		So(timeline.Events(), ShouldHaveLength, 2)
+	})
+
+	Convey("Ensure out of order closers fail the assertion", t, func() {
+		timeline := yamatest.NewTimeline(yamatest.NewClock(epoch))
+
+		_ = timeline.Closer("b").Close()
+		_ = timeline.Closer("a").Close()
+
+		tb := &fakeTB{TB: t}
+		timeline.AssertClosedBefore(tb, "a", "b")
+		So(tb.failed, ShouldBeTrue)
+
+		tb = &fakeTB{TB: t}
+		timeline.AssertClosedBefore(tb, "a", "missing")
+		So(tb.failed, ShouldBeTrue)
+	})
+}
+
+// fakeTB records failures instead of failing the test.
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failed = true
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+Package yamatest provides utilities for testing code that uses yama watchers.
+
+Signals delivers signals in-process, so that tests can deterministically
+trigger a watcher without sending signals to the test process, which races
+with other tests, and is not possible on all platforms.
+
+	signals := yamatest.NewSignals()
+	closers := yamatest.NewClosers()
+
+	watcher, err := yama.NewWatcher(
+		yama.WatchingSignals(syscall.SIGTERM),
+		yama.WithSignalSource(signals),
+		yama.WithClosers(closers.Closer("server"), closers.Closer("db")))
+
+	signals.Send(syscall.SIGTERM)
+	err = watcher.Wait()
+
+	yamatest.AssertInvoked(t, closers, "server", "db")
+
+Watchers can also be tested in testing/synctest bubbles, where the timeout of
+a watcher elapses instantly once the goroutines of the bubble are blocked.
+Since signals delivered by the OS originate outside the bubble, watched
+signals must be delivered by Signals.
+
+CloserSpy creates closers that record their calls, and that can be configured
+to take time or to fail; assertions are provided for GoConvey and in the style
+of testify.
+
+	db := yamatest.CloserSpy("db").WithDelay(time.Second).WithError(err)
+
+	So(db, yamatest.ShouldHaveBeenClosed, 1)
+	yamatest.AssertClosed(t, db, 1)
+
+Clock is a clock that tests advance instantly, instead of sleeping through the
+timeout of a watcher.
+
+	clock := yamatest.NewClock(time.Now())
+
+	watcher, err := yama.NewWatcher(
+		yama.WithClock(clock),
+		yama.WithTimeout(time.Minute),
+		yama.WithClosers(slow))
+
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}()
+
+	err = watcher.Close() // returns an *ErrTimedOut without waiting a minute
+
+Timeline records the order in which closers start and finish, so that tests
+can assert the order of a shutdown.
+
+	timeline := yamatest.NewTimeline(clock)
+	server := timeline.Wrap("server", server)
+	db := timeline.Wrap("db", db)
+
+	...
+
+	timeline.AssertClosedBefore(t, "server", "db")
+*/
+package yamatest // import "l7e.io/yama/yamatest"
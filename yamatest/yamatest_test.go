@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2021 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamatest_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"l7e.io/yama"
+	"l7e.io/yama/yamatest"
+)
+
+func TestSignals(t *testing.T) {
+
+	Convey("Ensure watchers are triggered by in-process signals", t, func() {
+		signals := yamatest.NewSignals()
+		closers := yamatest.NewClosers()
+
+		watcher, err := yama.NewWatcher(
+			yama.WatchingSignals(syscall.SIGTERM),
+			yama.WithSignalSource(signals),
+			yama.WithClosers(closers.Closer("server"), closers.Closer("db")))
+		So(err, ShouldBeNil)
+
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+
+		err = watcher.Wait()
+		So(err, ShouldBeNil)
+
+		yamatest.AssertInvoked(t, closers, "server", "db")
+	})
+
+	Convey("Ensure only watched signals are relayed", t, func() {
+		signals := yamatest.NewSignals()
+
+		c := make(chan os.Signal, 1)
+		signals.Notify(c, syscall.SIGTERM)
+
+		So(signals.Send(os.Interrupt), ShouldBeFalse)
+		So(signals.Send(syscall.SIGTERM), ShouldBeTrue)
+		So(<-c, ShouldEqual, syscall.SIGTERM)
+
+		signals.Stop(c)
+		So(signals.Send(syscall.SIGTERM), ShouldBeFalse)
+	})
+
+	Convey("Ensure all signals are relayed when none are specified", t, func() {
+		signals := yamatest.NewSignals()
+
+		c := make(chan os.Signal, 1)
+		signals.Notify(c)
+
+		So(signals.Send(os.Interrupt), ShouldBeTrue)
+		So(<-c, ShouldEqual, os.Interrupt)
+	})
+}
+
+func TestClosers(t *testing.T) {
+
+	Convey("Ensure invocations are recorded", t, func() {
+		closers := yamatest.NewClosers()
+
+		_ = closers.Closer("a").Close()
+		_ = closers.Closer("b").Close()
+		_ = closers.Closer("a").Close()
+
+		So(closers.Invoked(), ShouldResemble, []string{"a", "b", "a"})
+	})
+}